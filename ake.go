@@ -46,6 +46,19 @@ func (c *Conversation) initAKE() {
 	}
 }
 
+// copyForOtherInstance creates a copy of an AKE that has sent a DH-Commit message, so that another instance
+// of the peer can answer that same DH-Commit
+func (a *ake) copyForOtherInstance() *ake {
+	return &ake{
+		secretExponent:  new(big.Int).Set(a.secretExponent),
+		ourPublicValue:  new(big.Int).Set(a.ourPublicValue),
+		r:               a.r,
+		encryptedGx:     makeCopy(a.encryptedGx),
+		state:           a.state,
+		lastStateChange: a.lastStateChange,
+	}
+}
+
 func (c *Conversation) calcAKEKeys(s *big.Int) {
 	c.ssid, c.ake.revealKey, c.ake.sigKey = calculateAKEKeys(s, c.version)
 }
@@ -268,7 +281,7 @@ func (c *Conversation) processRevealSig(msg []byte) (err error) {
 
 	c.calcAKEKeys(c.calcDHSharedSecret())
 	if err = c.processEncryptedSig(encryptedSig, theirMAC, &c.ake.revealKey); err != nil {
-		return newOtrError("in reveal signature message: " + err.Error())
+		return wrapOtrError(err, "in reveal signature message: "+err.Error())
 	}
 
 	return nil
@@ -287,7 +300,7 @@ func (c *Conversation) processSig(msg []byte) (err error) {
 	encryptedSig := sigMsg.encryptedSig
 
 	if err := c.processEncryptedSig(encryptedSig, theirMAC, &c.ake.sigKey); err != nil {
-		return newOtrError("in signature message: " + err.Error())
+		return wrapOtrError(err, "in signature message: "+err.Error())
 	}
 
 	return nil
@@ -312,7 +325,7 @@ func verifyEncryptedSignatureMAC(encryptedSig []byte, theirMAC []byte, keys *ake
 	myMAC := sumHMAC(keys.m2, tomac, v)[:v.truncateLength()]
 
 	if len(myMAC) != len(theirMAC) || subtle.ConstantTimeCompare(myMAC, theirMAC) == 0 {
-		return newOtrError("bad signature MAC in encrypted signature")
+		return &MACVerificationError{Message: "encrypted signature"}
 	}
 
 	return nil
@@ -384,7 +397,7 @@ func checkDecryptedGx(decryptedGx, hashedGx []byte, v otrVersion) error {
 	digest := v.hash2(decryptedGx)
 
 	if subtle.ConstantTimeCompare(digest[:], hashedGx[:]) == 0 {
-		return newOtrError("bad commit MAC in reveal signature message")
+		return &MACVerificationError{Message: "DH commit"}
 	}
 
 	return nil
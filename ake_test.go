@@ -151,7 +151,7 @@ func Test_processEncryptedSigWithBadSignatureMACError(t *testing.T) {
 	_, encryptedSig, _ := extractData(bytesFromHex("000001b2dda2d4ef365711c172dad92804b201fcd2fdd6444568ebf0844019fb65ca4f5f57031936f9a339e08bfd4410905ab86c5d6f73e6c94de6a207f373beff3f7676faee7b1d3be21e630fe42e95db9d4ac559252bff530481301b590e2163b99bde8aa1b07448bf7252588e317b0ba2fc52f85a72a921ba757785b949e5e682341d98800aa180aa0bd01f51180d48260e4358ffae72a97f652f02eb6ae3bc6a25a317d0ca5ed0164a992240baac8e043f848332d22c10a46d12c745dc7b1b0ee37fd14614d4b69d500b8ce562040e3a4bfdd1074e2312d3e3e4c68bd15d70166855d8141f695b21c98c6055a5edb9a233925cf492218342450b806e58b3a821e5d1d2b9c6b9cbcba263908d7190a3428ace92572c064a328f86fa5b8ad2a9c76d5b9dcaeae5327f545b973795f7c655248141c2f82db0a2045e95c1936b726d6474f50283289e92ab5c7297081a54b9e70fce87603506dedd6734bab3c1567ee483cd4bcb0e669d9d97866ca274f178841dafc2acfdcd10cb0e2d07db244ff4b1d23afe253831f142083d912a7164a3425f82c95675298cf3c5eb3e096bbc95e44ecffafbb585738723c0adbe11f16c311a6cddde630b9c304717ce5b09247d482f32709ea71ced16ba930a554f9949c1acbecf"))
 	macSignature := bytesFromHex("8e6e5ef63a4e8d6aa2cfb1c5fe1831498862f69d7de32af4f9895180e4b494e6")
 	err := c.processEncryptedSig(encryptedSig, macSignature[:20], &c.ake.revealKey)
-	assertEquals(t, err.Error(), "otr: bad signature MAC in encrypted signature")
+	assertEquals(t, err.Error(), "otr: bad MAC in encrypted signature")
 	assertEquals(t, c.ake.keys.theirKeyID, uint32(0))
 }
 
@@ -223,18 +223,18 @@ func Test_processSig(t *testing.T) {
 func Test_processSig_returnsErrorIfTheSignatureDataIsInvalid(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processSig([]byte{0x01, 0x01, 0x00})
-	assertDeepEquals(t, err, newOtrError("corrupt signature message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "signature message", Field: "encrypted signature", Offset: 0})
 }
 func Test_processRevealSig_returnsErrorIfTheRDataIsInvalid(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processRevealSig([]byte{0x01, 0x01, 0x00})
-	assertDeepEquals(t, err, newOtrError("corrupt reveal signature message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "reveal signature message", Field: "r", Offset: 0})
 }
 
 func Test_processRevealSig_returnsErrorIfTheSignatureDataIsInvalid(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processRevealSig([]byte{0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt reveal signature message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "reveal signature message", Field: "encrypted signature", Offset: 5})
 }
 
 func Test_sigMessage(t *testing.T) {
@@ -312,7 +312,7 @@ func Test_checkDecryptedGxWithoutError(t *testing.T) {
 func Test_checkDecryptedGxWithError(t *testing.T) {
 	hashedGx := otrV3{}.hash2(appendMPI([]byte{}, fixedGY()))
 	err := checkDecryptedGx(appendMPI([]byte{}, fixedGX()), hashedGx[:], otrV3{})
-	assertDeepEquals(t, err.Error(), "otr: bad commit MAC in reveal signature message")
+	assertDeepEquals(t, err.Error(), "otr: bad MAC in DH commit")
 }
 
 func Test_extractGxWithoutError(t *testing.T) {
@@ -419,13 +419,13 @@ func Test_generateSigKeyEncryptedSignature(t *testing.T) {
 func Test_processDHCommit_returnsErrorIfTheEncryptedGXPartIsNotCorrect(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processDHCommit([]byte{0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH commit message", Field: "encrypted gx", Offset: 0})
 }
 
 func Test_processDHCommit_returnsErrorIfTheHashedGXPartIsNotCorrect(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	err := c.processDHCommit([]byte{0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH commit message", Field: "hashed gx", Offset: 5})
 }
 
 func Test_calcXBb_returnsErrorIfTheSigningDoesntWork(t *testing.T) {
@@ -496,7 +496,7 @@ func Test_sigMessage_returnsErrorFromgenerateEncryptedSignature(t *testing.T) {
 func Test_processDHKey_returnsErrorIfTheMessageHasAnIncorrectGyParameter(t *testing.T) {
 	c := newConversation(otrV2{}, fixedRand([]string{}))
 	_, err := c.processDHKey([]byte{0x00, 0x00, 0x00, 0x02, 0x01})
-	assertDeepEquals(t, err, newOtrError("corrupt DH key message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH key message", Field: "gy", Offset: 0})
 }
 
 func Test_processDHKey_returnsErrorIfGyIsNotAValidDHParameter(t *testing.T) {
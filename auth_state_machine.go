@@ -1,9 +1,6 @@
 package otr3
 
-import (
-	"bytes"
-	"time"
-)
+import "bytes"
 
 const minimumMessageLength = 3 // length of protocol version (SHORT) and message type (BYTE)
 
@@ -17,10 +14,10 @@ func (c *Conversation) akeHasFinished() error {
 	c.ake.wipe(false)
 
 	previousMsgState := c.msgState
-	c.lastMessageStateChange = time.Now()
-	c.msgState = encrypted
-	defer c.signalSecurityEventIf(previousMsgState != encrypted, GoneSecure)
-	defer c.signalSecurityEventIf(previousMsgState == encrypted, StillSecure)
+	c.lastMessageStateChange = c.now()
+	c.setMsgState(encrypted)
+	defer c.signalSecure(previousMsgState == encrypted)
+	c.count(MetricAKECompleted)
 
 	if c.ourCurrentKey.PublicKey().IsSame(c.theirKey) {
 		c.messageEvent(MessageEventMessageReflected)
@@ -31,6 +28,7 @@ func (c *Conversation) akeHasFinished() error {
 
 func (c *Conversation) processAKE(msgType byte, msg []byte) (toSend []messageWithHeader, err error) {
 	c.ensureAKE()
+	defer c.logAuthStateChange(c.authStateName())
 
 	var toSendSingle messageWithHeader
 	var toSendExtra []messageWithHeader
@@ -38,6 +36,9 @@ func (c *Conversation) processAKE(msgType byte, msg []byte) (toSend []messageWit
 	switch msgType {
 	case msgTypeDHCommit:
 		c.ake.state, toSendSingle, err = c.ake.state.receiveDHCommitMessage(c, msg)
+		if err == nil {
+			c.count(MetricAKEStarted)
+		}
 	case msgTypeDHKey:
 		c.ake.state, toSendSingle, err = c.ake.state.receiveDHKeyMessage(c, msg)
 	case msgTypeRevealSig:
@@ -50,7 +51,7 @@ func (c *Conversation) processAKE(msgType byte, msg []byte) (toSend []messageWit
 		err = newOtrErrorf("unknown message type 0x%X", msgType)
 	}
 
-	c.ake.lastStateChange = time.Now()
+	c.ake.lastStateChange = c.now()
 
 	messages := append([]messageWithHeader{toSendSingle}, toSendExtra...)
 	toSend = compactMessagesWithHeader(messages...)
@@ -270,7 +270,7 @@ func Test_authStateAwaitingRevealSig_receiveRevealSigMessage_returnsErrorIfProce
 	c := newConversation(otrV2{}, fixtureRand())
 	c.Policies.add(allowV2)
 	_, _, err := authStateAwaitingRevealSig{}.receiveRevealSigMessage(c, []byte{0x00, 0x00})
-	assertDeepEquals(t, err, newOtrError("corrupt reveal signature message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "reveal signature message", Field: "r", Offset: 0})
 }
 
 func Test_receiveRevealSig_IgnoreMessageIfNotInStateAwaitingRevealSig(t *testing.T) {
@@ -454,7 +454,7 @@ func Test_authStateAwaitingDHKey_receiveDHKeyMessage_returnsErrorIfprocessDHKeyR
 
 	_, _, err := authStateAwaitingDHKey{}.receiveDHKeyMessage(c, []byte{0x00, 0x02})
 
-	assertDeepEquals(t, err, newOtrError("corrupt DH key message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH key message", Field: "gy", Offset: 0})
 }
 
 func Test_authStateAwaitingDHKey_receiveDHKeyMessage_returnsErrorIfrevealSigMessageReturnsError(t *testing.T) {
@@ -483,14 +483,14 @@ func Test_authStateAwaitingSig_receiveDHKeyMessage_returnsErrorIfprocessDHKeyRet
 
 	_, _, err := authStateAwaitingSig{}.receiveDHKeyMessage(c, []byte{0x01, 0x02})
 
-	assertEquals(t, err, newOtrError("corrupt DH key message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH key message", Field: "gy", Offset: 0})
 }
 
 func Test_authStateAwaitingSig_receiveSigMessage_returnsErrorIfProcessSigFails(t *testing.T) {
 	c := newConversation(otrV2{}, fixtureRand())
 	c.Policies.add(allowV2)
 	_, _, err := authStateAwaitingSig{}.receiveSigMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt signature message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "signature message", Field: "encrypted signature", Offset: 0})
 }
 
 func Test_authStateAwaitingRevealSig_receiveDHCommitMessage_returnsErrorIfProcessDHCommitOrGenerateCommitInstanceTagsFailsFails(t *testing.T) {
@@ -502,7 +502,7 @@ func Test_authStateAwaitingRevealSig_receiveDHCommitMessage_returnsErrorIfProces
 	c.ake.theirPublicValue = ourDHCommitAKE.ake.ourPublicValue
 
 	_, _, err := authStateAwaitingRevealSig{}.receiveDHCommitMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH commit message", Field: "encrypted gx", Offset: 0})
 }
 
 func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfgenerateCommitMsgInstanceTagsFails(t *testing.T) {
@@ -514,7 +514,7 @@ func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfgenerateCommitMsgIn
 	c.ake.theirPublicValue = ourDHCommitAKE.ake.ourPublicValue
 
 	_, _, err := authStateNone{}.receiveDHCommitMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH commit message", Field: "encrypted gx", Offset: 0})
 }
 
 func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfdhKeyMessageFails(t *testing.T) {
@@ -538,7 +538,7 @@ func Test_authStateNone_receiveDHCommitMessage_returnsErrorIfProcessDHCommitFail
 	c.ake.theirPublicValue = ourDHCommitAKE.ake.ourPublicValue
 
 	_, _, err := authStateNone{}.receiveDHCommitMessage(c, []byte{0x00, 0x00})
-	assertEquals(t, err, newOtrError("corrupt DH commit message"))
+	assertDeepEquals(t, err, &MalformedMessageError{Message: "DH commit message", Field: "encrypted gx", Offset: 0})
 }
 
 func Test_authStateAwaitingDHKey_receiveDHCommitMessage_failsIfMsgDoesntHaveHeader(t *testing.T) {
@@ -4,6 +4,7 @@ package otr3
 // The authentication uses an optional question message and a shared secret. The authentication will proceed
 // until the event handler reports that SMP is complete, that a secret is needed or that SMP has failed.
 func (c *Conversation) StartAuthenticate(question string, mutualSecret []byte) ([]ValidMessage, error) {
+	defer c.logSMPStateChange(c.smpStateName())
 	c.smp.ensureSMP()
 
 	tlvs, err := c.smp.state.startAuthenticate(c, question, mutualSecret)
@@ -19,6 +20,7 @@ func (c *Conversation) StartAuthenticate(question string, mutualSecret []byte) (
 // ProvideAuthenticationSecret should be called when the peer has started an authentication request, and the UI has been notified that a secret is needed
 // It is only valid to call this function if the current SMP state is waiting for a secret to be provided. The return is the potential messages to send.
 func (c *Conversation) ProvideAuthenticationSecret(mutualSecret []byte) ([]ValidMessage, error) {
+	defer c.logSMPStateChange(c.smpStateName())
 	t, err := c.continueSMP(mutualSecret)
 	if err != nil {
 		return nil, err
@@ -31,6 +33,7 @@ func (c *Conversation) ProvideAuthenticationSecret(mutualSecret []byte) ([]Valid
 // AbortAuthentication should be called when the user wants to abort authentication with a peer.
 // It will return an SMP abort message to send.
 func (c *Conversation) AbortAuthentication() ([]ValidMessage, error) {
+	defer c.logSMPStateChange(c.smpStateName())
 	t := c.restartSMP()
 
 	msgs, _, err := c.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, []tlv{t})
@@ -39,6 +42,7 @@ func (c *Conversation) AbortAuthentication() ([]ValidMessage, error) {
 
 func (c *Conversation) potentialAuthError(toSend []messageWithHeader, err error) ([]messageWithHeader, error) {
 	if err != nil {
+		c.count(MetricAKEFailed)
 		c.messageEventWithError(MessageEventSetupError, err)
 	}
 
@@ -131,7 +131,7 @@ func Test_ProvideAuthenticationSecret_failsAndAbortsIfWeAreNotWaitingForASecret(
 	c.smp.state = smpStateExpect3{}
 
 	_, e := c.ProvideAuthenticationSecret([]byte("hello world"))
-	assertEquals(t, e, errNotWaitingForSMPSecret)
+	assertEquals(t, e, ErrNotWaitingForSMPSecret)
 }
 
 func Test_ProvideAuthenticationSecret_continuesWithMessageProcessingIfInTheRightState(t *testing.T) {
@@ -201,5 +201,5 @@ func Test_AbortAuthentication_generatesErrorWhenNoEncryptedChannelExists(t *test
 
 	_, e := c.AbortAuthentication()
 
-	assertEquals(t, e, errCannotSendUnencrypted)
+	assertEquals(t, e, ErrNotEncrypted)
 }
@@ -0,0 +1,17 @@
+package otr3
+
+import "time"
+
+// Clock is the source of the current time for a conversation. It is used for heartbeats, resending of messages
+// and for ignoring repeated query messages. Setting the Clock field of a Conversation makes it possible to control
+// time, for example in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+func (c *Conversation) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return time.Now()
+}
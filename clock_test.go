@@ -0,0 +1,110 @@
+package otr3
+
+import (
+	"testing"
+	"time"
+)
+
+func encryptedPeersWithClock(t *testing.T, clock Clock) (alice, bob *Conversation) {
+	alice = newStatePeer(alicePrivateKey)
+	bob = newStatePeer(bobPrivateKey)
+	alice.Clock = clock
+	bob.Clock = clock
+	exchangeBetween(t, alice, bob, []ValidMessage{alice.QueryMessage()})
+	return
+}
+
+func Test_now_usesTheClockOfTheConversation(t *testing.T) {
+	clock := newFakeClock()
+	c := &Conversation{Clock: clock}
+
+	assertEquals(t, c.now(), clock.t)
+}
+
+func Test_now_usesTheSystemTimeWithoutAClock(t *testing.T) {
+	c := &Conversation{}
+	before := time.Now()
+
+	now := c.now()
+
+	assertFalse(t, now.Before(before))
+	assertFalse(t, now.After(time.Now()))
+}
+
+func Test_Receive_sendsAHeartbeatOnlyAfterTheHeartbeatIntervalHasPassed(t *testing.T) {
+	clock := newFakeClock()
+	alice, bob := encryptedPeersWithClock(t, clock)
+	sendBetween(t, alice, bob, "hi")
+
+	clock.advance(30 * time.Second)
+	toAlice, _ := bob.Send(ValidMessage("hello"))
+	_, toSend, _ := alice.Receive(toAlice[0])
+	assertNil(t, toSend)
+
+	clock.advance(31 * time.Second)
+	toAlice, _ = bob.Send(ValidMessage("hello again"))
+	_, toSend, _ = alice.Receive(toAlice[0])
+	assertEquals(t, len(toSend), 1)
+	assertEquals(t, alice.heartbeat.lastSent, clock.t)
+}
+
+func Test_Receive_ignoresRepeatedQueryMessagesForAMinuteAfterGoingSecure(t *testing.T) {
+	clock := newFakeClock()
+	alice, bob := encryptedPeersWithClock(t, clock)
+	assertEquals(t, bob.lastMessageStateChange, clock.t)
+
+	clock.advance(59 * time.Second)
+	_, toSend, err := bob.Receive(alice.QueryMessage())
+	assertNil(t, err)
+	assertNil(t, toSend)
+
+	clock.advance(2 * time.Second)
+	_, toSend, err = bob.Receive(alice.QueryMessage())
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+}
+
+func resendAfter(t *testing.T, d time.Duration) []MessageEvent {
+	clock := newFakeClock()
+	alice := newStatePeer(alicePrivateKey)
+	alice.Policies.RequireEncryption()
+	alice.Clock = clock
+	bob := newStatePeer(bobPrivateKey)
+	bob.Clock = clock
+	var events []MessageEvent
+	alice.messageEventHandler = dynamicMessageEventHandler{func(event MessageEvent, message []byte, err error, trace ...interface{}) {
+		events = append(events, event)
+	}}
+
+	toBob, _ := alice.Send(ValidMessage("queued"))
+	clock.advance(d)
+	exchangeBetween(t, alice, bob, toBob)
+	assertTrue(t, alice.IsEncrypted())
+
+	return events
+}
+
+func Test_Receive_resendsQueuedMessagesWithinTheResendInterval(t *testing.T) {
+	events := resendAfter(t, 59*time.Second)
+
+	assertDeepEquals(t, events, []MessageEvent{MessageEventEncryptionRequired, MessageEventMessageSent, MessageEventLogHeartbeatReceived})
+}
+
+func Test_Receive_doesntResendQueuedMessagesAfterTheResendInterval(t *testing.T) {
+	events := resendAfter(t, 61*time.Second)
+
+	assertDeepEquals(t, events, []MessageEvent{MessageEventEncryptionRequired})
+}
+
+func Test_Session_usesTheClockOfTheMasterForTheLastReceivedTime(t *testing.T) {
+	clock := newFakeClock()
+	master := newSessionPeer(alicePrivateKey, 0x1000)
+	master.Clock = clock
+	alice := NewSession(master)
+	bob := newSessionPeer(bobPrivateKey, 0x2000)
+
+	exchangeWithSession(t, alice, bob, []ValidMessage{master.QueryMessage()})
+
+	assertEquals(t, alice.instances[0x2000].lastReceived, clock.t)
+	assertEquals(t, alice.instances[0x2000].c.Clock, Clock(clock))
+}
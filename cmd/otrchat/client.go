@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coyim/otr3"
+)
+
+const help = `Commands:
+  /otr start                start a private conversation
+  /otr end                  end the private conversation
+  /smp <secret>             authenticate the peer with a shared secret, or answer their authentication request
+  /smp <question> | <secret>
+                            authenticate the peer with a shared secret, asking a question
+  /smp abort                abort the authentication
+  /fingerprint              show our fingerprint and the fingerprint of the peer
+  /help                     show this help
+  /quit                     end the conversation and exit
+Anything else is sent to the peer.
+`
+
+var errQuit = errors.New("quit")
+
+// client connects a conversation with the user on one side and the peer on the other. Lines typed by the user are
+// given to handleInput, and lines received from the peer to handlePeer. All events are printed for the user.
+type client struct {
+	peer string
+	conv *otr3.LockedConversation
+
+	// send is called with the lock of the conversation held, so that messages are sent in the order they were made
+	send func(otr3.ValidMessage) error
+
+	outLock sync.Mutex
+	out     io.Writer
+
+	// answerPending is true when the peer has asked us to authenticate. It is only used with the conversation lock held
+	answerPending bool
+}
+
+func newClient(c *otr3.Conversation, peer string, send func(otr3.ValidMessage) error, out io.Writer) *client {
+	cl := &client{peer: peer, send: send, out: out}
+	c.SetSMPEventHandler(cl)
+	c.SetSecurityEventHandler(cl)
+	c.SetMessageEventHandler(cl)
+	c.SetErrorMessageHandler(cl)
+	cl.conv = otr3.NewLockedConversation(c)
+	return cl
+}
+
+func (cl *client) printf(format string, a ...interface{}) {
+	cl.outLock.Lock()
+	defer cl.outLock.Unlock()
+
+	fmt.Fprintf(cl.out, format, a...)
+}
+
+// do runs f with the conversation lock held, and sends all the messages it returns
+func (cl *client) do(f func(c *otr3.Conversation) ([]otr3.ValidMessage, error)) (err error) {
+	cl.conv.WithConversation(func(c *otr3.Conversation) {
+		var toSend []otr3.ValidMessage
+		if toSend, err = f(c); err != nil {
+			return
+		}
+		for _, m := range toSend {
+			if err = cl.send(m); err != nil {
+				return
+			}
+		}
+	})
+	return
+}
+
+func (cl *client) handleInput(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if !strings.HasPrefix(fields[0], "/") {
+		return cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+			return c.Send(otr3.ValidMessage(line))
+		})
+	}
+
+	switch {
+	case fields[0] == "/otr" && len(fields) == 2 && fields[1] == "start":
+		return cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+			return []otr3.ValidMessage{c.QueryMessage()}, nil
+		})
+	case fields[0] == "/otr" && len(fields) == 2 && fields[1] == "end":
+		return cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+			return c.End()
+		})
+	case fields[0] == "/smp" && len(fields) == 2 && fields[1] == "abort":
+		return cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+			cl.answerPending = false
+			return c.AbortAuthentication()
+		})
+	case fields[0] == "/smp" && len(fields) >= 2:
+		arg := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+		return cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+			return cl.authenticate(c, arg)
+		})
+	case fields[0] == "/fingerprint" && len(fields) == 1:
+		cl.conv.WithConversation(cl.printFingerprints)
+		return nil
+	case fields[0] == "/help":
+		cl.printf("%s", help)
+		return nil
+	case fields[0] == "/quit":
+		cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+			return c.End()
+		})
+		return errQuit
+	}
+
+	cl.printf("*** unknown command: %s - try /help\n", line)
+	return nil
+}
+
+// authenticate answers the pending authentication request with arg, or starts a new one. A new request can have a
+// question before the secret, separated by the first "|". Everything else is the secret, including any spaces in it.
+func (cl *client) authenticate(c *otr3.Conversation, arg string) ([]otr3.ValidMessage, error) {
+	if cl.answerPending {
+		cl.answerPending = false
+		return c.ProvideAuthenticationSecret([]byte(arg))
+	}
+
+	question, secret := "", arg
+	if i := strings.Index(arg, "|"); i != -1 {
+		question, secret = strings.TrimSpace(arg[:i]), strings.TrimSpace(arg[i+1:])
+	}
+	if secret == "" {
+		cl.printf("*** the secret can't be empty - use /smp <question> | <secret>\n")
+		return nil, nil
+	}
+	return c.StartAuthenticate(question, []byte(secret))
+}
+
+func (cl *client) printFingerprints(c *otr3.Conversation) {
+	for _, k := range c.GetOurKeys() {
+		cl.printf("*** our fingerprint:   %s\n", otr3.FormatFingerprint(k.PublicKey().Fingerprint()))
+	}
+	if k := c.GetTheirKey(); k != nil {
+		cl.printf("*** their fingerprint: %s\n", otr3.FormatFingerprint(k.Fingerprint()))
+	} else {
+		cl.printf("*** their fingerprint: unknown - start a private conversation first\n")
+	}
+}
+
+func (cl *client) handlePeer(line string) error {
+	return cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		plain, toSend, err := c.Receive(otr3.ValidMessage(line))
+		if len(plain) > 0 {
+			cl.printf("%s: %s\n", cl.peer, plain)
+		}
+		if err != nil {
+			cl.printf("*** error: %v\n", err)
+		}
+		return toSend, nil
+	})
+}
+
+func (cl *client) tick(now time.Time) error {
+	return cl.do(func(c *otr3.Conversation) ([]otr3.ValidMessage, error) {
+		return c.Tick(now)
+	})
+}
+
+// HandleSMPEvent implements otr3.SMPEventHandler
+func (cl *client) HandleSMPEvent(event otr3.SMPEvent, progressPercent int, question string) {
+	cl.printf("*** smp: %v (%d%%)\n", event, progressPercent)
+
+	switch event {
+	case otr3.SMPEventAskForAnswer:
+		cl.answerPending = true
+		cl.printf("*** %s asks: %s\n*** answer with /smp <answer>\n", cl.peer, question)
+	case otr3.SMPEventAskForSecret:
+		cl.answerPending = true
+		cl.printf("*** %s wants to authenticate - answer with /smp <secret>\n", cl.peer)
+	case otr3.SMPEventSuccess:
+		cl.printf("*** %s has been authenticated\n", cl.peer)
+	case otr3.SMPEventFailure, otr3.SMPEventCheated, otr3.SMPEventAbort, otr3.SMPEventError:
+		cl.answerPending = false
+		cl.printf("*** authentication of %s failed\n", cl.peer)
+	}
+}
+
+// HandleSecurityEvent implements otr3.SecurityEventHandler
+func (cl *client) HandleSecurityEvent(event otr3.SecurityEvent) {
+	cl.printf("*** security: %v\n", event)
+}
+
+// HandleMessageEvent implements otr3.MessageEventHandler
+func (cl *client) HandleMessageEvent(event otr3.MessageEvent, message []byte, err error, trace ...interface{}) {
+	switch {
+	case err != nil:
+		cl.printf("*** message: %v: %v\n", event, err)
+	case message != nil:
+		cl.printf("*** message: %v: %s\n", event, message)
+	default:
+		cl.printf("*** message: %v\n", event)
+	}
+}
+
+// HandleErrorMessage implements otr3.ErrorMessageHandler
+func (cl *client) HandleErrorMessage(error otr3.ErrorCode) []byte {
+	cl.printf("*** sending error: %v\n", error)
+	return []byte(error.String())
+}
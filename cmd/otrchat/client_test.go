@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coyim/otr3"
+)
+
+var alicePrivateKeyHex = "000000000080c81c2cb2eb729b7e6fd48e975a932c638b3a9055478583afa46755683e30102447f6da2d8bec9f386bbb5da6403b0040fee8650b6ab2d7f32c55ab017ae9b6aec8c324ab5844784e9a80e194830d548fb7f09a0410df2c4d5c8bc2b3e9ad484e65412be689cf0834694e0839fb2954021521ffdffb8f5c32c14dbf2020b3ce7500000014da4591d58def96de61aea7b04a8405fe1609308d000000808ddd5cb0b9d66956e3dea5a915d9aba9d8a6e7053b74dadb2fc52f9fe4e5bcc487d2305485ed95fed026ad93f06ebb8c9e8baf693b7887132c7ffdd3b0f72f4002ff4ed56583ca7c54458f8c068ca3e8a4dfa309d1dd5d34e2a4b68e6f4338835e5e0fb4317c9e4c7e4806dafda3ef459cd563775a586dd91b1319f72621bf3f00000080b8147e74d8c45e6318c37731b8b33b984a795b3653c2cd1d65cc99efe097cb7eb2fa49569bab5aab6e8a1c261a27d0f7840a5e80b317e6683042b59b6dceca2879c6ffc877a465be690c15e4a42f9a7588e79b10faac11b1ce3741fcef7aba8ce05327a2c16d279ee1b3d77eb783fb10e3356caa25635331e26dd42b8396c4d00000001420bec691fea37ecea58a5c717142f0b804452f57"
+var bobPrivateKeyHex = "000000000080a5138eb3d3eb9c1d85716faecadb718f87d31aaed1157671d7fee7e488f95e8e0ba60ad449ec732710a7dec5190f7182af2e2f98312d98497221dff160fd68033dd4f3a33b7c078d0d9f66e26847e76ca7447d4bab35486045090572863d9e4454777f24d6706f63e02548dfec2d0a620af37bbc1d24f884708a212c343b480d00000014e9c58f0ea21a5e4dfd9f44b6a9f7f6a9961a8fa9000000803c4d111aebd62d3c50c2889d420a32cdf1e98b70affcc1fcf44d59cca2eb019f6b774ef88153fb9b9615441a5fe25ea2d11b74ce922ca0232bd81b3c0fcac2a95b20cb6e6c0c5c1ace2e26f65dc43c751af0edbb10d669890e8ab6beea91410b8b2187af1a8347627a06ecea7e0f772c28aae9461301e83884860c9b656c722f0000008065af8625a555ea0e008cd04743671a3cda21162e83af045725db2eb2bb52712708dc0cc1a84c08b3649b88a966974bde27d8612c2861792ec9f08786a246fcadd6d8d3a81a32287745f309238f47618c2bd7612cb8b02d940571e0f30b96420bcd462ff542901b46109b1e5ad6423744448d20a57818a8cbb1647d0fea3b664e0000001440f9f2eb554cb00d45a5826b54bfa419b6980e48"
+
+func parseKey(t *testing.T, h string) otr3.PrivateKey {
+	b, _ := hex.DecodeString(h)
+	_, ok, key := otr3.ParsePrivateKey(b)
+	if !ok {
+		t.Fatalf("couldn't parse fixture key")
+	}
+	return key
+}
+
+type testPeer struct {
+	*client
+	out    *bytes.Buffer
+	outbox []otr3.ValidMessage
+}
+
+func newTestConversation(t *testing.T, keyHex string) *otr3.Conversation {
+	c := &otr3.Conversation{}
+	c.Policies.AllowV3()
+	c.SetOurKeys([]otr3.PrivateKey{parseKey(t, keyHex)})
+	return c
+}
+
+func newTestPeer(t *testing.T, name, keyHex string) *testPeer {
+	p := &testPeer{out: &bytes.Buffer{}}
+	p.client = newClient(newTestConversation(t, keyHex), name, func(m otr3.ValidMessage) error {
+		p.outbox = append(p.outbox, m)
+		return nil
+	}, p.out)
+	return p
+}
+
+func testPeers(t *testing.T) (alice, bob *testPeer) {
+	return newTestPeer(t, "bob", alicePrivateKeyHex), newTestPeer(t, "alice", bobPrivateKeyHex)
+}
+
+// deliver passes messages between the peers until there is nothing more to send
+func deliver(t *testing.T, a, b *testPeer) {
+	for len(a.outbox) > 0 || len(b.outbox) > 0 {
+		for _, p := range [][2]*testPeer{{a, b}, {b, a}} {
+			from, to := p[0], p[1]
+			msgs := from.outbox
+			from.outbox = nil
+			for _, m := range msgs {
+				if err := to.handlePeer(string(m)); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (p *testPeer) input(t *testing.T, line string) {
+	if err := p.handleInput(line); err != nil {
+		t.Fatalf("unexpected error for %q: %v", line, err)
+	}
+}
+
+func (p *testPeer) expectOutput(t *testing.T, expected ...string) {
+	out := p.out.String()
+	p.out.Reset()
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, out)
+		}
+	}
+}
+
+func encryptedTestPeers(t *testing.T) (alice, bob *testPeer) {
+	alice, bob = testPeers(t)
+	alice.input(t, "/otr start")
+	deliver(t, alice, bob)
+	alice.expectOutput(t, "*** security: GoneSecure")
+	bob.expectOutput(t, "*** security: GoneSecure")
+	return
+}
+
+func Test_client_canStartAndEndAPrivateConversation(t *testing.T) {
+	alice, bob := encryptedTestPeers(t)
+
+	alice.input(t, "hello there")
+	deliver(t, alice, bob)
+	bob.expectOutput(t, "alice: hello there\n")
+
+	bob.input(t, "/otr end")
+	deliver(t, alice, bob)
+	bob.expectOutput(t, "*** security: GoneInsecure")
+	alice.expectOutput(t, "*** security: GoneInsecure")
+	if alice.conv.IsEncrypted() || bob.conv.IsEncrypted() {
+		t.Errorf("expected the conversation to be ended")
+	}
+}
+
+func Test_client_sendsPlainMessagesBeforeStartingOTR(t *testing.T) {
+	alice, bob := testPeers(t)
+
+	alice.input(t, "not private")
+	deliver(t, alice, bob)
+
+	bob.expectOutput(t, "alice: not private\n")
+}
+
+func Test_client_canAuthenticateWithAQuestion(t *testing.T) {
+	alice, bob := encryptedTestPeers(t)
+
+	alice.input(t, "/smp what is our secret? | the swordfish")
+	deliver(t, alice, bob)
+	bob.expectOutput(t, "*** alice asks: what is our secret?")
+
+	bob.input(t, "/smp the swordfish")
+	deliver(t, alice, bob)
+	alice.expectOutput(t, "*** bob has been authenticated")
+	bob.expectOutput(t, "*** alice has been authenticated")
+}
+
+func Test_client_canAuthenticateWithoutAQuestion(t *testing.T) {
+	alice, bob := encryptedTestPeers(t)
+
+	alice.input(t, "/smp swordfish")
+	deliver(t, alice, bob)
+	bob.expectOutput(t, "*** alice wants to authenticate")
+
+	bob.input(t, "/smp tuna")
+	deliver(t, alice, bob)
+	alice.expectOutput(t, "*** authentication of bob failed")
+	bob.expectOutput(t, "*** authentication of alice failed")
+}
+
+func Test_client_canAuthenticateWithASecretWithSpaces(t *testing.T) {
+	alice, bob := encryptedTestPeers(t)
+
+	alice.input(t, "/smp correct horse battery staple")
+	deliver(t, alice, bob)
+	bob.input(t, "/smp correct horse battery staple")
+	deliver(t, alice, bob)
+
+	alice.expectOutput(t, "*** bob has been authenticated")
+	bob.expectOutput(t, "*** alice has been authenticated")
+}
+
+func Test_client_doesntStartAuthenticationWithoutASecret(t *testing.T) {
+	alice, _ := encryptedTestPeers(t)
+
+	alice.input(t, "/smp what is our secret? |")
+
+	alice.expectOutput(t, "*** the secret can't be empty")
+}
+
+func Test_client_canAbortAuthentication(t *testing.T) {
+	alice, bob := encryptedTestPeers(t)
+
+	alice.input(t, "/smp swordfish")
+	deliver(t, alice, bob)
+	bob.input(t, "/smp abort")
+	deliver(t, alice, bob)
+
+	alice.expectOutput(t, "*** smp: SMPEventAbort")
+	if bob.answerPending {
+		t.Errorf("expected the pending answer to be forgotten")
+	}
+}
+
+func Test_client_printsFingerprints(t *testing.T) {
+	alice, bob := testPeers(t)
+	aliceFingerprint := otr3.FormatFingerprint(parseKey(t, alicePrivateKeyHex).PublicKey().Fingerprint())
+
+	alice.input(t, "/fingerprint")
+	alice.expectOutput(t, "*** our fingerprint:   "+aliceFingerprint, "*** their fingerprint: unknown")
+
+	alice.input(t, "/otr start")
+	deliver(t, alice, bob)
+	bob.out.Reset()
+	bob.input(t, "/fingerprint")
+	bob.expectOutput(t, "*** their fingerprint: "+aliceFingerprint)
+}
+
+func Test_client_reportsUnknownCommands(t *testing.T) {
+	alice, _ := testPeers(t)
+
+	alice.input(t, "/frobnicate")
+	alice.input(t, "/otr")
+
+	alice.expectOutput(t, "*** unknown command: /frobnicate", "*** unknown command: /otr")
+	if len(alice.outbox) != 0 {
+		t.Errorf("expected nothing to be sent, got %v", alice.outbox)
+	}
+}
+
+func Test_client_quitEndsThePrivateConversation(t *testing.T) {
+	alice, bob := encryptedTestPeers(t)
+
+	err := alice.handleInput("/quit")
+	deliver(t, alice, bob)
+
+	if err != errQuit {
+		t.Errorf("expected errQuit, got %v", err)
+	}
+	bob.expectOutput(t, "*** security: GoneInsecure")
+}
+
+func Test_client_printsMessageEvents(t *testing.T) {
+	alice, bob := testPeers(t)
+	alice.conv.WithConversation(func(c *otr3.Conversation) {
+		c.Policies.RequireEncryption()
+	})
+
+	alice.input(t, "secret")
+	deliver(t, alice, bob)
+
+	alice.expectOutput(t, "*** message: MessageEventEncryptionRequired")
+	bob.expectOutput(t, "*** security: GoneSecure")
+}
+
+// syncBuffer is a buffer that can be written and read from different goroutines
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func waitForOutput(t *testing.T, b *syncBuffer, expected string) {
+	for i := 0; i < 500; i++ {
+		if strings.Contains(b.String(), expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected output to contain %q, got:\n%s", expected, b.String())
+}
+
+func Test_chat_exchangesMessagesOverAConnection(t *testing.T) {
+	aliceConn, bobConn := net.Pipe()
+	aliceIn, aliceInput := io.Pipe()
+	bobIn, bobInput := io.Pipe()
+	aliceOut, bobOut := &syncBuffer{}, &syncBuffer{}
+	done := make(chan bool)
+
+	go func() {
+		chat(newTestConversation(t, alicePrivateKeyHex), "bob", aliceConn, aliceIn, aliceOut)
+		aliceConn.Close()
+		done <- true
+	}()
+	go func() {
+		chat(newTestConversation(t, bobPrivateKeyHex), "alice", bobConn, bobIn, bobOut)
+		done <- true
+	}()
+
+	io.WriteString(aliceInput, "/otr start\n")
+	waitForOutput(t, bobOut, "*** security: GoneSecure")
+	io.WriteString(bobInput, "hello alice\n")
+	waitForOutput(t, aliceOut, "bob: hello alice\n")
+	io.WriteString(aliceInput, "/quit\n")
+
+	<-done
+	<-done
+	bobInput.Close()
+	waitForOutput(t, bobOut, "*** security: GoneInsecure")
+}
+
+func Test_loadKey_findsTheAccount(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "otrchat")
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "keys")
+	alice, bob := parseKey(t, alicePrivateKeyHex), parseKey(t, bobPrivateKeyHex)
+	otr3.ExportKeysToFile([]*otr3.Account{
+		{Name: "alice@example.org", Protocol: "prpl-jabber", Key: alice},
+		{Name: "bob@example.org", Protocol: "prpl-jabber", Key: bob},
+	}, fname)
+
+	first, err := loadKey(fname, "", "", "")
+	second, err2 := loadKey(fname, "bob@example.org", "prpl-jabber", "")
+	_, err3 := loadKey(fname, "carol@example.org", "", "")
+
+	if err != nil || !bytes.Equal(first.PublicKey().Fingerprint(), alice.PublicKey().Fingerprint()) {
+		t.Errorf("expected alice's key, got %v", err)
+	}
+	if err2 != nil || !bytes.Equal(second.PublicKey().Fingerprint(), bob.PublicKey().Fingerprint()) {
+		t.Errorf("expected bob's key, got %v", err2)
+	}
+	if err3 == nil {
+		t.Errorf("expected an error for an unknown account")
+	}
+}
@@ -0,0 +1,175 @@
+// Command otrchat is a minimal terminal chat client that talks OTR with one peer, without any IM network in between.
+// It is meant for trying out and testing otr3 end to end.
+//
+// One side listens and the other connects, over TCP or a Unix socket:
+//
+//	otrchat -keys alice.keys -listen 127.0.0.1:7777
+//	otrchat -keys bob.keys -connect 127.0.0.1:7777
+//	otrchat -keys alice.keys -network unix -listen /tmp/otrchat.sock
+//
+// With -stdio, the messages to and from the peer are read from standard input and written to standard output, and
+// the user interface uses the terminal instead. This makes it possible to connect peers with other tools.
+//
+// Every line typed is sent to the peer, except for commands - type /help to see them. Messages from the peer and all
+// OTR events are printed. The keys are read from a libotr private key file, which can be encrypted with the passphrase
+// in the OTRCHAT_PASSPHRASE environment variable. otrkey can be used to create it.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coyim/otr3"
+)
+
+const passphraseEnv = "OTRCHAT_PASSPHRASE"
+
+func defaultKeyFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".otr3", "otr.private_key")
+}
+
+func main() {
+	keys := flag.String("keys", defaultKeyFile(), "the libotr private key file to use")
+	account := flag.String("account", "", "the account to use from the key file - defaults to the first one")
+	protocol := flag.String("protocol", "", "the protocol of the account to use")
+	peer := flag.String("peer", "peer", "the name to show for the peer")
+	network := flag.String("network", "tcp", "the network to use: tcp or unix")
+	listen := flag.String("listen", "", "the address to listen on for the peer")
+	connect := flag.String("connect", "", "the address of the peer to connect to")
+	stdio := flag.Bool("stdio", false, "talk to the peer over standard input and output")
+	requireEncryption := flag.Bool("require-encryption", false, "never send messages unencrypted")
+	flag.Parse()
+
+	key, err := loadKey(*keys, *account, *protocol, os.Getenv(passphraseEnv))
+	if err != nil {
+		fatal(err)
+	}
+
+	transport, ui, err := open(*network, *listen, *connect, *stdio)
+	if err != nil {
+		fatal(err)
+	}
+	defer transport.Close()
+	defer ui.Close()
+
+	c := &otr3.Conversation{}
+	c.Policies.AllowV2()
+	c.Policies.AllowV3()
+	if *requireEncryption {
+		c.Policies.RequireEncryption()
+	}
+	c.SetOurKeys([]otr3.PrivateKey{key})
+
+	chat(c, *peer, transport, ui, ui)
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "otrchat: %v\n", err)
+	os.Exit(1)
+}
+
+// loadKey returns the key of the named account, or the first one in the file if no account is given
+func loadKey(fname, account, protocol, passphrase string) (otr3.PrivateKey, error) {
+	acs, err := otr3.ImportKeysFromFileWithPassphrase(fname, []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range acs {
+		if (account == "" || a.Name == account) && (protocol == "" || a.Protocol == protocol) {
+			return a.Key, nil
+		}
+	}
+	return nil, fmt.Errorf("no matching account in %s", fname)
+}
+
+// open returns the connection to the peer and the terminal to use for the user interface
+func open(network, listen, connect string, stdio bool) (transport, ui io.ReadWriteCloser, err error) {
+	switch {
+	case stdio && listen == "" && connect == "":
+		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		return stdStreams{}, tty, nil
+	case listen != "" && connect == "" && !stdio:
+		l, err := net.Listen(network, listen)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer l.Close()
+		fmt.Printf("*** waiting for the peer on %s\n", l.Addr())
+		conn, err := l.Accept()
+		return conn, stdStreams{}, err
+	case connect != "" && listen == "" && !stdio:
+		conn, err := net.Dial(network, connect)
+		return conn, stdStreams{}, err
+	}
+	return nil, nil, fmt.Errorf("exactly one of -listen, -connect and -stdio must be given")
+}
+
+// stdStreams reads from standard input and writes to standard output
+type stdStreams struct{}
+
+func (stdStreams) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (stdStreams) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (stdStreams) Close() error {
+	return nil
+}
+
+// chat runs the conversation until the peer disconnects or the user quits. Messages are exchanged with the peer
+// one per line.
+func chat(c *otr3.Conversation, peer string, transport io.ReadWriter, in io.Reader, out io.Writer) {
+	cl := newClient(c, peer, func(m otr3.ValidMessage) error {
+		_, err := fmt.Fprintf(transport, "%s\n", m)
+		return err
+	}, out)
+	cl.printf("*** connected - type /help to see the commands\n")
+
+	done := make(chan bool, 3)
+	go readLines(transport, done, func(line string) {
+		if err := cl.handlePeer(line); err != nil {
+			cl.printf("*** error: %v\n", err)
+		}
+	})
+	go readLines(in, done, func(line string) {
+		if err := cl.handleInput(line); err == errQuit {
+			done <- true
+		} else if err != nil {
+			cl.printf("*** error: %v\n", err)
+		}
+	})
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if err := cl.tick(now); err != nil {
+				cl.printf("*** error: %v\n", err)
+			}
+		}
+	}
+}
+
+func readLines(r io.Reader, done chan<- bool, f func(string)) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f(s.Text())
+	}
+	done <- true
+}
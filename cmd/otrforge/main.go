@@ -0,0 +1,281 @@
+// Command otrforge changes OTR data messages so that they still look valid, to show why OTR transcripts prove
+// nothing. It does the same thing as otr_readforge, otr_modify and otr_remac from the libotr toolkit.
+//
+// Usage:
+//
+//	otrforge <command> [arguments]
+//
+// The commands are:
+//
+//	read <aes key> <message> [new text]        decrypt a message, and replace its text if new text is given
+//	modify [-x] [-mackey key] <message> <offset> <old> <new>
+//	                                           change known plaintext at offset without knowing the AES key
+//	remac [-flags n] [-sender id] [-recipient id] [-y hex] [-counter hex] [-data hex] <mac key> <message>
+//	                                           change any field of a message and give it a new MAC
+//
+// Keys are given in hex, and messages are complete "?OTR:" data messages. A message given as "-" is read from
+// standard input. Changed messages are written to standard output on a line of their own.
+//
+// The AES key of a message can be found with otrsesskeys, if one of the private DH exponents is known. The MAC key
+// used for remac and modify can be one that was revealed in a later message of the conversation, and can be seen
+// with otrparse.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/coyim/otr3/forge"
+)
+
+type command struct {
+	name, usage string
+	run         func(t *tool, args []string) error
+}
+
+var commands = []command{
+	{"read", "<aes key> <message> [new text]", read},
+	{"modify", "[-x] [-mackey key] <message> <offset> <old> <new>", modify},
+	{"remac", "[-flags n] [-sender id] [-recipient id] [-y hex] [-counter hex] [-data hex] <mac key> <message>", remac},
+}
+
+// tool keeps what all commands need, so that they can be run from tests
+type tool struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	t := &tool{stdin: stdin, stdout: stdout, stderr: stderr}
+	for _, c := range commands {
+		if c.name == args[0] {
+			err := c.run(t, args[1:])
+			if err == errUsage {
+				fmt.Fprintf(stderr, "usage: otrforge %s %s\n", c.name, c.usage)
+				return 2
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "otrforge: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(stderr, "otrforge: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: otrforge <command> [arguments]\n\nThe commands are:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s %s\n", c.name, c.usage)
+	}
+}
+
+func newFlagSet(name string, t *tool) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(t.stderr)
+	flags.Usage = func() {}
+	return flags
+}
+
+func parseHex(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == ':' || r == '\t' {
+			return -1
+		}
+		return r
+	}, s)
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not valid hex", s)
+	}
+	return b, nil
+}
+
+func (t *tool) message(arg string) (*forge.Message, error) {
+	if arg == "-" {
+		s := bufio.NewScanner(t.stdin)
+		if !s.Scan() {
+			if err := s.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("no message on standard input")
+		}
+		arg = s.Text()
+	}
+	return forge.Parse([]byte(strings.TrimSpace(arg)))
+}
+
+func read(t *tool, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return errUsage
+	}
+
+	aesKey, err := parseHex(args[0])
+	if err != nil {
+		return err
+	}
+	m, err := t.message(args[1])
+	if err != nil {
+		return err
+	}
+
+	plain, err := m.Decrypt(aesKey)
+	if err != nil {
+		return err
+	}
+
+	text, tlvs := plain, []byte(nil)
+	if i := bytes.IndexByte(plain, 0); i != -1 {
+		text, tlvs = plain[:i], plain[i:]
+	}
+	fmt.Fprintf(t.stdout, "Message: %s\n", text)
+	if len(tlvs) > 1 {
+		fmt.Fprintf(t.stdout, "TLVs: %X\n", tlvs[1:])
+	}
+
+	if len(args) == 3 {
+		if err := m.Encrypt(aesKey, append([]byte(args[2]), tlvs...)); err != nil {
+			return err
+		}
+		return t.printMessage(m, forge.MACKeyFor(aesKey))
+	}
+	return nil
+}
+
+// printMessage gives m a new MAC with macKey, unless it is nil, and prints the encoded message
+func (t *tool) printMessage(m *forge.Message, macKey []byte) error {
+	if macKey != nil {
+		if err := m.ReMAC(macKey); err != nil {
+			return err
+		}
+	}
+
+	encoded, err := m.Encode()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.stdout, "%s\n", encoded)
+	return nil
+}
+
+func modify(t *tool, args []string) error {
+	flags := newFlagSet("modify", t)
+	isHex := flags.Bool("x", false, "the old and new text are given in hex")
+	macKeyHex := flags.String("mackey", "", "give the changed message a new MAC with this key")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 4 {
+		return errUsage
+	}
+
+	m, err := t.message(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.Atoi(flags.Arg(1))
+	if err != nil {
+		return errUsage
+	}
+
+	oldText, newText := []byte(flags.Arg(2)), []byte(flags.Arg(3))
+	if *isHex {
+		if oldText, err = parseHex(flags.Arg(2)); err != nil {
+			return err
+		}
+		if newText, err = parseHex(flags.Arg(3)); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Replace(offset, oldText, newText); err != nil {
+		return err
+	}
+
+	var macKey []byte
+	if *macKeyHex != "" {
+		if macKey, err = parseHex(*macKeyHex); err != nil {
+			return err
+		}
+	}
+
+	return t.printMessage(m, macKey)
+}
+
+func remac(t *tool, args []string) error {
+	flags := newFlagSet("remac", t)
+	msgFlags := flags.Int("flags", -1, "the new flags of the message")
+	sender := flags.Int64("sender", -1, "the new sender key id")
+	recipient := flags.Int64("recipient", -1, "the new recipient key id")
+	y := flags.String("y", "", "the new next DH public key, in hex")
+	counter := flags.String("counter", "", "the new top half of the counter, 8 bytes in hex")
+	data := flags.String("data", "", "the new encrypted message, in hex")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+
+	macKey, err := parseHex(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	m, err := t.message(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	if *msgFlags != -1 {
+		m.Flags = byte(*msgFlags)
+	}
+	if *sender != -1 {
+		m.SenderKeyID = uint32(*sender)
+	}
+	if *recipient != -1 {
+		m.RecipientKeyID = uint32(*recipient)
+	}
+	if *y != "" {
+		b, err := parseHex(*y)
+		if err != nil {
+			return err
+		}
+		m.NextDHPublicKey = new(big.Int).SetBytes(b)
+	}
+	if *counter != "" {
+		b, err := parseHex(*counter)
+		if err != nil {
+			return err
+		}
+		if len(b) != len(m.TopHalfCounter) {
+			return errors.New("the counter must be 8 bytes")
+		}
+		copy(m.TopHalfCounter[:], b)
+	}
+	if *data != "" {
+		if m.EncryptedMessage, err = parseHex(*data); err != nil {
+			return err
+		}
+	}
+
+	return t.printMessage(m, macKey)
+}
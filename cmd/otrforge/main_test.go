@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/coyim/otr3"
+	"github.com/coyim/otr3/forge"
+)
+
+var aesKey = []byte("0123456789abcdef")
+var aesKeyHex = hex.EncodeToString(aesKey)
+var macKeyHex = hex.EncodeToString(forge.MACKeyFor(aesKey))
+
+func fixtureMessage() string {
+	m := &forge.Message{}
+	m.Type = otr3.MessageTypeData
+	m.Version = 3
+	m.SenderInstanceTag, m.ReceiverInstanceTag = 0x101, 0x202
+	m.SenderKeyID, m.RecipientKeyID = 1, 2
+	m.NextDHPublicKey = big.NewInt(0x1234)
+	m.TopHalfCounter = [8]byte{0, 0, 0, 0, 0, 0, 0, 1}
+	m.Encrypt(aesKey, []byte("attack at dawn\x00\x00\x01\x00\x00"))
+	m.ReMAC(forge.MACKeyFor(aesKey))
+	encoded, _ := m.Encode()
+	return string(encoded)
+}
+
+func runTool(stdin string, args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, strings.NewReader(stdin), stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func parseOutput(t *testing.T, out string) *forge.Message {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	m, err := forge.Parse([]byte(lines[len(lines)-1]))
+	if err != nil {
+		t.Fatalf("expected a data message, got %q: %v", out, err)
+	}
+	return m
+}
+
+func decrypt(t *testing.T, m *forge.Message) string {
+	plain, err := m.Decrypt(aesKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(plain)
+}
+
+func Test_read_showsThePlaintext(t *testing.T) {
+	code, out, _ := runTool("", "read", aesKeyHex, fixtureMessage())
+
+	if code != 0 || out != "Message: attack at dawn\nTLVs: 00010000\n" {
+		t.Errorf("expected the plaintext, got %d %q", code, out)
+	}
+}
+
+func Test_read_replacesTheTextAndKeepsTheTLVs(t *testing.T) {
+	code, out, _ := runTool(fixtureMessage()+"\n", "read", aesKeyHex, "-", "retreat")
+
+	m := parseOutput(t, out)
+	if code != 0 || decrypt(t, m) != "retreat\x00\x00\x01\x00\x00" {
+		t.Errorf("expected the new text, got %d %q", code, decrypt(t, m))
+	}
+	if !m.VerifyMAC(forge.MACKeyFor(aesKey)) {
+		t.Errorf("expected a valid MAC")
+	}
+}
+
+func Test_modify_changesKnownPlaintext(t *testing.T) {
+	code, out, _ := runTool("", "modify", "-mackey", macKeyHex, fixtureMessage(), "10", "dawn", "dusk")
+
+	m := parseOutput(t, out)
+	if code != 0 || decrypt(t, m) != "attack at dusk\x00\x00\x01\x00\x00" {
+		t.Errorf("expected the changed text, got %d %q", code, decrypt(t, m))
+	}
+	if !m.VerifyMAC(forge.MACKeyFor(aesKey)) {
+		t.Errorf("expected a valid MAC")
+	}
+}
+
+func Test_modify_changesBytesGivenInHex(t *testing.T) {
+	code, out, _ := runTool("", "modify", "-x", fixtureMessage(), "16", "01", "02")
+
+	m := parseOutput(t, out)
+	if code != 0 || decrypt(t, m) != "attack at dawn\x00\x00\x02\x00\x00" {
+		t.Errorf("expected the changed TLV, got %d %q", code, decrypt(t, m))
+	}
+	if m.VerifyMAC(forge.MACKeyFor(aesKey)) {
+		t.Errorf("expected the MAC to be left alone without a MAC key")
+	}
+}
+
+func Test_modify_failsForChangesThatDontFit(t *testing.T) {
+	code, _, stderr := runTool("", "modify", fixtureMessage(), "18", "dawn", "dusk")
+
+	if code != 1 || !strings.Contains(stderr, forge.ErrOutOfRange.Error()) {
+		t.Errorf("expected an error, got %d %q", code, stderr)
+	}
+}
+
+func Test_remac_changesFieldsAndCalculatesANewMAC(t *testing.T) {
+	code, out, _ := runTool("", "remac", "-flags", "1", "-sender", "7", "-recipient", "8", "-counter", "0000000000000002", macKeyHex, fixtureMessage())
+
+	m := parseOutput(t, out)
+	if code != 0 || m.Flags != 1 || m.SenderKeyID != 7 || m.RecipientKeyID != 8 || m.TopHalfCounter[7] != 2 {
+		t.Errorf("expected the fields to change, got %d %q", code, out)
+	}
+	if !m.VerifyMAC(forge.MACKeyFor(aesKey)) {
+		t.Errorf("expected a valid MAC")
+	}
+}
+
+func Test_run_failsForInvalidArguments(t *testing.T) {
+	if code, _, _ := runTool(""); code != 2 {
+		t.Errorf("expected a usage error without a command, got %d", code)
+	}
+	if code, _, _ := runTool("", "unknown"); code != 2 {
+		t.Errorf("expected a usage error for an unknown command, got %d", code)
+	}
+	if code, _, stderr := runTool("", "read", aesKeyHex); code != 2 || !strings.Contains(stderr, "usage: otrforge read") {
+		t.Errorf("expected a usage error, got %d %q", code, stderr)
+	}
+	if code, _, stderr := runTool("", "read", "xyz", fixtureMessage()); code != 1 || !strings.Contains(stderr, "not valid hex") {
+		t.Errorf("expected a hex error, got %d %q", code, stderr)
+	}
+	if code, _, stderr := runTool("", "read", aesKeyHex, "?OTRv3?"); code != 1 || !strings.Contains(stderr, "not a data message") {
+		t.Errorf("expected an error for other messages, got %d %q", code, stderr)
+	}
+}
@@ -0,0 +1,299 @@
+// Command otrkey manages libotr formatted private key files.
+//
+// Usage:
+//
+//	otrkey [-f file] [-encrypted] <command> [arguments]
+//
+// The commands are:
+//
+//	generate [-force] <account> <protocol>   generate a new DSA key for an account
+//	list                                      list all accounts and their fingerprints
+//	export [-o file] <account> <protocol>     write one account as a libotr privkeys file
+//	import [-i file]                          add or replace all accounts from a libotr privkeys file
+//	merge <file>...                           add or replace all accounts from other key files
+//	delete <account> <protocol>               remove an account
+//
+// The key file defaults to ~/.otr3/otr.private_key. With -encrypted, the key file is encrypted with the passphrase
+// in the OTRKEY_PASSPHRASE environment variable. Plain key files are still read, and are encrypted when changed.
+// Files read by import and merge can be encrypted with the same passphrase, and export always writes a plain file.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/coyim/otr3"
+)
+
+const passphraseEnv = "OTRKEY_PASSPHRASE"
+
+type command struct {
+	name, usage string
+	run         func(t *tool, args []string) error
+}
+
+var commands = []command{
+	{"generate", "[-force] <account> <protocol>", generate},
+	{"list", "", list},
+	{"export", "[-o file] <account> <protocol>", export},
+	{"import", "[-i file]", importKeys},
+	{"merge", "<file>...", merge},
+	{"delete", "<account> <protocol>", deleteAccount},
+}
+
+// tool keeps what all commands need, so that they can be run from tests
+type tool struct {
+	keyFile    string
+	keyring    *otr3.FileKeyring
+	passphrase []byte
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+var errUsage = errors.New("invalid usage")
+
+func defaultKeyFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".otr3", "otr.private_key")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv(passphraseEnv), os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, passphrase string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("otrkey", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	fname := flags.String("f", defaultKeyFile(), "the libotr private key file to use")
+	encrypted := flags.Bool("encrypted", false, "encrypt the key file with the passphrase in "+passphraseEnv)
+	flags.Usage = func() { usage(flags, stderr) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	t := &tool{keyFile: *fname, stdin: stdin, stdout: stdout, stderr: stderr}
+	if *encrypted {
+		if passphrase == "" {
+			fmt.Fprintf(stderr, "otrkey: %s must be set when using -encrypted\n", passphraseEnv)
+			return 2
+		}
+		t.passphrase = []byte(passphrase)
+		t.keyring = otr3.NewEncryptedFileKeyring(*fname, t.passphrase)
+	} else {
+		t.keyring = otr3.NewFileKeyring(*fname)
+	}
+
+	for _, c := range commands {
+		if c.name == flags.Arg(0) {
+			err := c.run(t, flags.Args()[1:])
+			if err == errUsage {
+				fmt.Fprintf(stderr, "usage: otrkey %s %s\n", c.name, c.usage)
+				return 2
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "otrkey: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(stderr, "otrkey: unknown command %q\n", flags.Arg(0))
+	flags.Usage()
+	return 2
+}
+
+func usage(flags *flag.FlagSet, w io.Writer) {
+	fmt.Fprintf(w, "usage: otrkey [-f file] [-encrypted] <command> [arguments]\n\nThe commands are:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(w, "\nThe flags are:\n")
+	flags.PrintDefaults()
+}
+
+func accountAndProtocol(flags *flag.FlagSet, args []string) (account, protocol string, err error) {
+	if err := flags.Parse(args); err != nil {
+		return "", "", errUsage
+	}
+	if flags.NArg() != 2 {
+		return "", "", errUsage
+	}
+	return flags.Arg(0), flags.Arg(1), nil
+}
+
+func newFlagSet(name string, t *tool) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(t.stderr)
+	flags.Usage = func() {}
+	return flags
+}
+
+func generate(t *tool, args []string) error {
+	flags := newFlagSet("generate", t)
+	force := flags.Bool("force", false, "replace the existing key of the account")
+	account, protocol, err := accountAndProtocol(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var existing [][]byte
+	if a, ok, err := t.keyring.Get(account, protocol); err != nil {
+		return err
+	} else if ok && !*force {
+		existing = append(existing, a.Key.Serialize())
+	}
+
+	keys, err := otr3.GenerateMissingKeys(existing)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("%s (%s) already has a key - use -force to replace it", account, protocol)
+	}
+
+	a := &otr3.Account{Name: account, Protocol: protocol, Key: keys[0]}
+	if err := t.makeKeyDir(); err != nil {
+		return err
+	}
+	if err := t.keyring.Put(a); err != nil {
+		return err
+	}
+	printAccount(t.stdout, a)
+	return nil
+}
+
+func printAccount(w io.Writer, a *otr3.Account) {
+	fmt.Fprintf(w, "%s\t%s\t%s\n", a.Name, a.Protocol, otr3.FormatFingerprint(a.Key.PublicKey().Fingerprint()))
+}
+
+func list(t *tool, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	acs, err := t.keyring.List()
+	if err != nil {
+		return err
+	}
+	for _, a := range acs {
+		printAccount(t.stdout, a)
+	}
+	return nil
+}
+
+func export(t *tool, args []string) error {
+	flags := newFlagSet("export", t)
+	out := flags.String("o", "", "the file to write to, instead of standard output")
+	account, protocol, err := accountAndProtocol(flags, args)
+	if err != nil {
+		return err
+	}
+
+	a, ok, err := t.keyring.Get(account, protocol)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no key for %s (%s)", account, protocol)
+	}
+
+	if *out != "" {
+		return exportToFile(a, *out)
+	}
+	return otr3.ExportKeys([]*otr3.Account{a}, t.stdout)
+}
+
+// exportToFile writes the key without encryption, so the file is made readable only by the current user
+func exportToFile(a *otr3.Account, fname string) error {
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := f.Chmod(0600); err != nil {
+		return err
+	}
+	return otr3.ExportKeys([]*otr3.Account{a}, f)
+}
+
+func importKeys(t *tool, args []string) error {
+	flags := newFlagSet("import", t)
+	in := flags.String("i", "", "the file to read from, instead of standard input")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	r := t.stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	acs, err := otr3.ImportKeysWithPassphrase(r, t.passphrase)
+	if err != nil {
+		return err
+	}
+	return putAll(t, acs)
+}
+
+func merge(t *tool, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	var acs []*otr3.Account
+	for _, fname := range args {
+		res, err := otr3.ImportKeysFromFileWithPassphrase(fname, t.passphrase)
+		if err != nil {
+			return fmt.Errorf("%s: %v", fname, err)
+		}
+		acs = append(acs, res...)
+	}
+	return putAll(t, acs)
+}
+
+// makeKeyDir creates the directory of the key file, since the default one doesn't exist on a new machine
+func (t *tool) makeKeyDir() error {
+	return os.MkdirAll(filepath.Dir(t.keyFile), 0700)
+}
+
+func putAll(t *tool, acs []*otr3.Account) error {
+	if err := t.makeKeyDir(); err != nil {
+		return err
+	}
+	if err := t.keyring.PutAll(acs...); err != nil {
+		return err
+	}
+	for _, a := range acs {
+		printAccount(t.stdout, a)
+	}
+	return nil
+}
+
+func deleteAccount(t *tool, args []string) error {
+	account, protocol, err := accountAndProtocol(newFlagSet("delete", t), args)
+	if err != nil {
+		return err
+	}
+
+	if _, ok, err := t.keyring.Get(account, protocol); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("no key for %s (%s)", account, protocol)
+	}
+	return t.keyring.Delete(account, protocol)
+}
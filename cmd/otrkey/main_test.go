@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coyim/otr3"
+)
+
+var alicePrivateKeyHex = "000000000080c81c2cb2eb729b7e6fd48e975a932c638b3a9055478583afa46755683e30102447f6da2d8bec9f386bbb5da6403b0040fee8650b6ab2d7f32c55ab017ae9b6aec8c324ab5844784e9a80e194830d548fb7f09a0410df2c4d5c8bc2b3e9ad484e65412be689cf0834694e0839fb2954021521ffdffb8f5c32c14dbf2020b3ce7500000014da4591d58def96de61aea7b04a8405fe1609308d000000808ddd5cb0b9d66956e3dea5a915d9aba9d8a6e7053b74dadb2fc52f9fe4e5bcc487d2305485ed95fed026ad93f06ebb8c9e8baf693b7887132c7ffdd3b0f72f4002ff4ed56583ca7c54458f8c068ca3e8a4dfa309d1dd5d34e2a4b68e6f4338835e5e0fb4317c9e4c7e4806dafda3ef459cd563775a586dd91b1319f72621bf3f00000080b8147e74d8c45e6318c37731b8b33b984a795b3653c2cd1d65cc99efe097cb7eb2fa49569bab5aab6e8a1c261a27d0f7840a5e80b317e6683042b59b6dceca2879c6ffc877a465be690c15e4a42f9a7588e79b10faac11b1ce3741fcef7aba8ce05327a2c16d279ee1b3d77eb783fb10e3356caa25635331e26dd42b8396c4d00000001420bec691fea37ecea58a5c717142f0b804452f57"
+var bobPrivateKeyHex = "000000000080a5138eb3d3eb9c1d85716faecadb718f87d31aaed1157671d7fee7e488f95e8e0ba60ad449ec732710a7dec5190f7182af2e2f98312d98497221dff160fd68033dd4f3a33b7c078d0d9f66e26847e76ca7447d4bab35486045090572863d9e4454777f24d6706f63e02548dfec2d0a620af37bbc1d24f884708a212c343b480d00000014e9c58f0ea21a5e4dfd9f44b6a9f7f6a9961a8fa9000000803c4d111aebd62d3c50c2889d420a32cdf1e98b70affcc1fcf44d59cca2eb019f6b774ef88153fb9b9615441a5fe25ea2d11b74ce922ca0232bd81b3c0fcac2a95b20cb6e6c0c5c1ace2e26f65dc43c751af0edbb10d669890e8ab6beea91410b8b2187af1a8347627a06ecea7e0f772c28aae9461301e83884860c9b656c722f0000008065af8625a555ea0e008cd04743671a3cda21162e83af045725db2eb2bb52712708dc0cc1a84c08b3649b88a966974bde27d8612c2861792ec9f08786a246fcadd6d8d3a81a32287745f309238f47618c2bd7612cb8b02d940571e0f30b96420bcd462ff542901b46109b1e5ad6423744448d20a57818a8cbb1647d0fea3b664e0000001440f9f2eb554cb00d45a5826b54bfa419b6980e48"
+
+func parseKey(t *testing.T, h string) otr3.PrivateKey {
+	b, _ := hex.DecodeString(h)
+	_, ok, key := otr3.ParsePrivateKey(b)
+	if !ok {
+		t.Fatalf("couldn't parse fixture key")
+	}
+	return key
+}
+
+func fingerprintOf(key otr3.PrivateKey) string {
+	return otr3.FormatFingerprint(key.PublicKey().Fingerprint())
+}
+
+type testTool struct {
+	t          *testing.T
+	dir        string
+	passphrase string
+}
+
+func newTestTool(t *testing.T) *testTool {
+	dir, err := ioutil.TempDir("", "otrkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testTool{t: t, dir: dir}
+}
+
+func (tt *testTool) close() {
+	os.RemoveAll(tt.dir)
+}
+
+func (tt *testTool) file(name string) string {
+	return filepath.Join(tt.dir, name)
+}
+
+func (tt *testTool) writeKeys(name string, acs ...*otr3.Account) string {
+	fname := tt.file(name)
+	if err := otr3.ExportKeysToFile(acs, fname); err != nil {
+		tt.t.Fatal(err)
+	}
+	return fname
+}
+
+func (tt *testTool) runWithInput(in string, args ...string) (stdout, stderr string, code int) {
+	var out, errOut bytes.Buffer
+	args = append([]string{"-f", tt.file("keys")}, args...)
+	code = run(args, tt.passphrase, strings.NewReader(in), &out, &errOut)
+	return out.String(), errOut.String(), code
+}
+
+func (tt *testTool) run(args ...string) (stdout, stderr string, code int) {
+	return tt.runWithInput("", args...)
+}
+
+func (tt *testTool) mustRun(args ...string) string {
+	out, errOut, code := tt.run(args...)
+	if code != 0 {
+		tt.t.Fatalf("otrkey %v failed with %d: %s", args, code, errOut)
+	}
+	return out
+}
+
+func (tt *testTool) fixtureAccounts() []*otr3.Account {
+	return []*otr3.Account{
+		{Name: "alice@example.org", Protocol: "prpl-jabber", Key: parseKey(tt.t, alicePrivateKeyHex)},
+		{Name: "bob@example.org", Protocol: "prpl-jabber", Key: parseKey(tt.t, bobPrivateKeyHex)},
+	}
+}
+
+func Test_list_printsAllAccountsWithTheirFingerprints(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	acs := tt.fixtureAccounts()
+	tt.writeKeys("keys", acs...)
+
+	out := tt.mustRun("list")
+
+	expected := "alice@example.org\tprpl-jabber\t" + fingerprintOf(acs[0].Key) + "\n" +
+		"bob@example.org\tprpl-jabber\t" + fingerprintOf(acs[1].Key) + "\n"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func Test_list_printsNothingForAMissingFile(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+
+	if out := tt.mustRun("list"); out != "" {
+		t.Errorf("expected no output, got %q", out)
+	}
+}
+
+func Test_generate_createsANewKey(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+
+	out := tt.mustRun("generate", "alice@example.org", "prpl-jabber")
+
+	acs, err := otr3.ImportKeysFromFile(tt.file("keys"))
+	if err != nil || len(acs) != 1 {
+		t.Fatalf("expected one account, got %v, %v", acs, err)
+	}
+	if out != "alice@example.org\tprpl-jabber\t"+fingerprintOf(acs[0].Key)+"\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func Test_generate_createsTheDirectoryOfTheKeyFile(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	fname := filepath.Join(tt.dir, "new", "dir", "keys")
+
+	var out, errOut bytes.Buffer
+	code := run([]string{"-f", fname, "generate", "alice@example.org", "prpl-jabber"}, "", strings.NewReader(""), &out, &errOut)
+
+	if code != 0 {
+		t.Fatalf("expected generate to work, got %d: %s", code, errOut.String())
+	}
+	fi, err := os.Stat(filepath.Dir(fname))
+	if err != nil || fi.Mode().Perm() != 0700 {
+		t.Errorf("expected a directory with mode 0700, got %v, %v", fi, err)
+	}
+}
+
+func Test_generate_refusesToReplaceAKeyUnlessForced(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	acs := tt.fixtureAccounts()
+	tt.writeKeys("keys", acs...)
+
+	_, errOut, code := tt.run("generate", "alice@example.org", "prpl-jabber")
+
+	if code != 1 || !strings.Contains(errOut, "already has a key") {
+		t.Errorf("expected an error, got %d: %q", code, errOut)
+	}
+	if out := tt.mustRun("list"); !strings.Contains(out, fingerprintOf(acs[0].Key)) {
+		t.Errorf("expected the key to be left alone, got %q", out)
+	}
+}
+
+func Test_export_writesOneAccount(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	acs := tt.fixtureAccounts()
+	tt.writeKeys("keys", acs...)
+
+	out := tt.mustRun("export", "bob@example.org", "prpl-jabber")
+	tt.mustRun("export", "-o", tt.file("bob"), "bob@example.org", "prpl-jabber")
+
+	res, err := otr3.ImportKeys(strings.NewReader(out))
+	res2, err2 := otr3.ImportKeysFromFile(tt.file("bob"))
+	if err != nil || len(res) != 1 || res[0].Name != "bob@example.org" {
+		t.Errorf("expected bob's account, got %v, %v", res, err)
+	}
+	if err2 != nil || len(res2) != 1 || fingerprintOf(res2[0].Key) != fingerprintOf(acs[1].Key) {
+		t.Errorf("expected bob's account, got %v, %v", res2, err2)
+	}
+}
+
+func Test_export_writesTheFileReadableOnlyByTheCurrentUser(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	tt.writeKeys("keys", tt.fixtureAccounts()...)
+	ioutil.WriteFile(tt.file("bob"), nil, 0644)
+
+	tt.mustRun("export", "-o", tt.file("bob"), "bob@example.org", "prpl-jabber")
+
+	fi, err := os.Stat(tt.file("bob"))
+	if err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v, %v", fi, err)
+	}
+}
+
+func Test_export_failsForAnUnknownAccount(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	tt.writeKeys("keys", tt.fixtureAccounts()...)
+
+	_, errOut, code := tt.run("export", "carol@example.org", "prpl-jabber")
+
+	if code != 1 || errOut != "otrkey: no key for carol@example.org (prpl-jabber)\n" {
+		t.Errorf("expected an error, got %d: %q", code, errOut)
+	}
+}
+
+func Test_import_addsAccountsFromStandardInputOrAFile(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	acs := tt.fixtureAccounts()
+	tt.writeKeys("keys", acs[0])
+	in := &bytes.Buffer{}
+	otr3.ExportKeys(acs[1:], in)
+
+	_, errOut, code := tt.runWithInput(in.String(), "import")
+	if code != 0 {
+		t.Fatalf("import failed: %s", errOut)
+	}
+
+	res, _ := otr3.ImportKeysFromFile(tt.file("keys"))
+	if len(res) != 2 || res[1].Name != "bob@example.org" {
+		t.Errorf("expected both accounts, got %v", res)
+	}
+
+	tt.mustRun("import", "-i", tt.writeKeys("other", &otr3.Account{Name: "carol@example.org", Protocol: "prpl-irc", Key: acs[0].Key}))
+	if res, _ = otr3.ImportKeysFromFile(tt.file("keys")); len(res) != 3 {
+		t.Errorf("expected three accounts, got %v", res)
+	}
+}
+
+func Test_merge_addsAndReplacesAccountsFromAllFiles(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	acs := tt.fixtureAccounts()
+	tt.writeKeys("keys", acs[0])
+	first := tt.writeKeys("first", acs[1])
+	second := tt.writeKeys("second", &otr3.Account{Name: "alice@example.org", Protocol: "prpl-jabber", Key: acs[1].Key})
+
+	tt.mustRun("merge", first, second)
+
+	res, _ := otr3.ImportKeysFromFile(tt.file("keys"))
+	if len(res) != 2 || fingerprintOf(res[0].Key) != fingerprintOf(acs[1].Key) || res[1].Name != "bob@example.org" {
+		t.Errorf("expected alice's key to be replaced and bob to be added, got %v", res)
+	}
+}
+
+func Test_merge_failsWithoutChangesIfAFileIsInvalid(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	acs := tt.fixtureAccounts()
+	tt.writeKeys("keys", acs[0])
+	first := tt.writeKeys("first", acs[1])
+
+	_, errOut, code := tt.run("merge", first, tt.file("doesnt_exist"))
+
+	if code != 1 || !strings.Contains(errOut, "doesnt_exist") {
+		t.Errorf("expected an error, got %d: %q", code, errOut)
+	}
+	if res, _ := otr3.ImportKeysFromFile(tt.file("keys")); len(res) != 1 {
+		t.Errorf("expected the key file to be left alone, got %v", res)
+	}
+}
+
+func Test_delete_removesAnAccount(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	tt.writeKeys("keys", tt.fixtureAccounts()...)
+
+	tt.mustRun("delete", "alice@example.org", "prpl-jabber")
+	_, errOut, code := tt.run("delete", "alice@example.org", "prpl-jabber")
+
+	if out := tt.mustRun("list"); strings.Contains(out, "alice") {
+		t.Errorf("expected alice to be deleted, got %q", out)
+	}
+	if code != 1 || errOut != "otrkey: no key for alice@example.org (prpl-jabber)\n" {
+		t.Errorf("expected an error, got %d: %q", code, errOut)
+	}
+}
+
+func Test_encrypted_keepsTheKeyFileEncrypted(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+	tt.passphrase = "secret"
+	tt.writeKeys("keys", tt.fixtureAccounts()...)
+
+	tt.mustRun("-encrypted", "delete", "bob@example.org", "prpl-jabber")
+
+	data, _ := ioutil.ReadFile(tt.file("keys"))
+	if !otr3.IsEncryptedKeyData(data) {
+		t.Errorf("expected the key file to be encrypted")
+	}
+	if out := tt.mustRun("-encrypted", "list"); !strings.HasPrefix(out, "alice@example.org") {
+		t.Errorf("expected alice to be listed, got %q", out)
+	}
+
+	tt.passphrase = "wrong"
+	if _, _, code := tt.run("-encrypted", "list"); code != 1 {
+		t.Errorf("expected the wrong passphrase to fail, got %d", code)
+	}
+}
+
+func Test_run_reportsUsageErrors(t *testing.T) {
+	tt := newTestTool(t)
+	defer tt.close()
+
+	for _, args := range [][]string{
+		{},
+		{"frobnicate"},
+		{"generate", "alice@example.org"},
+		{"list", "extra"},
+		{"merge"},
+		{"import", "extra"},
+		{"-encrypted", "list"},
+	} {
+		if _, _, code := tt.run(args...); code != 2 {
+			t.Errorf("expected usage error for %v, got %d", args, code)
+		}
+	}
+}
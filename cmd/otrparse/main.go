@@ -0,0 +1,95 @@
+// Command otrparse shows what OTR messages contain, like otr_parse from the libotr toolkit.
+//
+// Usage:
+//
+//	otrparse [message...]
+//
+// Each argument is parsed as a message. Without arguments, messages are read from standard input, one per line.
+// Anything before the first "?OTR" on a line is ignored, so lines can be pasted directly from logs. When all
+// fragments of a message have been seen, the message they make up is shown as well.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/coyim/otr3"
+)
+
+func main() {
+	p := &parser{out: os.Stdout}
+
+	if len(os.Args) > 1 {
+		for _, m := range os.Args[1:] {
+			p.parseLine(m)
+		}
+	} else {
+		s := bufio.NewScanner(os.Stdin)
+		for s.Scan() {
+			p.parseLine(s.Text())
+		}
+	}
+
+	if p.failed {
+		os.Exit(1)
+	}
+}
+
+// parser shows the messages it is given, and puts fragments back together
+type parser struct {
+	out    io.Writer
+	failed bool
+
+	fragments            []byte
+	fragmentIndex, count uint16
+}
+
+func (p *parser) parseLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if i := strings.Index(line, "?OTR"); i > 0 {
+		line = line[i:]
+	}
+
+	m := p.parse([]byte(line))
+	if m.Type == otr3.MessageTypeFragment {
+		p.addFragment(m)
+	}
+}
+
+func (p *parser) parse(msg []byte) otr3.ParsedMessage {
+	m, err := otr3.ParseMessage(msg)
+	fmt.Fprint(p.out, m)
+	if err != nil {
+		p.failed = true
+		fmt.Fprintf(p.out, "\tError: %v\n", err)
+	}
+	fmt.Fprintln(p.out)
+	return m
+}
+
+func (p *parser) addFragment(m otr3.ParsedMessage) {
+	switch {
+	case m.FragmentIndex == 1:
+		p.fragments = append([]byte{}, m.FragmentData...)
+	case m.FragmentIndex == p.fragmentIndex+1 && m.FragmentCount == p.count:
+		p.fragments = append(p.fragments, m.FragmentData...)
+	default:
+		p.fragments = nil
+		p.fragmentIndex, p.count = 0, 0
+		return
+	}
+	p.fragmentIndex, p.count = m.FragmentIndex, m.FragmentCount
+
+	if p.fragmentIndex == p.count {
+		fmt.Fprintf(p.out, "Reassembled from %d fragments:\n", p.count)
+		p.parse(p.fragments)
+		p.fragments = nil
+		p.fragmentIndex, p.count = 0, 0
+	}
+}
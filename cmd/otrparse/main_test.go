@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func parseLines(lines ...string) (string, bool) {
+	out := &bytes.Buffer{}
+	p := &parser{out: out}
+	for _, l := range lines {
+		p.parseLine(l)
+	}
+	return out.String(), p.failed
+}
+
+func Test_parseLine_showsTheMessage(t *testing.T) {
+	out, failed := parseLines("?OTRv3? hello", "", "alice: ?OTR Error: oops")
+
+	expected := "Query message:\n\tVersions: [3]\n\tMessage: hello\n\n" +
+		"Error message:\n\tMessage: oops\n\n"
+	if out != expected || failed {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func Test_parseLine_showsErrors(t *testing.T) {
+	out, failed := parseLines("?OTR:!!!!.")
+
+	if !strings.Contains(out, "\tError: otr: invalid OTR message\n") || !failed {
+		t.Errorf("expected an error, got %q", out)
+	}
+}
+
+func Test_parseLine_reassemblesFragments(t *testing.T) {
+	// A DH-Key message split in three
+	out, failed := parseLines(
+		"?OTR|00000100|00000201,00001,00003,?OTR:AAMKAAABAAAAAgEAAAAB,",
+		"?OTR|00000100|00000201,00002,00003,Ag==,",
+		"?OTR|00000100|00000201,00003,00003,.,",
+	)
+
+	if failed {
+		t.Errorf("expected no errors, got %q", out)
+	}
+	if !strings.Contains(out, "Reassembled from 3 fragments:\nD-H Key Message:\n\tVersion: 3\n\tSender instance: 00000100\n\tReceiver instance: 00000201\n\tg^y: 02\n") {
+		t.Errorf("expected the reassembled message, got %q", out)
+	}
+}
+
+func Test_parseLine_forgetsFragmentsOutOfOrder(t *testing.T) {
+	out, _ := parseLines(
+		"?OTR,00001,00002,?OTR:AAIK,",
+		"?OTR,00001,00003,AAAAAQI=.,",
+		"?OTR,00002,00002,AAAAAQI=.,",
+	)
+
+	if strings.Contains(out, "Reassembled") {
+		t.Errorf("expected no reassembled message, got %q", out)
+	}
+}
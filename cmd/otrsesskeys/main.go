@@ -0,0 +1,110 @@
+// Command otrsesskeys shows the session keys that come from a Diffie-Hellman private exponent and the public value of
+// the peer, like otr_sesskeys from the libotr toolkit.
+//
+// Usage:
+//
+//	otrsesskeys [-v 2|3] <our private exponent> <their public value>
+//
+// Both numbers are given in hex. Spaces and colons are ignored, so values can be copied from otrparse or a debugger.
+// Without -v, the keys are shown for both protocol versions 2 and 3.
+//
+// Anyone who learns one of the private exponents of a conversation can calculate all of these keys, which is part of
+// what makes OTR conversations deniable.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/coyim/otr3"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("otrsesskeys", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	version := flags.Int("v", 0, "only show the keys for this protocol version")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: otrsesskeys [-v 2|3] <our private exponent> <their public value>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	ourPrivateKey, err1 := parseHex(flags.Arg(0))
+	theirPublicKey, err2 := parseHex(flags.Arg(1))
+	if err1 != nil || err2 != nil {
+		fmt.Fprintln(stderr, "otrsesskeys: the keys must be given in hex")
+		return 2
+	}
+
+	versions := []int{2, 3}
+	if *version != 0 {
+		versions = []int{*version}
+	}
+
+	for i, v := range versions {
+		keys, err := otr3.CalculateSessionKeys(ourPrivateKey, theirPublicKey, v)
+		if err != nil {
+			fmt.Fprintf(stderr, "otrsesskeys: %v\n", err)
+			return 1
+		}
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		printKeys(stdout, v, keys)
+	}
+
+	return 0
+}
+
+var errNotHex = errors.New("not a hex number")
+
+func parseHex(s string) (*big.Int, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == ':' || r == '\t' {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		return nil, errNotHex
+	}
+	return n, nil
+}
+
+func printKeys(w io.Writer, version int, keys otr3.SessionKeys) {
+	line := func(name string, b []byte) {
+		fmt.Fprintf(w, "\t%-20s %X\n", name+":", b)
+	}
+
+	fmt.Fprintf(w, "Version %d:\n", version)
+	fmt.Fprintf(w, "\t%-20s %X %X\n", "Session id:", keys.SSID[:4], keys.SSID[4:])
+	line("Sending AES key", keys.SendingAESKey)
+	line("Sending MAC key", keys.SendingMACKey)
+	line("Receiving AES key", keys.ReceivingAESKey)
+	line("Receiving MAC key", keys.ReceivingMACKey)
+	line("Extra symmetric key", keys.ExtraKey)
+	line("AKE c", keys.RevealSigAESKey)
+	line("AKE m1", keys.RevealSigMAC1)
+	line("AKE m2", keys.RevealSigMAC2)
+	line("AKE c'", keys.SignatureAESKey)
+	line("AKE m1'", keys.SignatureMAC1)
+	line("AKE m2'", keys.SignatureMAC2)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const ourPrivateKey = "bbcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcd"
+const ourPublicKey = "75dfab5a1eab059052d0ad881c4938d52669630d61833a367155d67d03a457f619683d0fa829781e974fd24f6865e8128a9312a167b77326a87dea032fc31784d05b18b9cbafebe162ae9b5369f8b0c5911cf1be757f45f2a674be5126a714a6366c28086b3c7088911dcc4e5fb1481ad70a5237b8e4a6aff4954c2ca6df338b9f08691e4c0defe12689b37d4df30ddef2687f789fcf623c5d0cf6f09b7e5e69f481d5fd1b24a77636fb676e6d733d129eb93e81189340233044766a36eb07d"
+const theirPrivateKey = "abcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcd"
+const theirPublicKey = "2cdacabb00e63d8949aa85f7e6a095b1ee81a60779e58f8938ff1a7ed1e651d954bd739162e699cc73b820728af53aae60a46d529620792ddf839c5d03d2d4e92137a535b27500e3b3d34d59d0cd460d1f386b5eb46a7404b15c1ef84840697d2d3d2405dcdda351014d24a8717f7b9c51f6c84de365fea634737ae18ba22253a8e15249d9beb2dded640c6c0d74e4f7e19161cf828ce3ffa9d425fb68c0fddcaa7cbe81a7a5c2c595cce69a255059d9e5c04b49fb15901c087e225da850ff27"
+
+func runTool(args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func keyLine(out, name string) string {
+	for _, l := range strings.Split(out, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), name+":") {
+			return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), name+":"))
+		}
+	}
+	return ""
+}
+
+func Test_run_printsTheKeysForBothVersions(t *testing.T) {
+	code, out, _ := runTool(ourPrivateKey, theirPublicKey)
+
+	if code != 0 {
+		t.Fatalf("expected success, got %d", code)
+	}
+	if !strings.HasPrefix(out, "Version 2:\n") || !strings.Contains(out, "\n\nVersion 3:\n") {
+		t.Errorf("expected keys for both versions, got %q", out)
+	}
+	if !strings.Contains(out, "\tSending AES key:     42E258BEBF031ACF442F52D6EF52D6F1\n") {
+		t.Errorf("expected the sending AES key, got %q", out)
+	}
+	if !strings.Contains(out, "\tExtra symmetric key: 0E1810C7C62C3BACE6450DCBEF16AF8A271B5AC93030B83E9D0D80E0641E3C18\n") {
+		t.Errorf("expected the extra symmetric key, got %q", out)
+	}
+}
+
+func Test_run_givesTheOtherSideTheSameSessionID(t *testing.T) {
+	_, ours, _ := runTool("-v", "3", ourPrivateKey, theirPublicKey)
+	_, theirs, _ := runTool("-v", "3", theirPrivateKey, "0x"+strings.ToUpper(ourPublicKey))
+
+	if keyLine(ours, "Session id") == "" || keyLine(ours, "Session id") != keyLine(theirs, "Session id") {
+		t.Errorf("expected the same session id, got %q and %q", ours, theirs)
+	}
+	if keyLine(ours, "Sending AES key") != keyLine(theirs, "Receiving AES key") {
+		t.Errorf("expected our sending key to be their receiving key, got %q and %q", ours, theirs)
+	}
+	if strings.Contains(ours, "Version 2") {
+		t.Errorf("expected only version 3, got %q", ours)
+	}
+}
+
+func Test_run_failsForInvalidArguments(t *testing.T) {
+	if code, _, _ := runTool(ourPrivateKey); code != 2 {
+		t.Errorf("expected a usage error for a missing key, got %d", code)
+	}
+
+	if code, _, stderr := runTool(ourPrivateKey, "xyz"); code != 2 || !strings.Contains(stderr, "hex") {
+		t.Errorf("expected an error for invalid hex, got %d %q", code, stderr)
+	}
+
+	if code, _, stderr := runTool("-v", "4", ourPrivateKey, theirPublicKey); code != 1 || !strings.Contains(stderr, "unsupported OTR version") {
+		t.Errorf("expected an error for an unsupported version, got %d %q", code, stderr)
+	}
+
+	if code, _, stderr := runTool(ourPrivateKey, "01"); code != 1 || !strings.Contains(stderr, "invalid DH key") {
+		t.Errorf("expected an error for an invalid public value, got %d %q", code, stderr)
+	}
+}
@@ -24,6 +24,7 @@ var (
 type Conversation struct {
 	version otrVersion
 	Rand    io.Reader
+	Clock   Clock
 
 	msgState        msgState
 	whitespaceState whitespaceState
@@ -54,6 +55,11 @@ type Conversation struct {
 	messageEventHandler  MessageEventHandler
 	securityEventHandler SecurityEventHandler
 	receivedKeyHandler   ReceivedKeyHandler
+	trustResolver        TrustResolver
+	logger               Logger
+	logSecrets           bool
+	metrics              Metrics
+	events               *eventStream
 
 	debug         bool
 	sentRevealSig bool
@@ -113,14 +119,16 @@ func (c *Conversation) IsEncrypted() bool {
 // the peer and switches to unencrypted communication.
 func (c *Conversation) End() (toSend []ValidMessage, err error) {
 	previousMsgState := c.msgState
+	defer c.logSMPStateChange(c.smpStateName())
 	if c.msgState == encrypted {
 		c.smp.wipe()
 		// Error can only happen when Rand reader is broken
 		toSend, _, err = c.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, []tlv{tlv{tlvType: tlvTypeDisconnected}})
 	}
 	c.lastMessageStateChange = time.Time{}
+	defer c.logAuthStateChange(c.authStateName())
 	c.ake = nil
-	c.msgState = plainText
+	c.setMsgState(plainText)
 	defer c.signalSecurityEventIf(previousMsgState == encrypted, GoneInsecure)
 
 	c.keys.ourCurrentDHKeys.wipe()
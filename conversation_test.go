@@ -45,7 +45,7 @@ func Test_receive_verifiesMessageProtocolVersion(t *testing.T) {
 
 	_, _, err := c.receiveDecoded(msg)
 
-	assertEquals(t, err, errWrongProtocolVersion)
+	assertEquals(t, err, ErrWrongProtocolVersion)
 }
 
 func Test_receive_returnsAnErrorForAnInvalidOTRMessageWithoutVersionData(t *testing.T) {
@@ -12,7 +12,7 @@ func (c *Conversation) genDataMsg(message []byte, tlvs ...tlv) (dataMsg, dataMes
 
 func (c *Conversation) genDataMsgWithFlag(message []byte, flag byte, tlvs ...tlv) (dataMsg, dataMessageExtra, error) {
 	if c.msgState != encrypted {
-		return dataMsg{}, dataMessageExtra{}, errCannotSendUnencrypted
+		return dataMsg{}, dataMessageExtra{}, ErrNotEncrypted
 	}
 
 	keys, err := c.keys.calculateDHSessionKeys(c.keys.ourKeyID-1, c.keys.theirKeyID, c.version)
@@ -51,7 +51,6 @@ func (c *Conversation) genDataMsgWithFlag(message []byte, flag byte, tlvs ...tlv
 		oldMACKeys:     c.keys.revealMACKeys(),
 	}
 
-	// fmt.Printf("sendingMACKey: len: %d %X\n", len(keys.sendingMACKey), keys.sendingMACKey)
 	dataMessage.sign(keys.sendingMACKey, header, c.version)
 
 	c.updateMayRetransmitTo(noRetransmit)
@@ -95,7 +94,11 @@ func (c *Conversation) encode(msg messageWithHeader) encodedMessage {
 func (c *Conversation) processDataMessage(header, msg []byte) (plain MessagePlaintext, toSend messageWithHeader, err error) {
 	ignoreUnreadable := (extractDataMessageFlag(msg) & messageFlagIgnoreUnreadable) == messageFlagIgnoreUnreadable
 	plain, toSend, err = c.processDataMessageWithRawErrors(header, msg)
+	if err != nil {
+		c.count(MetricDecryptionFailed)
+	}
 	if err != nil && ignoreUnreadable {
+		c.logRejectedMessage(msg, err)
 		err = nil
 	}
 	return
@@ -138,6 +141,7 @@ func (c *Conversation) processDataMessageWithRawErrors(header, msg []byte) (plai
 	if len(plain) == 0 {
 		plain = nil
 		c.messageEvent(MessageEventLogHeartbeatReceived)
+		c.count(MetricHeartbeatReceived)
 	}
 
 	err = c.rotateKeys(dataMessage)
@@ -180,6 +184,7 @@ func decideFlagFrom(tlvs []tlv) byte {
 }
 
 func (c *Conversation) processSMPTLV(t tlv, x dataMessageExtra) (toSend *tlv, err error) {
+	defer c.logSMPStateChange(c.smpStateName())
 	c.smp.ensureSMP()
 
 	smpMessage, ok := t.smpMessage()
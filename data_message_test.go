@@ -228,7 +228,7 @@ func Test_processDataMessage_returnsErrorIfSomethingGoesWrongWithDeserialize(t *
 	c.msgState = encrypted
 	_, _, err := c.processDataMessage([]byte{}, []byte{})
 
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize empty message")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid flags at offset 0")
 }
 
 func Test_processDataMessage_returnsErrorIfDataMessageHasWrongCounter(t *testing.T) {
@@ -339,7 +339,7 @@ func Test_processDataMessage_shouldNotRotateKeysWhenDecryptFails(t *testing.T) {
 	bob.msgState = encrypted
 	_, _, err := bob.receiveDecoded(msg)
 
-	assertDeepEquals(t, err, newOtrConflictError("bad signature MAC in encrypted signature"))
+	assertDeepEquals(t, err, &MACVerificationError{Message: "data message"})
 	assertDeepEquals(t, bobCurrentDHKeys, bob.keys.ourCurrentDHKeys)
 	assertDeepEquals(t, bobPreviousDHKeys, bob.keys.ourPreviousDHKeys)
 
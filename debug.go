@@ -2,6 +2,8 @@ package otr3
 
 import (
 	"bufio"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -14,7 +16,8 @@ var standardErrorOutput io.Writer = os.Stderr
 
 // SetDebug sets the debug mode for this conversation.
 // If debug mode is enabled, calls to Send with a message equals to "?OTR!"
-// will dump debug information about the current conversation state to stderr
+// will dump debug information about the current conversation state to stderr.
+// State and DumpState give the same information without needing debug mode.
 func (c *Conversation) SetDebug(d bool) {
 	c.debug = d
 }
@@ -35,54 +38,124 @@ func (c *Conversation) otrOffer() string {
 	}
 }
 
-func (c *Conversation) dump(w *bufio.Writer) {
-	w.WriteString("Context:\n\n")
-	w.WriteString(fmt.Sprintf("  Our instance:   %08X\n", c.ourInstanceTag))
-	w.WriteString(fmt.Sprintf("  Their instance: %08X\n\n", c.theirInstanceTag))
-	w.WriteString(fmt.Sprintf("  Msgstate: %d (%s)\n\n", c.msgState, c.msgState.identityString()))
-	w.WriteString(fmt.Sprintf("  Protocol version: %d\n", c.version.protocolVersion()))
-	w.WriteString(fmt.Sprintf("  OTR offer: %s\n\n", c.otrOffer()))
-	if c.ake == nil {
-		w.WriteString("  Auth info: NULL\n")
-	} else {
-		c.dumpAKE(w)
+// ConversationState is a snapshot of the state of a conversation, returned by State. It is meant for showing in
+// debugging tools and support bundles, so the states are given by name. It has no secret values.
+type ConversationState struct {
+	// MessageState is PLAINTEXT, ENCRYPTED or FINISHED
+	MessageState string
+	// AuthState is the state of the AKE - NONE, AWAITING_DHKEY, AWAITING_REVEALSIG or AWAITING_SIG
+	AuthState string
+	// SMPState is the next SMP message expected - EXPECT1, EXPECT1_WQ, EXPECT2, EXPECT3 or EXPECT4
+	SMPState            string
+	SMPQuestionReceived bool
+
+	// Version is the negotiated protocol version, or 0 if none has been negotiated yet
+	Version                          int
+	OurInstanceTag, TheirInstanceTag uint32
+	// OTROffer tells if we have offered OTR with a whitespace tag - NOT, SENT, ACCEPTED or REJECTED
+	OTROffer string
+
+	OurKeyID, TheirKeyID uint32
+	// Counters are the counters used with each pair of DH keys
+	Counters []KeyCounters
+
+	SSID                             [8]byte
+	OurFingerprint, TheirFingerprint []byte
+}
+
+// KeyCounters are the counters of the data messages sent and received with one pair of DH keys
+type KeyCounters struct {
+	OurKeyID, TheirKeyID     uint32
+	OurCounter, TheirCounter uint64
+}
+
+// State returns a snapshot of the current state of the conversation
+func (c *Conversation) State() ConversationState {
+	s := ConversationState{
+		MessageState:     c.msgState.identityString(),
+		AuthState:        c.authStateName(),
+		SMPState:         c.smpStateName(),
+		OurInstanceTag:   c.ourInstanceTag,
+		TheirInstanceTag: c.theirInstanceTag,
+		OTROffer:         c.otrOffer(),
+		OurKeyID:         c.keys.ourKeyID,
+		TheirKeyID:       c.keys.theirKeyID,
+		SSID:             c.ssid,
 	}
-	w.WriteString("\n")
 
-	c.dumpSMP(w)
+	s.SMPQuestionReceived = c.smp.question != nil
 
-	w.Flush()
-}
+	if c.version != nil {
+		s.Version = int(c.version.protocolVersion())
+	}
 
-// Will only be called if AKE is valid
-func (c *Conversation) dumpAKE(w *bufio.Writer) {
-	w.WriteString("  Auth info:\n")
+	for _, k := range c.keys.counterHistory.counters {
+		s.Counters = append(s.Counters, KeyCounters{k.ourKeyID, k.theirKeyID, k.ourCounter, k.theirCounter})
+	}
 
-	if c.ake != nil {
-		w.WriteString(fmt.Sprintf("    State: %d (%s)\n", c.ake.state.identity(), c.ake.state.identityString()))
+	if c.ourCurrentKey != nil {
+		s.OurFingerprint = c.ourCurrentKey.PublicKey().Fingerprint()
+	}
+	if c.theirKey != nil {
+		s.TheirFingerprint = c.theirKey.Fingerprint()
 	}
 
-	w.WriteString(fmt.Sprintf("    Our keyid:   %d\n", c.keys.ourKeyID))
-	w.WriteString(fmt.Sprintf("    Their keyid: %d\n", c.keys.theirKeyID))
-	w.WriteString(fmt.Sprintf("    Their fingerprint: %X\n", c.theirKey.Fingerprint()))
-	w.WriteString(fmt.Sprintf("    Proto version = %d\n", c.version.protocolVersion()))
-	w.Flush()
+	return s
 }
 
-func (c *Conversation) dumpSMP(w *bufio.Writer) {
-	w.WriteString("  SM state:\n")
+// DumpState writes the current state of the conversation to w, in a human readable format
+func (c *Conversation) DumpState(w io.Writer) error {
+	s := c.State()
+	bw := bufio.NewWriter(w)
 
-	if c.smp.state != nil {
-		w.WriteString(fmt.Sprintf("    Next expected: %d (%s)\n", c.smp.state.identity(), c.smp.state.identityString()))
-	}
+	fmt.Fprintf(bw, "Context:\n\n")
+	fmt.Fprintf(bw, "  Our instance:   %08X\n", s.OurInstanceTag)
+	fmt.Fprintf(bw, "  Their instance: %08X\n\n", s.TheirInstanceTag)
+	fmt.Fprintf(bw, "  Msgstate: %s\n\n", s.MessageState)
+	fmt.Fprintf(bw, "  Protocol version: %d\n", s.Version)
+	fmt.Fprintf(bw, "  OTR offer: %s\n\n", s.OTROffer)
 
-	receivedQ := 0
-	if c.smp.question != nil {
-		receivedQ = 1
-	}
-	w.WriteString(fmt.Sprintf("    Received_Q: %d\n", receivedQ))
+	fmt.Fprintf(bw, "  Auth info:\n")
+	fmt.Fprintf(bw, "    State: %s\n", s.AuthState)
+	fmt.Fprintf(bw, "    Our keyid:   %d\n", s.OurKeyID)
+	fmt.Fprintf(bw, "    Their keyid: %d\n", s.TheirKeyID)
+	fmt.Fprintf(bw, "    Our fingerprint:   %X\n", s.OurFingerprint)
+	fmt.Fprintf(bw, "    Their fingerprint: %X\n", s.TheirFingerprint)
+	fmt.Fprintf(bw, "    SSID: %X\n\n", s.SSID)
 
-	w.Flush()
+	fmt.Fprintf(bw, "  Counters:\n")
+	for _, k := range s.Counters {
+		fmt.Fprintf(bw, "    Our keyid %d, their keyid %d: sent %d, received %d\n", k.OurKeyID, k.TheirKeyID, k.OurCounter, k.TheirCounter)
+	}
+	fmt.Fprintf(bw, "\n")
+
+	fmt.Fprintf(bw, "  SM state:\n")
+	fmt.Fprintf(bw, "    Next expected: %s\n", s.SMPState)
+	fmt.Fprintf(bw, "    Received question: %t\n", s.SMPQuestionReceived)
+
+	return bw.Flush()
+}
+
+// DumpStateJSON writes the current state of the conversation to w as JSON. The SSID and fingerprints are
+// written as hex strings.
+func (c *Conversation) DumpStateJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c.State())
+}
+
+// MarshalJSON implements json.Marshaler, to write the SSID and fingerprints as hex strings
+func (s ConversationState) MarshalJSON() ([]byte, error) {
+	type plain ConversationState
+	return json.Marshal(struct {
+		plain
+		SSID             string
+		OurFingerprint   string
+		TheirFingerprint string
+	}{
+		plain(s),
+		hex.EncodeToString(s.SSID[:]),
+		hex.EncodeToString(s.OurFingerprint),
+		hex.EncodeToString(s.TheirFingerprint),
+	})
 }
 
 func (smpStateExpect1) identity() int {
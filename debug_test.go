@@ -1,40 +1,35 @@
 package otr3
 
 import (
-	"bufio"
 	"bytes"
 	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 )
 
-func Test_dumpSMP_dumpsTheCurrentSMPState(t *testing.T) {
+func Test_State_returnsTheCurrentSMPState(t *testing.T) {
 	c := newConversation(otrV3{}, fixtureRand())
 	c.smp.state = smpStateExpect2{}
-	c.smp.s1 = fixtureSmp1()
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
 	q := "Blarg"
 	c.smp.question = &q
 
-	bt := bytes.NewBuffer(make([]byte, 0, 200))
-	c.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 2 (EXPECT2)
-    Received_Q: 1
-`)
+	s := c.State()
+
+	assertEquals(t, s.SMPState, "EXPECT2")
+	assertTrue(t, s.SMPQuestionReceived)
 }
 
-func Test_dumpSMP_dumpsTheCurrentSMPStateWithQuestion(t *testing.T) {
+func Test_State_returnsTheCurrentSMPStateWithoutQuestion(t *testing.T) {
 	c := newConversation(otrV3{}, fixtureRand())
 	c.smp.state = smpStateExpect2{}
-	c.smp.s1 = fixtureSmp1()
-	c.smp.secret = bnFromHex("ABCDE56321F9A9F8E364607C8C82DECD8E8E6209E2CB952C7E649620F5286FE3")
-
-	bt := bytes.NewBuffer(make([]byte, 0, 200))
-	c.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 2 (EXPECT2)
-    Received_Q: 0
-`)
+
+	s := c.State()
+
+	assertEquals(t, s.SMPState, "EXPECT2")
+	assertFalse(t, s.SMPQuestionReceived)
 }
 
 func Test_identity_isCorrectForAllSMPStates(t *testing.T) {
@@ -53,18 +48,20 @@ func Test_identityString_isCorrectForAllSMPStates(t *testing.T) {
 	assertEquals(t, smpStateExpect4{}.identityString(), "EXPECT4")
 }
 
-func Test_dumpAKE_dumpsTheCurrentAKEState(t *testing.T) {
+func Test_DumpState_writesTheCurrentAKEState(t *testing.T) {
 	c := aliceContextAtAwaitingRevealSig()
 	c.theirKey = bobPrivateKey.PublicKey()
-	bt := bytes.NewBuffer(make([]byte, 0, 200))
-	c.dumpAKE(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  Auth info:
-    State: 2 (AWAITING_REVEALSIG)
+
+	bt := &bytes.Buffer{}
+	c.DumpState(bt)
+
+	assertTrue(t, strings.Contains(bt.String(), `  Auth info:
+    State: AWAITING_REVEALSIG
     Our keyid:   0
     Their keyid: 0
-    Their fingerprint: 8798FAA7735267FB8457733098482E94096D4ABD
-    Proto version = 2
-`)
+`))
+	assertTrue(t, strings.Contains(bt.String(), "    Their fingerprint: 8798FAA7735267FB8457733098482E94096D4ABD\n"))
+	assertTrue(t, strings.Contains(bt.String(), "  Protocol version: 2\n"))
 }
 
 func Test_identity_isCorrectForAllAKEStates(t *testing.T) {
@@ -81,30 +78,38 @@ func Test_identityString_isCorrectForAllAKEStates(t *testing.T) {
 	assertEquals(t, authStateAwaitingSig{}.identityString(), "AWAITING_SIG")
 }
 
-func Test_dump_dumpsAllKindsOfConversationState(t *testing.T) {
+func Test_DumpState_writesAllKindsOfConversationState(t *testing.T) {
 	c := bobContextAfterAKE()
 	c.ake = nil
 	c.msgState = encrypted
 	c.whitespaceState = whitespaceSent
 	c.theirInstanceTag = 0x102
 
-	bt := bytes.NewBuffer(make([]byte, 0, 200))
-	c.dump(bufio.NewWriter(bt))
+	bt := &bytes.Buffer{}
+	c.DumpState(bt)
 	assertDeepEquals(t, bt.String(), `Context:
 
   Our instance:   00000101
   Their instance: 00000102
 
-  Msgstate: 1 (ENCRYPTED)
+  Msgstate: ENCRYPTED
 
   Protocol version: 3
   OTR offer: ACCEPTED
 
-  Auth info: NULL
+  Auth info:
+    State: NONE
+    Our keyid:   2
+    Their keyid: 1
+    Our fingerprint:   
+    Their fingerprint: 
+    SSID: 0000000000000000
+
+  Counters:
 
   SM state:
-    Next expected: 0 (EXPECT1)
-    Received_Q: 0
+    Next expected: EXPECT1
+    Received question: false
 `)
 }
 
@@ -183,34 +188,15 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 	assertEquals(t, bob.IsEncrypted(), true)
 	assertEquals(t, alice.IsEncrypted(), true)
 
-	bt := bytes.NewBuffer(make([]byte, 0, 200))
-
-	// Should not they all be "Next expected: 0 (EXPECT1)" ?
-	// The spec says: This is the default state when SMP has not yet begun
-	// This is probably a consequence of needing ensureSMP() to initialize
-
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Received_Q: 0
-`)
-
-	bt.Reset()
-	bob.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Received_Q: 0
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT1")
+	assertEquals(t, bob.State().SMPState, "EXPECT1")
 
 	bobMessages, err = bob.StartAuthenticate("", []byte("secret"))
 	assertNil(t, err)
 	assertEquals(t, bob.smp.state, smpStateExpect2{})
 
-	bt.Reset()
-	bob.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 2 (EXPECT2)
-    Received_Q: 0
-`)
+	assertEquals(t, bob.State().SMPState, "EXPECT2")
+	assertFalse(t, bob.State().SMPQuestionReceived)
 
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
@@ -219,71 +205,43 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 	_, ok := alice.smp.state.(smpStateWaitingForSecret)
 	assertEquals(t, ok, true)
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-
-	// This feels wrong.
-	// I read "Alice is expecting a SMP1Q" which is not true because she has already received a SMP1Q
-	// BTW, libotr otrl_sm_dump() does not have it
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 1 (EXPECT1_WQ)
-    Received_Q: 0
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT1_WQ")
+	assertFalse(t, alice.State().SMPQuestionReceived)
 
 	aliceMessages, err = alice.ProvideAuthenticationSecret([]byte("secret"))
 	assertNil(t, err)
 	assertEquals(t, alice.smp.state, smpStateExpect3{})
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 3 (EXPECT3)
-    Received_Q: 0
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT3")
+	assertFalse(t, alice.State().SMPQuestionReceived)
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
 	assertEquals(t, bob.smp.state, smpStateExpect4{})
 
-	bt.Reset()
-	bob.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 4 (EXPECT4)
-    Received_Q: 0
-`)
+	assertEquals(t, bob.State().SMPState, "EXPECT4")
+	assertFalse(t, bob.State().SMPQuestionReceived)
 
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
 	assertEquals(t, alice.smp.state, smpStateExpect1{})
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 0 (EXPECT1)
-    Received_Q: 0
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT1")
+	assertFalse(t, alice.State().SMPQuestionReceived)
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
 	assertEquals(t, bob.smp.state, smpStateExpect1{})
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 0 (EXPECT1)
-    Received_Q: 0
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT1")
+	assertFalse(t, alice.State().SMPQuestionReceived)
 
 	bobMessages, err = bob.StartAuthenticate("What is the secret?", []byte("secret"))
 	assertNil(t, err)
 	assertEquals(t, bob.smp.state, smpStateExpect2{})
 
-	bt.Reset()
-	bob.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 2 (EXPECT2)
-    Received_Q: 0
-`)
+	assertEquals(t, bob.State().SMPState, "EXPECT2")
+	assertFalse(t, bob.State().SMPQuestionReceived)
 
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
@@ -292,58 +250,131 @@ func Test_SMP_CompleteDebug(t *testing.T) {
 	_, ok = alice.smp.state.(smpStateWaitingForSecret)
 	assertEquals(t, ok, true)
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-
-	// This feels wrong.
-	// I read "Alice is expecting a SMP1Q" which is not true because she has already received a SMP1Q
-	// BTW, libotr otrl_sm_dump() does not have it
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 1 (EXPECT1_WQ)
-    Received_Q: 1
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT1_WQ")
+	assertTrue(t, alice.State().SMPQuestionReceived)
 
 	aliceMessages, err = alice.ProvideAuthenticationSecret([]byte("secret"))
 	assertNil(t, err)
 	assertEquals(t, alice.smp.state, smpStateExpect3{})
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 3 (EXPECT3)
-    Received_Q: 1
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT3")
+	assertTrue(t, alice.State().SMPQuestionReceived)
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
 	assertEquals(t, bob.smp.state, smpStateExpect4{})
 
-	bt.Reset()
-	bob.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 4 (EXPECT4)
-    Received_Q: 0
-`)
+	assertEquals(t, bob.State().SMPState, "EXPECT4")
+	assertFalse(t, bob.State().SMPQuestionReceived)
 
 	_, aliceMessages, err = alice.Receive(bobMessages[0])
 	assertNil(t, err)
 	assertEquals(t, alice.smp.state, smpStateExpect1{})
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 0 (EXPECT1)
-    Received_Q: 0
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT1")
+	assertFalse(t, alice.State().SMPQuestionReceived)
 
 	_, bobMessages, err = bob.Receive(aliceMessages[0])
 	assertNil(t, err)
 	assertEquals(t, bob.smp.state, smpStateExpect1{})
 
-	bt.Reset()
-	alice.dumpSMP(bufio.NewWriter(bt))
-	assertDeepEquals(t, bt.String(), `  SM state:
-    Next expected: 0 (EXPECT1)
-    Received_Q: 0
-`)
+	assertEquals(t, alice.State().SMPState, "EXPECT1")
+	assertFalse(t, alice.State().SMPQuestionReceived)
+}
+
+func Test_State_returnsTheStateOfANewConversation(t *testing.T) {
+	c := &Conversation{}
+
+	s := c.State()
+
+	assertEquals(t, s.MessageState, "PLAINTEXT")
+	assertEquals(t, s.AuthState, "NONE")
+	assertEquals(t, s.SMPState, "EXPECT1")
+	assertEquals(t, s.Version, 0)
+	assertEquals(t, s.OTROffer, "NOT")
+	assertNil(t, s.Counters)
+	assertNil(t, s.TheirFingerprint)
+}
+
+func Test_State_returnsTheStateOfAnEncryptedConversation(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hello")
+	bob.smp.question = new(string)
+
+	s := bob.State()
+
+	assertEquals(t, s.MessageState, "ENCRYPTED")
+	assertEquals(t, s.AuthState, "NONE")
+	assertEquals(t, s.Version, 3)
+	assertEquals(t, s.OurInstanceTag, bob.ourInstanceTag)
+	assertEquals(t, s.TheirInstanceTag, alice.ourInstanceTag)
+	assertEquals(t, s.OurKeyID, bob.keys.ourKeyID)
+	assertEquals(t, s.TheirKeyID, bob.keys.theirKeyID)
+	assertEquals(t, s.SSID, alice.GetSSID())
+	assertTrue(t, s.SMPQuestionReceived)
+	assertDeepEquals(t, s.OurFingerprint, bobPrivateKey.PublicKey().Fingerprint())
+	assertDeepEquals(t, s.TheirFingerprint, alicePrivateKey.PublicKey().Fingerprint())
+	assertDeepEquals(t, s.Counters, []KeyCounters{
+		{OurKeyID: 1, TheirKeyID: 1, OurCounter: 0, TheirCounter: 1},
+		{OurKeyID: 1, TheirKeyID: 2, OurCounter: 2, TheirCounter: 0},
+	})
+}
+
+func Test_State_returnsTheAKEState(t *testing.T) {
+	c := aliceContextAtAwaitingRevealSig()
+
+	assertEquals(t, c.State().AuthState, "AWAITING_REVEALSIG")
+	assertEquals(t, c.State().Version, 2)
+}
+
+func Test_DumpState_writesTheStateAsText(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hello")
+
+	bt := &bytes.Buffer{}
+	err := bob.DumpState(bt)
+
+	assertNil(t, err)
+	assertEquals(t, bt.String(), fmt.Sprintf(`Context:
+
+  Our instance:   %08X
+  Their instance: %08X
+
+  Msgstate: ENCRYPTED
+
+  Protocol version: 3
+  OTR offer: NOT
+
+  Auth info:
+    State: NONE
+    Our keyid:   2
+    Their keyid: 2
+    Our fingerprint:   %X
+    Their fingerprint: %X
+    SSID: %X
+
+  Counters:
+    Our keyid 1, their keyid 1: sent 0, received 1
+    Our keyid 1, their keyid 2: sent 2, received 0
+
+  SM state:
+    Next expected: EXPECT1
+    Received question: false
+`, bob.ourInstanceTag, bob.theirInstanceTag, bobPrivateKey.PublicKey().Fingerprint(), alicePrivateKey.PublicKey().Fingerprint(), bob.ssid))
+}
+
+func Test_DumpStateJSON_writesTheStateAsJSON(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+
+	bt := &bytes.Buffer{}
+	err := bob.DumpStateJSON(bt)
+
+	var s map[string]interface{}
+	assertNil(t, err)
+	assertNil(t, json.Unmarshal(bt.Bytes(), &s))
+	assertEquals(t, s["MessageState"], "ENCRYPTED")
+	assertEquals(t, s["Version"], float64(3))
+	assertEquals(t, s["TheirInstanceTag"], float64(alice.ourInstanceTag))
+	assertEquals(t, s["SSID"], hex.EncodeToString(bob.ssid[:]))
+	assertEquals(t, s["TheirFingerprint"], hex.EncodeToString(alicePrivateKey.PublicKey().Fingerprint()))
 }
@@ -7,7 +7,9 @@ func (c *Conversation) processDisconnectedTLV(t tlv, x dataMessageExtra) (toSend
 
 	defer c.signalSecurityEventIf(previousMsgState == encrypted, GoneInsecure)
 	c.lastMessageStateChange = time.Time{}
-	c.msgState = finished
+	defer c.logAuthStateChange(c.authStateName())
+	defer c.logSMPStateChange(c.smpStateName())
+	c.setMsgState(finished)
 	c.smp.wipe()
 	c.ake = nil
 
@@ -32,6 +32,18 @@
 //  // You can also setup a debug mode
 //  c.SetDebug(true)
 //
+//  // Or look at the state of the conversation at any time
+//  c.DumpState(os.Stderr)
+//
+//  // Events can be read from a channel instead of, or as well as, using the handlers
+//  go func() {
+//  	for e := range c.Events() {
+//  		if se, ok := e.(otr3.SecurityEventNotification); ok {
+//  			fmt.Println(se.Event)
+//  		}
+//  	}
+//  }()
+//
 //  // Use Send and Receive for messages exchange
 //  toSend, err := c.Send(otr3.ValidMessage("hello"))
 //  plain, toSend, err := c.Receive(toSend[0])
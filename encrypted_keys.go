@@ -0,0 +1,202 @@
+package otr3
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// An encrypted key file wraps the libotr privkeys s-expression like this:
+//
+//	magic, SHORT format version, BYTE log2(scrypt N), WORD scrypt r, WORD scrypt p,
+//	DATA salt, DATA nonce, DATA AES-256-GCM encrypted s-expression
+//
+// Everything before the encrypted s-expression is authenticated as additional data.
+var encryptedKeysMagic = []byte("OTR3-ENCRYPTED-PRIVKEYS\n")
+
+const encryptedKeysFormatVersion = uint16(1)
+
+// These limit the memory and time a malicious encrypted key file can make us use to derive the key
+const maxEncryptedKeysMemory = 1 << 30
+const maxEncryptedKeysParallelization = 16
+
+type scryptParameters struct {
+	logN byte
+	r, p uint32
+}
+
+// defaultScryptParameters uses 32MB of memory, which takes around a tenth of a second on a modern computer
+var defaultScryptParameters = scryptParameters{logN: 15, r: 8, p: 1}
+
+var errInvalidEncryptedKeys = wrapOtrError(ErrInvalidKey, "invalid encrypted private keys")
+
+// IsEncryptedKeyData returns true if the data given is an encrypted key file, as written by ExportKeysWithPassphrase
+func IsEncryptedKeyData(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedKeysMagic)
+}
+
+// ImportKeysFromFileWithPassphrase will read the named file and return all accounts defined in it. The file can be
+// either encrypted with the passphrase or a plain libotr formatted file - the format is detected automatically.
+func ImportKeysFromFileWithPassphrase(fname string, passphrase []byte) ([]*Account, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ImportKeysWithPassphrase(f, passphrase)
+}
+
+// ExportKeysToFileWithPassphrase will create the named file (or truncate it), readable only by the current user,
+// and write all the accounts to it in libotr format, encrypted with the passphrase.
+func ExportKeysToFileWithPassphrase(acs []*Account, fname string, passphrase []byte) error {
+	f, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ExportKeysWithPassphrase(acs, f, passphrase)
+}
+
+// ImportKeysWithPassphrase will read either encrypted or plain libotr formatted data and return all accounts defined in it
+func ImportKeysWithPassphrase(r io.Reader, passphrase []byte) ([]*Account, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsEncryptedKeyData(data) {
+		return ImportKeys(bytes.NewReader(data))
+	}
+
+	plain, err := decryptKeyData(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(plain)
+
+	return ImportKeys(bytes.NewReader(plain))
+}
+
+// ExportKeysWithPassphrase will write all the accounts in libotr format, encrypted with the passphrase.
+// The key used for encryption is derived from the passphrase using scrypt.
+func ExportKeysWithPassphrase(acs []*Account, w io.Writer, passphrase []byte) error {
+	plain := &bytes.Buffer{}
+	exportAccounts(acs, plain)
+	defer wipeBytes(plain.Bytes())
+
+	data, err := encryptKeyData(plain.Bytes(), passphrase, defaultScryptParameters, rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func (p scryptParameters) isReasonable() bool {
+	return p.logN >= 1 && p.logN < 32 && p.r >= 1 && p.p >= 1 &&
+		p.p <= maxEncryptedKeysParallelization &&
+		uint64(p.r) <= maxEncryptedKeysMemory/128 &&
+		uint64(128)*uint64(p.r)<<p.logN <= maxEncryptedKeysMemory
+}
+
+func (p scryptParameters) deriveKey(passphrase, salt []byte) ([]byte, error) {
+	return scryptKey(passphrase, salt, 1<<p.logN, int(p.r), int(p.p), 32)
+}
+
+func keyDataCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptKeyData(plain, passphrase []byte, params scryptParameters, r io.Reader) ([]byte, error) {
+	salt := make([]byte, 16)
+	if err := randomInto(r, salt); err != nil {
+		return nil, err
+	}
+
+	key, err := params.deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(key)
+
+	aead, err := keyDataCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if err := randomInto(r, nonce); err != nil {
+		return nil, err
+	}
+
+	out := appendShort(makeCopy(encryptedKeysMagic), encryptedKeysFormatVersion)
+	out = append(out, params.logN)
+	out = appendWord(out, params.r)
+	out = appendWord(out, params.p)
+	out = appendData(out, salt)
+	out = appendData(out, nonce)
+
+	return appendData(out, aead.Seal(nil, nonce, plain, out)), nil
+}
+
+func decryptKeyData(data, passphrase []byte) ([]byte, error) {
+	var ok bool
+	var formatVersion uint16
+	var params scryptParameters
+	var salt, nonce, encrypted []byte
+
+	index := data[len(encryptedKeysMagic):]
+	if index, formatVersion, ok = extractShort(index); !ok || formatVersion != encryptedKeysFormatVersion || len(index) < 1 {
+		return nil, errInvalidEncryptedKeys
+	}
+	params.logN, index = index[0], index[1:]
+	if index, params.r, ok = extractWord(index); !ok {
+		return nil, errInvalidEncryptedKeys
+	}
+	if index, params.p, ok = extractWord(index); !ok {
+		return nil, errInvalidEncryptedKeys
+	}
+	if index, salt, ok = extractData(index); !ok {
+		return nil, errInvalidEncryptedKeys
+	}
+	if index, nonce, ok = extractData(index); !ok {
+		return nil, errInvalidEncryptedKeys
+	}
+	additionalData := data[:len(data)-len(index)]
+	if index, encrypted, ok = extractData(index); !ok || len(index) != 0 {
+		return nil, errInvalidEncryptedKeys
+	}
+
+	if !params.isReasonable() {
+		return nil, errInvalidEncryptedKeys
+	}
+
+	key, err := params.deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, errInvalidEncryptedKeys
+	}
+	defer wipeBytes(key)
+
+	aead, err := keyDataCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errInvalidEncryptedKeys
+	}
+
+	plain, err := aead.Open(nil, nonce, encrypted, additionalData)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plain, nil
+}
@@ -0,0 +1,134 @@
+package otr3
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+var fixtureScryptParameters = scryptParameters{logN: 4, r: 1, p: 1}
+
+func fixtureAccounts() []*Account {
+	return []*Account{
+		{Name: "alice@example.org", Protocol: "prpl-jabber", Key: alicePrivateKey},
+		{Name: "bob@example.org", Protocol: "prpl-jabber", Key: bobPrivateKey},
+	}
+}
+
+func fixtureEncryptedKeyData(t *testing.T, passphrase string) []byte {
+	plain := &bytes.Buffer{}
+	exportAccounts(fixtureAccounts(), plain)
+	data, err := encryptKeyData(plain.Bytes(), []byte(passphrase), fixtureScryptParameters, fixtureRand())
+	assertNil(t, err)
+	return data
+}
+
+func Test_ExportKeysWithPassphrase_canBeReadBack(t *testing.T) {
+	bt := &bytes.Buffer{}
+
+	err := ExportKeysWithPassphrase(fixtureAccounts(), bt, []byte("my passphrase"))
+	assertNil(t, err)
+	assertTrue(t, IsEncryptedKeyData(bt.Bytes()))
+	assertFalse(t, bytes.Contains(bt.Bytes(), []byte("privkeys")))
+
+	res, err := ImportKeysWithPassphrase(bt, []byte("my passphrase"))
+	assertNil(t, err)
+	assertEquals(t, len(res), 2)
+	assertEquals(t, res[0].Name, "alice@example.org")
+	assertDeepEquals(t, res[0].Key.Serialize(), alicePrivateKey.Serialize())
+	assertDeepEquals(t, res[1].Key.Serialize(), bobPrivateKey.Serialize())
+}
+
+func Test_ImportKeysWithPassphrase_readsPlainLibOTRFiles(t *testing.T) {
+	plain := &bytes.Buffer{}
+	exportAccounts(fixtureAccounts(), plain)
+
+	res, err := ImportKeysWithPassphrase(plain, []byte("doesn't matter"))
+
+	assertNil(t, err)
+	assertEquals(t, len(res), 2)
+	assertFalse(t, IsEncryptedKeyData(plain.Bytes()))
+}
+
+func Test_ImportKeysWithPassphrase_returnsAnErrorForTheWrongPassphrase(t *testing.T) {
+	data := fixtureEncryptedKeyData(t, "right")
+
+	_, err := ImportKeysWithPassphrase(bytes.NewReader(data), []byte("wrong"))
+
+	assertEquals(t, err, ErrWrongPassphrase)
+}
+
+func Test_ImportKeysWithPassphrase_returnsAnErrorIfTheHeaderHasBeenChanged(t *testing.T) {
+	data := fixtureEncryptedKeyData(t, "right")
+	// the last byte of the salt
+	data[len(encryptedKeysMagic)+2+1+4+4+4+15] ^= 0x01
+
+	_, err := ImportKeysWithPassphrase(bytes.NewReader(data), []byte("right"))
+
+	assertEquals(t, err, ErrWrongPassphrase)
+}
+
+func Test_ImportKeysWithPassphrase_returnsAnErrorForTruncatedData(t *testing.T) {
+	data := fixtureEncryptedKeyData(t, "right")
+
+	for i := len(encryptedKeysMagic); i < len(data); i++ {
+		_, err := ImportKeysWithPassphrase(bytes.NewReader(data[:i]), []byte("right"))
+		assertEquals(t, err, errInvalidEncryptedKeys)
+	}
+}
+
+func Test_ImportKeysWithPassphrase_rejectsUnreasonableScryptParameters(t *testing.T) {
+	for _, params := range []scryptParameters{
+		{logN: 0, r: 1, p: 1},
+		{logN: 4, r: 0, p: 1},
+		{logN: 4, r: 1, p: 0},
+		{logN: 4, r: 1, p: 17},
+		{logN: 30, r: 8, p: 1},
+		{logN: 1, r: 0xFFFFFFFF, p: 1},
+	} {
+		data := fixtureEncryptedKeyData(t, "right")
+		header := len(encryptedKeysMagic) + 2
+		data[header] = params.logN
+		copy(data[header+1:], appendWord(appendWord(nil, params.r), params.p))
+
+		_, err := ImportKeysWithPassphrase(bytes.NewReader(data), []byte("right"))
+		assertEquals(t, err, errInvalidEncryptedKeys)
+	}
+}
+
+func Test_encryptKeyData_returnsAnErrorIfRandomnessFails(t *testing.T) {
+	_, err := encryptKeyData([]byte("hello"), []byte("pass"), fixtureScryptParameters, fixedRand([]string{"0102"}))
+
+	assertEquals(t, err, errShortRandomRead)
+}
+
+func Test_ExportKeysToFileWithPassphrase_writesAFileOnlyReadableByTheUser(t *testing.T) {
+	fname := "test_resources/test_export_of_encrypted_keys.blah"
+	err := ExportKeysToFileWithPassphrase(fixtureAccounts(), fname, []byte("my passphrase"))
+	assertNil(t, err)
+	defer os.Remove(fname)
+
+	info, _ := os.Stat(fname)
+	assertEquals(t, info.Mode().Perm(), os.FileMode(0600))
+
+	res, err := ImportKeysFromFileWithPassphrase(fname, []byte("my passphrase"))
+	assertNil(t, err)
+	assertEquals(t, len(res), 2)
+}
+
+func Test_ImportKeysFromFileWithPassphrase_readsPlainLibOTRFiles(t *testing.T) {
+	res, err := ImportKeysFromFileWithPassphrase("test_resources/valid_key.asc", nil)
+
+	assertNil(t, err)
+	assertEquals(t, len(res) > 0, true)
+}
+
+func Test_ImportKeysFromFileWithPassphrase_returnsAnErrorIfTheFileDoesntExist(t *testing.T) {
+	_, err := ImportKeysFromFileWithPassphrase("this_file_doesnt_exist.asc", nil)
+	assertNotNil(t, err)
+}
+
+func Test_ExportKeysToFileWithPassphrase_returnsAnErrorIfTheFileCantBeCreated(t *testing.T) {
+	err := ExportKeysToFileWithPassphrase(fixtureAccounts(), "non_existing_directory/keys.blah", []byte("pass"))
+	assertNotNil(t, err)
+}
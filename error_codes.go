@@ -34,6 +34,7 @@ func (d dynamicErrorMessageHandler) HandleErrorMessage(error ErrorCode) []byte {
 }
 
 func (c *Conversation) generatePotentialErrorMessage(ec ErrorCode) {
+	c.sendErrorMessageEvent(ec)
 	if c.errorMessageHandler != nil {
 		msg := c.errorMessageHandler.HandleErrorMessage(ec)
 		c.injectMessage(append(append(errorMarker, ' '), msg...))
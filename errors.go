@@ -2,25 +2,48 @@ package otr3
 
 import "fmt"
 
-var errCantAuthenticateWithoutEncryption = newOtrError("can't authenticate a peer without a secure conversation established")
+// These errors are returned by the library, either as they are or wrapped in a more specific error. Use
+// errors.Is to check for them, or compare directly if you don't need to look at wrapped errors.
+var (
+	// ErrNotEncrypted is returned when something needs an encrypted conversation, but the conversation isn't encrypted
+	ErrNotEncrypted = newOtrConflictError("cannot send message in unencrypted state")
+	// ErrNoPeerKey is returned when the conversation is encrypted, but we don't have a DH key from the peer yet
+	ErrNoPeerKey = newOtrError("no key has been received from the peer yet")
+	// ErrSessionFinished is returned when sending a message after the peer has ended the encrypted conversation
+	ErrSessionFinished = newOtrError("cannot send message because secure conversation has finished")
+	// ErrInvalidVersion is returned when there is no protocol version both we and the peer can use
+	ErrInvalidVersion = newOtrError("no valid version agreement could be found") //libotr ignores this situation
+	// ErrUnsupportedVersion is returned for messages using a protocol version our policies don't allow
+	ErrUnsupportedVersion = newOtrError("unsupported OTR version")
+	// ErrWrongProtocolVersion is returned for messages using another protocol version than the conversation
+	ErrWrongProtocolVersion = newOtrError("wrong protocol version")
+	// ErrNotWaitingForSMPSecret is returned when an SMP secret is provided without the peer asking for one
+	ErrNotWaitingForSMPSecret = newOtrError("not expected SMP secret to be provided now")
+	// ErrUnexpectedSMPMessage is given with SMPEventError when the peer sends an SMP message we are not expecting
+	ErrUnexpectedSMPMessage = newOtrError("unexpected SMP message")
+	// ErrSMPSecretsDiffer is given with SMPEventFailure when the SMP ran correctly, but the secrets were not the same
+	ErrSMPSecretsDiffer = newOtrError("protocol failed: x != y")
+	// ErrInvalidKey is returned when private keys can't be read
+	ErrInvalidKey = newOtrError("couldn't import data into private key")
+	// ErrWrongPassphrase is returned when encrypted private keys can't be decrypted
+	ErrWrongPassphrase = newOtrError("couldn't decrypt private keys - wrong passphrase or corrupt data")
+)
+
+var errCantAuthenticateWithoutEncryption = wrapOtrError(ErrNotEncrypted, "can't authenticate a peer without a secure conversation established")
 var errCorruptEncryptedSignature = newOtrError("corrupt encrypted signature")
-var errEncryptedMessageWithNoSecureChannel = newOtrError("encrypted message received without encrypted session established")
+var errEncryptedMessageWithNoSecureChannel = wrapOtrError(ErrNotEncrypted, "encrypted message received without encrypted session established")
 var errUnexpectedPlainMessage = newOtrError("plain message received when encryption was required")
 var errInvalidOTRMessage = newOtrError("invalid OTR message")
-var errInvalidVersion = newOtrError("no valid version agreement could be found") //libotr ignores this situation
-var errNotWaitingForSMPSecret = newOtrError("not expected SMP secret to be provided now")
+var errNotDataMessage = newOtrError("not a data message")
 var errReceivedMessageForOtherInstance = newOtrError("received message for other OTR instance") //not exactly an error - we should ignore these messages by default
 var errShortRandomRead = newOtrError("short read from random source")
-var errUnexpectedMessage = newOtrError("unexpected SMP message")
-var errUnsupportedOTRVersion = newOtrError("unsupported OTR version")
-var errWrongProtocolVersion = newOtrError("wrong protocol version")
 var errMessageNotInPrivate = newOtrError("message not in private")
-var errCannotSendUnencrypted = newOtrConflictError("cannot send message in unencrypted state")
 
 // OtrError is an error in the OTR library
 type OtrError struct {
 	msg      string
 	conflict bool
+	cause    error
 }
 
 func newOtrError(s string) error {
@@ -31,6 +54,11 @@ func newOtrConflictError(s string) error {
 	return OtrError{msg: s, conflict: true}
 }
 
+// wrapOtrError returns an error with the given message that errors.Is and errors.As see as the cause
+func wrapOtrError(cause error, s string) error {
+	return OtrError{msg: s, cause: cause}
+}
+
 func newOtrErrorf(format string, a ...interface{}) error {
 	return OtrError{msg: fmt.Sprintf(format, a...), conflict: false}
 }
@@ -39,6 +67,68 @@ func (oe OtrError) Error() string {
 	return "otr: " + oe.msg
 }
 
+// Unwrap returns the error this error wraps, or nil
+func (oe OtrError) Unwrap() error {
+	return oe.cause
+}
+
+// MACVerificationError is returned when the MAC of a received message, or of a part of it, is wrong. This happens
+// when a message has been changed on the way, or was made with other keys than the ones we have.
+type MACVerificationError struct {
+	// Message is what the MAC was for, for example "data message"
+	Message string
+}
+
+func (e *MACVerificationError) Error() string {
+	return "otr: bad MAC in " + e.Message
+}
+
+// SMPVerificationError is given with SMPEventCheated when a value in an SMP message from the peer doesn't verify.
+// This means that the peer is broken or trying to cheat - different secrets give ErrSMPSecretsDiffer instead.
+type SMPVerificationError struct {
+	// Field is the value that didn't verify, for example "g2a" or "c2"
+	Field string
+	// ZeroKnowledgeProof is true if Field is a zero knowledge proof that doesn't hold, and false if Field is
+	// not a valid group element
+	ZeroKnowledgeProof bool
+}
+
+func newSMPGroupElementError(field string) error {
+	return &SMPVerificationError{Field: field}
+}
+
+func newSMPProofError(field string) error {
+	return &SMPVerificationError{Field: field, ZeroKnowledgeProof: true}
+}
+
+func (e *SMPVerificationError) Error() string {
+	if e.ZeroKnowledgeProof {
+		return "otr: " + e.Field + " is not a valid zero knowledge proof"
+	}
+	return "otr: " + e.Field + " is an invalid group element"
+}
+
+// MalformedMessageError is returned when a received message can't be decoded
+type MalformedMessageError struct {
+	// Message is the kind of message, for example "DH commit message"
+	Message string
+	// Field is the part of the message that couldn't be read, if known
+	Field string
+	// Offset is where the problem was found, counted in bytes from the end of the message header
+	Offset int
+}
+
+func newMalformedMessageError(message, field string, msg, rest []byte) error {
+	return &MalformedMessageError{Message: message, Field: field, Offset: len(msg) - len(rest)}
+}
+
+func (e *MalformedMessageError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("otr: corrupt %s at offset %d", e.Message, e.Offset)
+	}
+	return fmt.Sprintf("otr: corrupt %s: invalid %s at offset %d", e.Message, e.Field, e.Offset)
+}
+
 func firstError(es ...error) error {
 	for _, e := range es {
 		if e != nil {
@@ -49,8 +139,11 @@ func firstError(es ...error) error {
 }
 
 func isConflict(e error) bool {
-	if oe, ok := e.(OtrError); ok {
+	switch oe := e.(type) {
+	case OtrError:
 		return oe.conflict
+	case *MACVerificationError:
+		return true
 	}
 	return false
 }
@@ -0,0 +1,113 @@
+//go:build go1.13
+// +build go1.13
+
+package otr3
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func Test_errors_Is_findsWrappedSentinelErrors(t *testing.T) {
+	c := &Conversation{}
+	_, err := c.StartAuthenticate("", []byte("secret"))
+
+	assertTrue(t, errors.Is(err, ErrNotEncrypted))
+	assertTrue(t, errors.Is(fmt.Errorf("sending: %w", ErrSessionFinished), ErrSessionFinished))
+	assertTrue(t, !errors.Is(err, ErrSessionFinished))
+}
+
+func Test_errors_Is_findsInvalidKeysForEncryptedKeyFiles(t *testing.T) {
+	_, err := decryptKeyData(encryptedKeysMagic, []byte("passphrase"))
+
+	assertTrue(t, errors.Is(err, ErrInvalidKey))
+}
+
+func tamperWithLastByte(m ValidMessage) ValidMessage {
+	decoded, _ := b64decode(removeOTRMsgEnvelope(encodedMessage(m)))
+	decoded[len(decoded)-1] ^= 0x01
+	return append(append(makeCopy(msgMarker), b64encode(decoded)...), '.')
+}
+
+func Test_errors_As_findsTheMACVerificationErrorOfAnAKEMessage(t *testing.T) {
+	alice := newStatePeer(alicePrivateKey)
+	bob := newStatePeer(bobPrivateKey)
+
+	_, dhCommit, _ := bob.Receive(alice.QueryMessage())
+	_, dhKey, _ := alice.Receive(dhCommit[0])
+	_, revealSig, _ := bob.Receive(dhKey[0])
+	_, _, err := alice.Receive(tamperWithLastByte(revealSig[0]))
+
+	var macErr *MACVerificationError
+	assertTrue(t, errors.As(err, &macErr))
+	assertEquals(t, macErr.Message, "encrypted signature")
+}
+
+// smpEventAfter returns the first SMP event with the given type that c sends while receiving m
+func smpEventAfter(t *testing.T, c *Conversation, m ValidMessage, e SMPEvent) SMPEventNotification {
+	events := c.Events()
+	_, _, err := c.Receive(m)
+	assertNil(t, err)
+
+	for {
+		select {
+		case ev := <-events:
+			if n, ok := ev.(SMPEventNotification); ok && n.Event == e {
+				return n
+			}
+		default:
+			t.Fatalf("no %s event was sent", e)
+			return SMPEventNotification{}
+		}
+	}
+}
+
+func Test_errors_As_findsTheSMPVerificationErrorOfACheatingPeer(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	bob.StartAuthenticate("", []byte("secret"))
+	bob.smp.s1.msg.g2a = big.NewInt(1)
+	toSend, _, _ := bob.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, []tlv{bob.smp.s1.msg.tlv()})
+
+	n := smpEventAfter(t, alice, toSend[0], SMPEventCheated)
+
+	var smpErr *SMPVerificationError
+	assertTrue(t, errors.As(n.Err, &smpErr))
+	assertEquals(t, smpErr.Field, "g2a")
+	assertFalse(t, smpErr.ZeroKnowledgeProof)
+}
+
+func Test_errors_Is_findsAnUnexpectedSMPMessage(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	toSend, _ := bob.StartAuthenticate("", []byte("secret"))
+	alice.Receive(toSend[0])
+	again, _, _ := bob.createSerializedDataMessage(nil, messageFlagIgnoreUnreadable, []tlv{bob.smp.s1.msg.tlv()})
+
+	n := smpEventAfter(t, alice, again[0], SMPEventError)
+
+	assertTrue(t, errors.Is(n.Err, ErrUnexpectedSMPMessage))
+}
+
+func Test_errors_Is_findsThatTheSMPSecretsDiffer(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	toSend, _ := bob.StartAuthenticate("", []byte("secret"))
+	alice.Receive(toSend[0])
+	toSend, _ = alice.ProvideAuthenticationSecret([]byte("another secret"))
+	_, toSend, _ = bob.Receive(toSend[0])
+
+	n := smpEventAfter(t, alice, toSend[0], SMPEventFailure)
+
+	assertTrue(t, errors.Is(n.Err, ErrSMPSecretsDiffer))
+}
+
+func Test_errors_As_findsTheOffsetOfAMalformedMessage(t *testing.T) {
+	m := dataMsg{}
+	err := m.deserialize([]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00}, otrV3{})
+
+	var malformed *MalformedMessageError
+	assertTrue(t, errors.As(err, &malformed))
+	assertEquals(t, malformed.Message, "data message")
+	assertEquals(t, malformed.Field, "recipient key id")
+	assertEquals(t, malformed.Offset, 5)
+}
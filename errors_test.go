@@ -6,3 +6,46 @@ func Test_OtrError_Error_returnsAValidErrorString(t *testing.T) {
 	e := newOtrError("hello world")
 	assertEquals(t, e.Error(), "otr: hello world")
 }
+
+func Test_wrapOtrError_unwrapsToTheCause(t *testing.T) {
+	e := wrapOtrError(ErrNotEncrypted, "hello world")
+
+	assertEquals(t, e.Error(), "otr: hello world")
+	assertEquals(t, e.(OtrError).Unwrap(), ErrNotEncrypted)
+	assertNil(t, ErrNotEncrypted.(OtrError).Unwrap())
+}
+
+func Test_MACVerificationError_Error_namesWhatTheMACWasFor(t *testing.T) {
+	e := &MACVerificationError{Message: "data message"}
+	assertEquals(t, e.Error(), "otr: bad MAC in data message")
+}
+
+func Test_MalformedMessageError_Error_includesTheOffset(t *testing.T) {
+	e := &MalformedMessageError{Message: "DH key message", Offset: 3}
+	assertEquals(t, e.Error(), "otr: corrupt DH key message at offset 3")
+
+	e.Field = "gy"
+	assertEquals(t, e.Error(), "otr: corrupt DH key message: invalid gy at offset 3")
+}
+
+func Test_SMPVerificationError_Error_namesTheFieldThatDidntVerify(t *testing.T) {
+	assertEquals(t, newSMPGroupElementError("g2a").Error(), "otr: g2a is an invalid group element")
+	assertEquals(t, newSMPProofError("c2").Error(), "otr: c2 is not a valid zero knowledge proof")
+}
+
+func Test_isConflict_isTrueForMACVerificationErrors(t *testing.T) {
+	assertTrue(t, isConflict(&MACVerificationError{Message: "data message"}))
+	assertTrue(t, isConflict(ErrNotEncrypted))
+	assertTrue(t, !isConflict(ErrSessionFinished))
+	assertTrue(t, !isConflict(&MalformedMessageError{Message: "data message"}))
+}
+
+func Test_Send_returnsErrSessionFinishedAfterThePeerEnds(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	toSend, _ := alice.End()
+	bob.Receive(toSend[0])
+
+	_, err := bob.Send(ValidMessage("hello"))
+
+	assertEquals(t, err, ErrSessionFinished)
+}
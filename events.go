@@ -0,0 +1,149 @@
+package otr3
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EventBufferSize is how many events the channel returned by Events can hold before events are dropped
+const EventBufferSize = 100
+
+// Event is something that happened in a conversation, sent on the channel returned by Events. It is one of
+// SMPEventNotification, MessageEventNotification, SecurityEventNotification and ErrorMessageNotification.
+type Event interface {
+	// Info returns what all events have in common
+	Info() EventInfo
+}
+
+// EventInfo is the part of an Event that all kinds of events have
+type EventInfo struct {
+	// Conversation is where the event happened. It must only be used the same way as the conversation is used
+	// elsewhere - for example, by holding the lock of a LockedConversation.
+	Conversation                     *Conversation
+	OurInstanceTag, TheirInstanceTag uint32
+	Time                             time.Time
+}
+
+// Info implements Event
+func (i EventInfo) Info() EventInfo {
+	return i
+}
+
+// SMPEventNotification is sent for the same things as SMPEventHandler.HandleSMPEvent is called for
+type SMPEventNotification struct {
+	EventInfo
+	Event           SMPEvent
+	ProgressPercent int
+	Question        string
+	// Err is the reason for SMPEventCheated, SMPEventError and SMPEventFailure. It is an *SMPVerificationError,
+	// ErrUnexpectedSMPMessage or ErrSMPSecretsDiffer, and can be checked with errors.Is and errors.As.
+	Err error
+}
+
+// MessageEventNotification is sent for the same things as MessageEventHandler.HandleMessageEvent is called for
+type MessageEventNotification struct {
+	EventInfo
+	Event   MessageEvent
+	Message []byte
+	Err     error
+	Trace   []interface{}
+}
+
+// SecurityEventNotification is sent for the same things as SecurityEventHandler.HandleSecurityEvent is called for
+type SecurityEventNotification struct {
+	EventInfo
+	Event SecurityEvent
+}
+
+// ErrorMessageNotification is sent when something went wrong that the peer can be told about with an OTR error
+// message. The ErrorMessageHandler still decides if such a message is sent, and what it says.
+type ErrorMessageNotification struct {
+	EventInfo
+	Code ErrorCode
+}
+
+type eventStream struct {
+	ch      chan Event
+	dropped uint64
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{ch: make(chan Event, EventBufferSize)}
+}
+
+// send never blocks. If the channel is full, the oldest event in it is thrown away to make room.
+func (s *eventStream) send(e Event) {
+	for {
+		select {
+		case s.ch <- e:
+			return
+		default:
+		}
+
+		select {
+		case <-s.ch:
+			atomic.AddUint64(&s.dropped, 1)
+		default:
+		}
+	}
+}
+
+// Events returns a channel that receives an Event for everything the SMP, message, security and error message
+// handlers are told about. The handlers are still called as before, so both can be used at the same time.
+//
+// Nothing is sent until the first call, and all calls return the same channel. Events are sent while the
+// conversation is handling a message, but sending never blocks: the channel holds EventBufferSize events, and when
+// it is full the oldest event is dropped to make room for the new one. DroppedEvents tells how many were lost.
+// Conversations a Session creates for other instances send to the same channel, if it was asked for before they
+// were created.
+func (c *Conversation) Events() <-chan Event {
+	if c.events == nil {
+		c.events = newEventStream()
+	}
+	return c.events.ch
+}
+
+// DroppedEvents returns how many events have been dropped because the channel returned by Events was full
+func (c *Conversation) DroppedEvents() uint64 {
+	if c.events == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.events.dropped)
+}
+
+func (c *Conversation) eventInfo() EventInfo {
+	return EventInfo{
+		Conversation:     c,
+		OurInstanceTag:   c.ourInstanceTag,
+		TheirInstanceTag: c.theirInstanceTag,
+		Time:             c.now(),
+	}
+}
+
+func (c *Conversation) sendSMPEvent(e SMPEvent, percent int, question string, err error) {
+	if c.events != nil {
+		c.events.send(SMPEventNotification{c.eventInfo(), e, percent, question, err})
+	}
+}
+
+func (c *Conversation) sendMessageEvent(e MessageEvent, message []byte, err error, trace []interface{}) {
+	if c.events == nil {
+		return
+	}
+	if message != nil {
+		message = makeCopy(message)
+	}
+	c.events.send(MessageEventNotification{c.eventInfo(), e, message, err, trace})
+}
+
+func (c *Conversation) sendSecurityEvent(e SecurityEvent) {
+	if c.events != nil {
+		c.events.send(SecurityEventNotification{c.eventInfo(), e})
+	}
+}
+
+func (c *Conversation) sendErrorMessageEvent(ec ErrorCode) {
+	if c.events != nil {
+		c.events.send(ErrorMessageNotification{c.eventInfo(), ec})
+	}
+}
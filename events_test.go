@@ -0,0 +1,177 @@
+package otr3
+
+import (
+	"errors"
+	"testing"
+)
+
+func receivedEvents(ch <-chan Event) []Event {
+	var result []Event
+	for {
+		select {
+		case e := <-ch:
+			result = append(result, e)
+		default:
+			return result
+		}
+	}
+}
+
+func securityEventsIn(events []Event) []SecurityEvent {
+	var result []SecurityEvent
+	for _, e := range events {
+		if se, ok := e.(SecurityEventNotification); ok {
+			result = append(result, se.Event)
+		}
+	}
+	return result
+}
+
+func Test_Events_receivesSecurityEventsDuringTheAKE(t *testing.T) {
+	alice := newStatePeer(alicePrivateKey)
+	bob := newStatePeer(bobPrivateKey)
+	clock := newFakeClock()
+	alice.Clock = clock
+	events := alice.Events()
+
+	exchangeBetween(t, alice, bob, []ValidMessage{alice.QueryMessage()})
+
+	received := receivedEvents(events)
+	assertDeepEquals(t, securityEventsIn(received), []SecurityEvent{GoneSecure})
+	info := received[len(received)-1].Info()
+	assertEquals(t, info.Conversation, alice)
+	assertEquals(t, info.OurInstanceTag, alice.ourInstanceTag)
+	assertEquals(t, info.TheirInstanceTag, bob.ourInstanceTag)
+	assertEquals(t, info.Time, clock.Now())
+}
+
+func Test_Events_receivesSMPEventsWithTheQuestion(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	events := bob.Events()
+
+	toSend, _ := alice.StartAuthenticate("what's the secret?", []byte("secret"))
+	bob.Receive(toSend[0])
+
+	var smpEvents []SMPEventNotification
+	for _, e := range receivedEvents(events) {
+		if se, ok := e.(SMPEventNotification); ok {
+			smpEvents = append(smpEvents, se)
+		}
+	}
+	assertEquals(t, len(smpEvents), 1)
+	e := smpEvents[0]
+	assertEquals(t, e.Event, SMPEventAskForAnswer)
+	assertEquals(t, e.ProgressPercent, 25)
+	assertEquals(t, e.Question, "what's the secret?")
+}
+
+func Test_Events_receivesMessageEventsWithACopyOfTheMessage(t *testing.T) {
+	c := &Conversation{}
+	events := c.Events()
+	msg := []byte("hello")
+	err := errors.New("some error")
+
+	c.messageEventWithMessage(MessageEventReceivedMessageUnencrypted, msg)
+	msg[0] = 'j'
+	c.messageEventWithError(MessageEventSetupError, err)
+	c.messageEvent(MessageEventMessageSent, "trace")
+
+	received := receivedEvents(events)
+	assertEquals(t, len(received), 3)
+	assertDeepEquals(t, received[0].(MessageEventNotification).Message, []byte("hello"))
+	assertEquals(t, received[1].(MessageEventNotification).Err, err)
+	assertNil(t, received[1].(MessageEventNotification).Message)
+	assertDeepEquals(t, received[2].(MessageEventNotification).Trace, []interface{}{"trace"})
+}
+
+func Test_Events_receivesErrorMessagesEvenWithoutAnErrorMessageHandler(t *testing.T) {
+	c := &Conversation{}
+	events := c.Events()
+
+	c.generatePotentialErrorMessage(ErrorCodeMessageMalformed)
+
+	received := receivedEvents(events)
+	assertEquals(t, len(received), 1)
+	assertEquals(t, received[0].(ErrorMessageNotification).Code, ErrorCodeMessageMalformed)
+}
+
+func Test_Events_stillCallsTheHandlers(t *testing.T) {
+	c := &Conversation{}
+	events := c.Events()
+	var handled []SecurityEvent
+	c.SetSecurityEventHandler(dynamicSecurityEventHandler{func(e SecurityEvent) {
+		handled = append(handled, e)
+	}})
+
+	c.securityEvent(GoneInsecure)
+
+	assertDeepEquals(t, handled, []SecurityEvent{GoneInsecure})
+	assertDeepEquals(t, securityEventsIn(receivedEvents(events)), []SecurityEvent{GoneInsecure})
+}
+
+func Test_Events_returnsTheSameChannelEveryTime(t *testing.T) {
+	c := &Conversation{}
+	assertEquals(t, c.Events(), c.Events())
+}
+
+func Test_Events_dropsTheOldestEventsWhenTheChannelIsFull(t *testing.T) {
+	c := &Conversation{}
+	events := c.Events()
+
+	c.securityEvent(GoneInsecure)
+	c.securityEvent(GoneSecure)
+	for i := 0; i < EventBufferSize; i++ {
+		c.securityEvent(StillSecure)
+	}
+
+	received := securityEventsIn(receivedEvents(events))
+	assertEquals(t, len(received), EventBufferSize)
+	assertEquals(t, received[0], StillSecure)
+	assertEquals(t, c.DroppedEvents(), uint64(2))
+}
+
+func Test_Events_doesNothingUntilAskedFor(t *testing.T) {
+	c := &Conversation{}
+
+	c.securityEvent(GoneInsecure)
+
+	assertNil(t, c.events)
+	assertEquals(t, c.DroppedEvents(), uint64(0))
+}
+
+func Test_Events_areSharedWithConversationsForOtherInstances(t *testing.T) {
+	c := &Conversation{}
+	events := c.Events()
+
+	other := c.newInstanceConversation(0x101)
+	other.securityEvent(GoneSecure)
+
+	received := receivedEvents(events)
+	assertEquals(t, len(received), 1)
+	assertEquals(t, received[0].Info().Conversation, other)
+	assertEquals(t, received[0].Info().TheirInstanceTag, uint32(0x101))
+}
+
+func Test_Manager_Events_receivesEventsFromAllNewConversations(t *testing.T) {
+	m := newTestManager()
+	events := m.Events()
+
+	c1, _ := m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+	c2, _ := m.Conversation("alice", "irc", "bob")
+	c1.securityEvent(GoneSecure)
+	c2.securityEvent(GoneInsecure)
+
+	received := receivedEvents(events)
+	assertEquals(t, len(received), 2)
+	assertEquals(t, received[0].Info().Conversation, c1)
+	assertEquals(t, received[1].Info().Conversation, c2)
+	assertEquals(t, c1.Events(), events)
+}
+
+func Test_LockedConversation_Events_returnsTheEventsOfTheConversation(t *testing.T) {
+	c := &Conversation{}
+	l := NewLockedConversation(c)
+
+	assertEquals(t, l.Events(), c.Events())
+	assertEquals(t, l.DroppedEvents(), uint64(0))
+}
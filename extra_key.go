@@ -11,9 +11,11 @@ func (c *Conversation) processExtraSymmetricKeyTLV(t tlv, x dataMessageExtra) (t
 // UseExtraSymmetricKey takes a usage parameter and optional usageData and returns the current symmetric key
 // and a set of messages to send in order to ask the peer to use the same symmetric key for the usage defined
 func (c *Conversation) UseExtraSymmetricKey(usage uint32, usageData []byte) ([]byte, []ValidMessage, error) {
-	if c.msgState != encrypted ||
-		c.keys.theirKeyID == 0 {
-		return nil, nil, newOtrError("cannot send message in current state")
+	if c.msgState != encrypted {
+		return nil, nil, ErrNotEncrypted
+	}
+	if c.keys.theirKeyID == 0 {
+		return nil, nil, ErrNoPeerKey
 	}
 
 	t := tlv{
@@ -86,7 +86,7 @@ func Test_UseExtraSymmetricKey_returnsErrorIfWeAreNotInEncryptedMode(t *testing.
 	c.msgState = plainText
 
 	_, _, err := c.UseExtraSymmetricKey(0, nil)
-	assertDeepEquals(t, err, newOtrError("cannot send message in current state"))
+	assertDeepEquals(t, err, ErrNotEncrypted)
 }
 
 func Test_UseExtraSymmetricKey_returnsErrorIfTheirKeyIDIsZero(t *testing.T) {
@@ -95,7 +95,7 @@ func Test_UseExtraSymmetricKey_returnsErrorIfTheirKeyIDIsZero(t *testing.T) {
 	c.keys.theirKeyID = 0
 
 	_, _, err := c.UseExtraSymmetricKey(0, nil)
-	assertDeepEquals(t, err, newOtrError("cannot send message in current state"))
+	assertDeepEquals(t, err, ErrNoPeerKey)
 }
 
 func Test_UseExtraSymmetricKey_generatesADataMessageWithTheDataProvided(t *testing.T) {
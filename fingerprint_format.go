@@ -0,0 +1,85 @@
+package otr3
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// FingerprintURIScheme is the scheme of the URIs created by FingerprintURI
+const FingerprintURIScheme = "otr-fingerprint"
+
+var errInvalidFingerprint = newOtrError("invalid fingerprint")
+var errInvalidFingerprintURI = newOtrError("invalid fingerprint URI")
+
+// FormatFingerprint returns the fingerprint the way libotr shows it to users - upper case hex in groups of eight
+// characters separated by spaces, for example "12345678 9ABCDEF0 12345678 9ABCDEF0 12345678"
+func FormatFingerprint(fingerprint []byte) string {
+	h := strings.ToUpper(hex.EncodeToString(fingerprint))
+
+	groups := make([]string, 0, (len(h)+7)/8)
+	for len(h) > 8 {
+		groups = append(groups, h[:8])
+		h = h[8:]
+	}
+	groups = append(groups, h)
+
+	return strings.Join(groups, " ")
+}
+
+func isFingerprintSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ':' || r == '-'
+}
+
+// ParseFingerprint parses a fingerprint typed or pasted by a user. Upper and lower case hex are accepted, and
+// spaces, colons and dashes anywhere are ignored. It returns an error if the result isn't exactly one fingerprint.
+func ParseFingerprint(s string) ([]byte, error) {
+	h := strings.Map(func(r rune) rune {
+		if isFingerprintSeparator(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	fingerprint, err := hex.DecodeString(h)
+	if err != nil || len(fingerprint) != fingerprintHashInstanceForVersion(3).Size() {
+		return nil, errInvalidFingerprint
+	}
+	return fingerprint, nil
+}
+
+// FingerprintsEqual returns true if the two fingerprints are the same. The comparison takes the same amount of
+// time no matter where the fingerprints differ.
+func FingerprintsEqual(a, b []byte) bool {
+	return len(a) == len(b) && subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// FingerprintURI returns a URI for the fingerprint of the given account, suitable for showing as a QR code so that
+// a peer can verify the fingerprint out of band. It looks like this:
+//
+//	otr-fingerprint:0123456789abcdef0123456789abcdef01234567?account=alice%40example.org
+//
+// The account is left out if it is empty.
+func FingerprintURI(account string, fingerprint []byte) string {
+	uri := FingerprintURIScheme + ":" + hex.EncodeToString(fingerprint)
+	if account != "" {
+		uri += "?" + url.Values{"account": {account}}.Encode()
+	}
+	return uri
+}
+
+// ParseFingerprintURI returns the account and fingerprint of a URI created by FingerprintURI.
+// The account is empty if the URI doesn't have one.
+func ParseFingerprintURI(uri string) (account string, fingerprint []byte, err error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || !strings.EqualFold(u.Scheme, FingerprintURIScheme) || u.Opaque == "" {
+		return "", nil, errInvalidFingerprintURI
+	}
+
+	if fingerprint, err = ParseFingerprint(u.Opaque); err != nil {
+		return "", nil, errInvalidFingerprintURI
+	}
+
+	return u.Query().Get("account"), fingerprint, nil
+}
@@ -0,0 +1,88 @@
+package otr3
+
+import "testing"
+
+var fixtureFingerprint = bytesFromHex("0123456789abcdef0123456789abcdef01234567")
+
+func Test_FormatFingerprint_groupsTheFingerprintLikeLibOTR(t *testing.T) {
+	assertEquals(t, FormatFingerprint(fixtureFingerprint), "01234567 89ABCDEF 01234567 89ABCDEF 01234567")
+	assertEquals(t, FormatFingerprint([]byte{0xab, 0xcd}), "ABCD")
+	assertEquals(t, FormatFingerprint(nil), "")
+}
+
+func Test_ParseFingerprint_acceptsCommonWaysOfWritingFingerprints(t *testing.T) {
+	for _, s := range []string{
+		"01234567 89ABCDEF 01234567 89ABCDEF 01234567",
+		"0123456789abcdef0123456789abcdef01234567",
+		" 01234567 89abcdef\t01234567 89ABCDEF 01234567\n",
+		"01:23:45:67:89:ab:cd:ef:01:23:45:67:89:ab:cd:ef:01:23:45:67",
+		"01234567-89ABCDEF-01234567-89ABCDEF-01234567",
+	} {
+		fp, err := ParseFingerprint(s)
+		assertNil(t, err)
+		assertDeepEquals(t, fp, fixtureFingerprint)
+	}
+}
+
+func Test_ParseFingerprint_returnsAnErrorForInvalidFingerprints(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"01234567 89ABCDEF 01234567 89ABCDEF",
+		"01234567 89ABCDEF 01234567 89ABCDEF 01234567 89",
+		"01234567 89ABCDEF 01234567 89ABCDEF 0123456G",
+		"01234567 89ABCDEF 01234567 89ABCDEF 0123456",
+	} {
+		_, err := ParseFingerprint(s)
+		assertEquals(t, err, errInvalidFingerprint)
+	}
+}
+
+func Test_ParseFingerprint_canParseAFormattedFingerprint(t *testing.T) {
+	fp := alicePrivateKey.PublicKey().Fingerprint()
+
+	res, err := ParseFingerprint(FormatFingerprint(fp))
+
+	assertNil(t, err)
+	assertDeepEquals(t, res, fp)
+}
+
+func Test_FingerprintsEqual_comparesFingerprints(t *testing.T) {
+	other := makeCopy(fixtureFingerprint)
+	other[19] ^= 0x01
+
+	assertTrue(t, FingerprintsEqual(fixtureFingerprint, makeCopy(fixtureFingerprint)))
+	assertFalse(t, FingerprintsEqual(fixtureFingerprint, other))
+	assertFalse(t, FingerprintsEqual(fixtureFingerprint, fixtureFingerprint[:10]))
+	assertFalse(t, FingerprintsEqual(fixtureFingerprint, nil))
+}
+
+func Test_FingerprintURI_includesTheAccount(t *testing.T) {
+	assertEquals(t, FingerprintURI("alice@example.org", fixtureFingerprint), "otr-fingerprint:0123456789abcdef0123456789abcdef01234567?account=alice%40example.org")
+	assertEquals(t, FingerprintURI("", fixtureFingerprint), "otr-fingerprint:0123456789abcdef0123456789abcdef01234567")
+}
+
+func Test_ParseFingerprintURI_returnsTheAccountAndFingerprint(t *testing.T) {
+	account, fp, err := ParseFingerprintURI(FingerprintURI("alice@example.org", fixtureFingerprint))
+	assertNil(t, err)
+	assertEquals(t, account, "alice@example.org")
+	assertDeepEquals(t, fp, fixtureFingerprint)
+
+	account, fp, err = ParseFingerprintURI("OTR-FINGERPRINT:0123456789ABCDEF0123456789ABCDEF01234567\n")
+	assertNil(t, err)
+	assertEquals(t, account, "")
+	assertDeepEquals(t, fp, fixtureFingerprint)
+}
+
+func Test_ParseFingerprintURI_returnsAnErrorForInvalidURIs(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"otr-fingerprint:",
+		"otr-fingerprint:0123",
+		"http://example.org/0123456789abcdef0123456789abcdef01234567",
+		"xmpp:0123456789abcdef0123456789abcdef01234567",
+		"otr-fingerprint:%zz",
+	} {
+		_, _, err := ParseFingerprintURI(s)
+		assertEquals(t, err, errInvalidFingerprintURI)
+	}
+}
@@ -0,0 +1,242 @@
+package otr3
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// TrustUnverified is the trust level of a fingerprint we have seen but never verified
+	TrustUnverified = ""
+	// TrustVerified is the trust level libotr clients use for fingerprints verified manually by the user
+	TrustVerified = "verified"
+	// TrustSMP is the trust level libotr clients use for fingerprints verified through a successful SMP run
+	TrustSMP = "smp"
+)
+
+// KnownFingerprint is a fingerprint we have seen for a peer. It corresponds to one line in a libotr fingerprints file
+type KnownFingerprint struct {
+	Peer        string
+	Account     string
+	Protocol    string
+	Fingerprint []byte
+	Trust       string
+}
+
+// IsVerified returns true if the fingerprint has been verified in any way
+func (k KnownFingerprint) IsVerified() bool {
+	return k.Trust != TrustUnverified
+}
+
+func (k *KnownFingerprint) matches(account, protocol, peer string) bool {
+	return k.Account == account && k.Protocol == protocol && k.Peer == peer
+}
+
+// FingerprintStore keeps track of the fingerprints we know for our peers, and their trust levels.
+// The zero value is an empty store ready to use. It is safe to use a FingerprintStore from several goroutines,
+// which means it can be queried from inside of event handlers - for example a SecurityEventHandler can check
+// whether the key of a newly secured conversation is verified:
+//
+//	store.IsVerified(account, protocol, peer, c.GetTheirKey().Fingerprint())
+type FingerprintStore struct {
+	lock    sync.RWMutex
+	entries []*KnownFingerprint
+}
+
+func (s *FingerprintStore) find(account, protocol, peer string, fingerprint []byte) *KnownFingerprint {
+	for _, e := range s.entries {
+		if e.matches(account, protocol, peer) && bytes.Equal(e.Fingerprint, fingerprint) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *FingerprintStore) findOrAdd(account, protocol, peer string, fingerprint []byte) *KnownFingerprint {
+	if e := s.find(account, protocol, peer, fingerprint); e != nil {
+		return e
+	}
+
+	e := &KnownFingerprint{
+		Peer:        peer,
+		Account:     account,
+		Protocol:    protocol,
+		Fingerprint: makeCopy(fingerprint),
+	}
+	s.entries = append(s.entries, e)
+	return e
+}
+
+// Add will remember the fingerprint for the given peer. If the fingerprint is already known, its trust level is left alone,
+// otherwise it will be added as unverified.
+func (s *FingerprintStore) Add(account, protocol, peer string, fingerprint []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.findOrAdd(account, protocol, peer, fingerprint)
+}
+
+// SetTrust sets the trust level for the given fingerprint, adding it to the store if necessary.
+// After a successful SMP run, this should be called with TrustSMP.
+func (s *FingerprintStore) SetTrust(account, protocol, peer string, fingerprint []byte, trust string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.findOrAdd(account, protocol, peer, fingerprint).Trust = trust
+}
+
+// Lookup returns the entry for the given fingerprint, and not ok if the fingerprint isn't known for that peer
+func (s *FingerprintStore) Lookup(account, protocol, peer string, fingerprint []byte) (KnownFingerprint, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if e := s.find(account, protocol, peer, fingerprint); e != nil {
+		return *e, true
+	}
+	return KnownFingerprint{}, false
+}
+
+// IsVerified returns true if the fingerprint is known for the peer and has been verified
+func (s *FingerprintStore) IsVerified(account, protocol, peer string, fingerprint []byte) bool {
+	e, ok := s.Lookup(account, protocol, peer, fingerprint)
+	return ok && e.IsVerified()
+}
+
+// FingerprintsFor returns all the fingerprints known for the given peer
+func (s *FingerprintStore) FingerprintsFor(account, protocol, peer string) []KnownFingerprint {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var result []KnownFingerprint
+	for _, e := range s.entries {
+		if e.matches(account, protocol, peer) {
+			result = append(result, *e)
+		}
+	}
+	return result
+}
+
+// All returns every fingerprint in the store, in the order they were added
+func (s *FingerprintStore) All() []KnownFingerprint {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := make([]KnownFingerprint, len(s.entries))
+	for i, e := range s.entries {
+		result[i] = *e
+	}
+	return result
+}
+
+// Remove forgets the given fingerprint. It returns false if the fingerprint wasn't known
+func (s *FingerprintStore) Remove(account, protocol, peer string, fingerprint []byte) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, e := range s.entries {
+		if e.matches(account, protocol, peer) && bytes.Equal(e.Fingerprint, fingerprint) {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ImportFingerprintsFromFile will read the libotr formatted fingerprints file given and return a store with all entries in it
+func ImportFingerprintsFromFile(fname string) (*FingerprintStore, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ImportFingerprints(f)
+}
+
+// ExportFingerprintsToFile will create the named file (or truncate it) and write all the fingerprints in the store to that file in libotr format.
+func ExportFingerprintsToFile(s *FingerprintStore, fname string) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+
+	err = ExportFingerprints(s, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ImportFingerprints will read libotr formatted fingerprint data and return a store with all entries in it.
+// Just like libotr, lines that can't be parsed are ignored.
+func ImportFingerprints(r io.Reader) (*FingerprintStore, error) {
+	s := &FingerprintStore{}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if e, ok := parseFingerprintLine(line); ok {
+			s.entries = append(s.entries, e)
+		}
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ExportFingerprints will write all the fingerprints in the store in libotr format
+func ExportFingerprints(s *FingerprintStore, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range s.All() {
+		exportFingerprint(e, bw)
+	}
+	return bw.Flush()
+}
+
+// A fingerprint line looks like this:
+//
+//	peer<TAB>account<TAB>protocol<TAB>hex fingerprint[<TAB>trust]
+func parseFingerprintLine(line string) (*KnownFingerprint, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	parts := strings.Split(line, "\t")
+	if len(parts) < 4 {
+		return nil, false
+	}
+
+	fpr, err := hex.DecodeString(parts[3])
+	if err != nil || len(fpr) != fingerprintHashInstanceForVersion(3).Size() {
+		return nil, false
+	}
+
+	e := &KnownFingerprint{
+		Peer:        parts[0],
+		Account:     parts[1],
+		Protocol:    parts[2],
+		Fingerprint: fpr,
+	}
+
+	if len(parts) > 4 {
+		e.Trust = parts[4]
+	}
+
+	return e, true
+}
+
+func exportFingerprint(e KnownFingerprint, w *bufio.Writer) {
+	w.WriteString(e.Peer)
+	w.WriteString("\t")
+	w.WriteString(e.Account)
+	w.WriteString("\t")
+	w.WriteString(e.Protocol)
+	w.WriteString("\t")
+	w.WriteString(hex.EncodeToString(e.Fingerprint))
+	w.WriteString("\t")
+	w.WriteString(e.Trust)
+	w.WriteString("\n")
+}
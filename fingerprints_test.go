@@ -0,0 +1,141 @@
+package otr3
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+const fixtureFingerprintsFile = "bob@example.org\talice@example.org/laptop\tprpl-jabber\t0102030405060708090a0b0c0d0e0f1011121314\tsmp\n" +
+	"bob@example.org\talice@example.org/laptop\tprpl-jabber\tfffefdfcfbfaf9f8f7f6f5f4f3f2f1f0efeeedec\t\n" +
+	"carol@example.org\talice@example.org/laptop\tprpl-jabber\taaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\n"
+
+func Test_ImportFingerprints_readsAllEntries(t *testing.T) {
+	s, err := ImportFingerprints(bytes.NewBufferString(fixtureFingerprintsFile))
+	assertNil(t, err)
+
+	all := s.All()
+	assertEquals(t, len(all), 3)
+	assertDeepEquals(t, all[0], KnownFingerprint{
+		Peer:        "bob@example.org",
+		Account:     "alice@example.org/laptop",
+		Protocol:    "prpl-jabber",
+		Fingerprint: bytesFromHex("0102030405060708090a0b0c0d0e0f1011121314"),
+		Trust:       TrustSMP,
+	})
+	assertEquals(t, all[1].Trust, TrustUnverified)
+	assertEquals(t, all[2].Peer, "carol@example.org")
+	assertEquals(t, all[2].Trust, TrustUnverified)
+}
+
+func Test_ImportFingerprints_ignoresMalformedLines(t *testing.T) {
+	s, err := ImportFingerprints(bytes.NewBufferString("not a fingerprint line\n" +
+		"bob\talice\tprpl-jabber\tzzzz\tsmp\n" +
+		"bob\talice\tprpl-jabber\t0102\tsmp\n" +
+		"bob\talice\tprpl-jabber\t0102030405060708090a0b0c0d0e0f1011121314\tverified"))
+	assertNil(t, err)
+
+	all := s.All()
+	assertEquals(t, len(all), 1)
+	assertEquals(t, all[0].Trust, TrustVerified)
+}
+
+func Test_ExportFingerprints_writesTheLibOTRFormat(t *testing.T) {
+	s, _ := ImportFingerprints(bytes.NewBufferString(fixtureFingerprintsFile))
+	bt := bytes.NewBuffer(nil)
+
+	err := ExportFingerprints(s, bt)
+	assertNil(t, err)
+	assertEquals(t, bt.String(),
+		"bob@example.org\talice@example.org/laptop\tprpl-jabber\t0102030405060708090a0b0c0d0e0f1011121314\tsmp\n"+
+			"bob@example.org\talice@example.org/laptop\tprpl-jabber\tfffefdfcfbfaf9f8f7f6f5f4f3f2f1f0efeeedec\t\n"+
+			"carol@example.org\talice@example.org/laptop\tprpl-jabber\taaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\t\n")
+}
+
+func Test_ExportFingerprintsToFile_canBeReadBack(t *testing.T) {
+	s, _ := ImportFingerprints(bytes.NewBufferString(fixtureFingerprintsFile))
+
+	err := ExportFingerprintsToFile(s, "test_resources/test_export_of_fingerprints.blah")
+	assertNil(t, err)
+	defer os.Remove("test_resources/test_export_of_fingerprints.blah")
+
+	res, err2 := ImportFingerprintsFromFile("test_resources/test_export_of_fingerprints.blah")
+	assertNil(t, err2)
+	assertDeepEquals(t, res.All(), s.All())
+}
+
+func Test_ImportFingerprintsFromFile_returnsAnErrorIfTheFileDoesntExist(t *testing.T) {
+	_, err := ImportFingerprintsFromFile("this_file_doesnt_exist.fingerprints")
+	assertNotNil(t, err)
+}
+
+func Test_FingerprintStore_addsUnverifiedFingerprints(t *testing.T) {
+	s := &FingerprintStore{}
+	fpr := bobPrivateKey.PublicKey().Fingerprint()
+
+	s.Add("alice", "xmpp", "bob", fpr)
+	s.Add("alice", "xmpp", "bob", fpr)
+
+	assertEquals(t, len(s.All()), 1)
+	e, ok := s.Lookup("alice", "xmpp", "bob", fpr)
+	assertTrue(t, ok)
+	assertEquals(t, e.Trust, TrustUnverified)
+	assertFalse(t, s.IsVerified("alice", "xmpp", "bob", fpr))
+}
+
+func Test_FingerprintStore_SetTrustMarksAFingerprintAsVerified(t *testing.T) {
+	s := &FingerprintStore{}
+	fpr := bobPrivateKey.PublicKey().Fingerprint()
+	s.Add("alice", "xmpp", "bob", fpr)
+
+	s.SetTrust("alice", "xmpp", "bob", fpr, TrustSMP)
+
+	assertTrue(t, s.IsVerified("alice", "xmpp", "bob", fpr))
+	assertFalse(t, s.IsVerified("alice", "xmpp", "carol", fpr))
+	assertFalse(t, s.IsVerified("alice", "irc", "bob", fpr))
+}
+
+func Test_FingerprintStore_AddDoesntChangeTheTrustOfAKnownFingerprint(t *testing.T) {
+	s := &FingerprintStore{}
+	fpr := bobPrivateKey.PublicKey().Fingerprint()
+	s.SetTrust("alice", "xmpp", "bob", fpr, TrustVerified)
+
+	s.Add("alice", "xmpp", "bob", fpr)
+
+	assertTrue(t, s.IsVerified("alice", "xmpp", "bob", fpr))
+}
+
+func Test_FingerprintStore_FingerprintsForReturnsOnlyTheFingerprintsOfThatPeer(t *testing.T) {
+	s, _ := ImportFingerprints(bytes.NewBufferString(fixtureFingerprintsFile))
+
+	res := s.FingerprintsFor("alice@example.org/laptop", "prpl-jabber", "bob@example.org")
+	assertEquals(t, len(res), 2)
+	assertEquals(t, len(s.FingerprintsFor("alice@example.org/laptop", "prpl-jabber", "dave@example.org")), 0)
+}
+
+func Test_FingerprintStore_RemoveForgetsTheFingerprint(t *testing.T) {
+	s, _ := ImportFingerprints(bytes.NewBufferString(fixtureFingerprintsFile))
+	fpr := bytesFromHex("0102030405060708090a0b0c0d0e0f1011121314")
+
+	assertTrue(t, s.Remove("alice@example.org/laptop", "prpl-jabber", "bob@example.org", fpr))
+	assertFalse(t, s.Remove("alice@example.org/laptop", "prpl-jabber", "bob@example.org", fpr))
+
+	_, ok := s.Lookup("alice@example.org/laptop", "prpl-jabber", "bob@example.org", fpr)
+	assertFalse(t, ok)
+	assertEquals(t, len(s.All()), 2)
+}
+
+func Test_FingerprintStore_canBeQueriedFromASecurityEventHandler(t *testing.T) {
+	s := &FingerprintStore{}
+	c := bobContextAfterAKE()
+	c.theirKey = alicePrivateKey.PublicKey()
+	s.SetTrust("bob", "xmpp", "alice", alicePrivateKey.PublicKey().Fingerprint(), TrustSMP)
+
+	verified := false
+	c.securityEventHandler = dynamicSecurityEventHandler{func(event SecurityEvent) {
+		verified = s.IsVerified("bob", "xmpp", "alice", c.GetTheirKey().Fingerprint())
+	}}
+	c.securityEvent(GoneSecure)
+
+	assertTrue(t, verified)
+}
@@ -0,0 +1,130 @@
+// Package forge shows how the deniability of OTR works, by changing data messages so that they still look valid.
+//
+// Data messages are encrypted with AES in counter mode. Anyone who knows part of the plaintext can change it at the
+// same position in the encrypted message, without knowing the key. MAC keys are revealed to everyone once they are no
+// longer used, so anyone can then give a changed message a valid MAC. And anyone who learns an AES key can decrypt the
+// message and replace all of it. All of this means that a transcript of an OTR conversation proves nothing.
+//
+// This package does the same thing as otr_readforge, otr_modify and otr_remac from the libotr toolkit.
+package forge
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+
+	"github.com/coyim/otr3"
+)
+
+var (
+	// ErrNotDataMessage is returned when the message to change isn't a complete OTR data message
+	ErrNotDataMessage = errors.New("forge: not a data message")
+	// ErrOutOfRange is returned when a change doesn't fit inside the encrypted message
+	ErrOutOfRange = errors.New("forge: change is outside of the encrypted message")
+	// ErrLengthMismatch is returned when the old and new text of a change don't have the same length
+	ErrLengthMismatch = errors.New("forge: old and new text must have the same length")
+)
+
+// Message is a data message that can be changed. All the fields found by otr3.ParseMessage can be changed directly,
+// and are used when the message is encoded again.
+type Message struct {
+	otr3.ParsedMessage
+}
+
+// Parse returns the data message in msg, which should be a complete "?OTR:" message. Fragments have to be put
+// together first.
+func Parse(msg []byte) (*Message, error) {
+	p, err := otr3.ParseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	if p.Type != otr3.MessageTypeData {
+		return nil, ErrNotDataMessage
+	}
+	return &Message{p}, nil
+}
+
+// MACKeyFor returns the MAC key that belongs to the given AES key. Whoever knows the AES key of a message can
+// always calculate a valid MAC for it.
+func MACKeyFor(aesKey []byte) []byte {
+	k := sha1.Sum(aesKey)
+	return k[:]
+}
+
+func (m *Message) keyStream(aesKey []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, m.TopHalfCounter[:])
+
+	out := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(out, data)
+	return out, nil
+}
+
+// Decrypt returns the plaintext of the message. It has the message the user sees, followed by a NUL byte and
+// the TLVs of the message, if there are any.
+func (m *Message) Decrypt(aesKey []byte) ([]byte, error) {
+	return m.keyStream(aesKey, m.EncryptedMessage)
+}
+
+// Encrypt replaces the encrypted message with the given plaintext, which doesn't have to have the same length as the
+// one it replaces. The MAC is not changed.
+func (m *Message) Encrypt(aesKey, plaintext []byte) error {
+	enc, err := m.keyStream(aesKey, plaintext)
+	if err != nil {
+		return err
+	}
+	m.EncryptedMessage = enc
+	return nil
+}
+
+// Replace changes the plaintext at offset from oldText to newText, without knowing the key. If oldText isn't
+// what the plaintext really has at offset, the result will be garbage. The MAC is not changed.
+func (m *Message) Replace(offset int, oldText, newText []byte) error {
+	if len(oldText) != len(newText) {
+		return ErrLengthMismatch
+	}
+	if offset < 0 || offset+len(oldText) > len(m.EncryptedMessage) {
+		return ErrOutOfRange
+	}
+
+	enc := append([]byte{}, m.EncryptedMessage...)
+	for i := range oldText {
+		enc[offset+i] ^= oldText[i] ^ newText[i]
+	}
+	m.EncryptedMessage = enc
+	return nil
+}
+
+func (m *Message) calculateMAC(macKey []byte) ([]byte, error) {
+	data, err := m.AuthenticatedData()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, macKey)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// ReMAC calculates a new MAC for the message with the given MAC key, for example one that was revealed later in
+// the conversation.
+func (m *Message) ReMAC(macKey []byte) error {
+	mac, err := m.calculateMAC(macKey)
+	if err != nil {
+		return err
+	}
+	m.Authenticator = mac
+	return nil
+}
+
+// VerifyMAC returns true if the message has a valid MAC for the given MAC key
+func (m *Message) VerifyMAC(macKey []byte) bool {
+	mac, err := m.calculateMAC(macKey)
+	return err == nil && hmac.Equal(m.Authenticator, mac)
+}
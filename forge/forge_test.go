@@ -0,0 +1,148 @@
+package forge
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/coyim/otr3"
+)
+
+var alicePrivateKeyHex = "000000000080c81c2cb2eb729b7e6fd48e975a932c638b3a9055478583afa46755683e30102447f6da2d8bec9f386bbb5da6403b0040fee8650b6ab2d7f32c55ab017ae9b6aec8c324ab5844784e9a80e194830d548fb7f09a0410df2c4d5c8bc2b3e9ad484e65412be689cf0834694e0839fb2954021521ffdffb8f5c32c14dbf2020b3ce7500000014da4591d58def96de61aea7b04a8405fe1609308d000000808ddd5cb0b9d66956e3dea5a915d9aba9d8a6e7053b74dadb2fc52f9fe4e5bcc487d2305485ed95fed026ad93f06ebb8c9e8baf693b7887132c7ffdd3b0f72f4002ff4ed56583ca7c54458f8c068ca3e8a4dfa309d1dd5d34e2a4b68e6f4338835e5e0fb4317c9e4c7e4806dafda3ef459cd563775a586dd91b1319f72621bf3f00000080b8147e74d8c45e6318c37731b8b33b984a795b3653c2cd1d65cc99efe097cb7eb2fa49569bab5aab6e8a1c261a27d0f7840a5e80b317e6683042b59b6dceca2879c6ffc877a465be690c15e4a42f9a7588e79b10faac11b1ce3741fcef7aba8ce05327a2c16d279ee1b3d77eb783fb10e3356caa25635331e26dd42b8396c4d00000001420bec691fea37ecea58a5c717142f0b804452f57"
+var bobPrivateKeyHex = "000000000080a5138eb3d3eb9c1d85716faecadb718f87d31aaed1157671d7fee7e488f95e8e0ba60ad449ec732710a7dec5190f7182af2e2f98312d98497221dff160fd68033dd4f3a33b7c078d0d9f66e26847e76ca7447d4bab35486045090572863d9e4454777f24d6706f63e02548dfec2d0a620af37bbc1d24f884708a212c343b480d00000014e9c58f0ea21a5e4dfd9f44b6a9f7f6a9961a8fa9000000803c4d111aebd62d3c50c2889d420a32cdf1e98b70affcc1fcf44d59cca2eb019f6b774ef88153fb9b9615441a5fe25ea2d11b74ce922ca0232bd81b3c0fcac2a95b20cb6e6c0c5c1ace2e26f65dc43c751af0edbb10d669890e8ab6beea91410b8b2187af1a8347627a06ecea7e0f772c28aae9461301e83884860c9b656c722f0000008065af8625a555ea0e008cd04743671a3cda21162e83af045725db2eb2bb52712708dc0cc1a84c08b3649b88a966974bde27d8612c2861792ec9f08786a246fcadd6d8d3a81a32287745f309238f47618c2bd7612cb8b02d940571e0f30b96420bcd462ff542901b46109b1e5ad6423744448d20a57818a8cbb1647d0fea3b664e0000001440f9f2eb554cb00d45a5826b54bfa419b6980e48"
+
+var fixtureAESKey = []byte("0123456789abcdef")
+
+func newConversation(t *testing.T, keyHex string) *otr3.Conversation {
+	b, _ := hex.DecodeString(keyHex)
+	_, ok, key := otr3.ParsePrivateKey(b)
+	if !ok {
+		t.Fatalf("couldn't parse fixture key")
+	}
+
+	c := &otr3.Conversation{}
+	c.Policies.AllowV3()
+	c.SetOurKeys([]otr3.PrivateKey{key})
+	return c
+}
+
+func deliver(t *testing.T, to *otr3.Conversation, msgs []otr3.ValidMessage) []otr3.ValidMessage {
+	var toSend []otr3.ValidMessage
+	for _, m := range msgs {
+		_, s, err := to.Receive(m)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		toSend = append(toSend, s...)
+	}
+	return toSend
+}
+
+// conversationMessages returns the data messages of a short conversation between alice and bob
+func conversationMessages(t *testing.T) []otr3.ValidMessage {
+	alice := newConversation(t, alicePrivateKeyHex)
+	bob := newConversation(t, bobPrivateKeyHex)
+
+	msgs := []otr3.ValidMessage{otr3.ValidMessage("?OTRv3?")}
+	for len(msgs) > 0 {
+		msgs = deliver(t, alice, deliver(t, bob, msgs))
+	}
+
+	var data []otr3.ValidMessage
+	from, to := alice, bob
+	for _, text := range []string{"hello bob", "hi alice", "how are you?", "fine", "good"} {
+		msgs, err := from.Send(otr3.ValidMessage(text))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		deliver(t, to, msgs)
+		data = append(data, msgs...)
+		from, to = to, from
+	}
+	return data
+}
+
+func fixtureMessage() *Message {
+	m := &Message{}
+	m.Type = otr3.MessageTypeData
+	m.Version = 3
+	m.SenderInstanceTag, m.ReceiverInstanceTag = 0x101, 0x202
+	m.SenderKeyID, m.RecipientKeyID = 1, 2
+	m.NextDHPublicKey = big.NewInt(0x1234)
+	m.TopHalfCounter = [8]byte{0, 0, 0, 0, 0, 0, 0, 1}
+	m.Encrypt(fixtureAESKey, []byte("attack at dawn\x00"))
+	m.ReMAC(MACKeyFor(fixtureAESKey))
+	return m
+}
+
+func Test_Replace_changesKnownPlaintextWithoutTheKey(t *testing.T) {
+	m := fixtureMessage()
+
+	if err := m.Replace(10, []byte("dawn"), []byte("dusk")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plain, _ := m.Decrypt(fixtureAESKey); string(plain) != "attack at dusk\x00" {
+		t.Errorf("expected the changed plaintext, got %q", plain)
+	}
+	if m.VerifyMAC(MACKeyFor(fixtureAESKey)) {
+		t.Errorf("expected the MAC to be invalid after the change")
+	}
+
+	m.ReMAC(MACKeyFor(fixtureAESKey))
+	if !m.VerifyMAC(MACKeyFor(fixtureAESKey)) {
+		t.Errorf("expected the MAC to be valid again")
+	}
+}
+
+func Test_Replace_failsForInvalidChanges(t *testing.T) {
+	m := fixtureMessage()
+
+	if err := m.Replace(0, []byte("attack"), []byte("defend!")); err != ErrLengthMismatch {
+		t.Errorf("expected %v, got %v", ErrLengthMismatch, err)
+	}
+	if err := m.Replace(12, []byte("dawn"), []byte("dusk")); err != ErrOutOfRange {
+		t.Errorf("expected %v, got %v", ErrOutOfRange, err)
+	}
+}
+
+func Test_Parse_failsForOtherMessages(t *testing.T) {
+	if _, err := Parse([]byte("?OTRv3?")); err != ErrNotDataMessage {
+		t.Errorf("expected %v, got %v", ErrNotDataMessage, err)
+	}
+}
+
+func Test_revealedMACKeysCanBeUsedToForgeEarlierMessages(t *testing.T) {
+	msgs := conversationMessages(t)
+
+	var parsed []*Message
+	var revealed [][]byte
+	for _, msg := range msgs {
+		m, err := Parse(msg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed = append(parsed, m)
+		revealed = append(revealed, m.RevealedMACKeys...)
+	}
+
+	forged := 0
+	for _, m := range parsed {
+		for _, k := range revealed {
+			if !m.VerifyMAC(k) {
+				continue
+			}
+
+			m.Replace(0, []byte{0}, []byte{1})
+			m.ReMAC(k)
+			encoded, _ := m.Encode()
+			if f, err := Parse(encoded); err != nil || !f.VerifyMAC(k) {
+				t.Errorf("expected the forged message to have a valid MAC")
+			}
+			forged++
+		}
+	}
+
+	if forged == 0 {
+		t.Errorf("expected a revealed MAC key to be valid for an earlier message")
+	}
+}
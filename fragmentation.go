@@ -114,21 +114,36 @@ func (c *Conversation) receiveFragment(beforeCtx fragmentationContext, data Vali
 
 	if ignore {
 		c.messageEvent(MessageEventReceivedMessageForOtherInstance)
+		c.logDroppedFragment(ix, l, "not for this conversation")
 		return beforeCtx, nil
 	}
 
 	if !ok1 || !ok2 {
+		c.logDroppedFragment(ix, l, "invalid")
 		return beforeCtx, newOtrError("invalid OTR fragment")
 	}
 
 	switch {
 	case fragmentIsInvalid(ix, l):
+		c.logDroppedFragment(ix, l, "invalid index")
 		return beforeCtx.discardFragment(), nil
 	case fragmentIsFirstMessage(ix, l):
+		if beforeCtx.currentIndex > 0 && !fragmentsFinished(beforeCtx) {
+			c.logDroppedFragment(beforeCtx.currentIndex, beforeCtx.currentLen, "restarted by a new first fragment")
+		}
 		return restartFragment(resultData, ix, l), nil
 	case fragmentIsNextMessage(beforeCtx, ix, l):
 		return beforeCtx.appendFragment(resultData, ix, l), nil
 	default:
+		c.logDroppedFragment(ix, l, "out of order")
 		return forgetFragment(), nil
 	}
 }
+
+func (c *Conversation) logDroppedFragment(ix, l uint16, reason string) {
+	c.log(LogFragmentDropped,
+		LogField{Key: "index", Value: ix},
+		LogField{Key: "count", Value: l},
+		LogField{Key: "reason", Value: reason},
+	)
+}
@@ -2,15 +2,36 @@ package otr3
 
 import "time"
 
-// How long after sending a packet should we wait to send a heartbeat?
-const heartbeatInterval = 60 * time.Second
+// How long after sending a packet should we wait to send a heartbeat, if nothing else has been configured?
+const defaultHeartbeatInterval = 60 * time.Second
 
 type heartbeatContext struct {
 	lastSent time.Time
+	interval time.Duration
+	// unacknowledged is true if we have received a message since the last time we sent something
+	unacknowledged bool
+}
+
+func (h *heartbeatContext) sentAt(t time.Time) {
+	h.lastSent = t
+	h.unacknowledged = false
+}
+
+// SetHeartbeatInterval sets how long after sending a message we wait before sending a heartbeat to acknowledge the
+// messages we have received. Setting it to zero will use the default of 60 seconds.
+func (c *Conversation) SetHeartbeatInterval(d time.Duration) {
+	c.heartbeat.interval = d
+}
+
+func (c *Conversation) heartbeatInterval() time.Duration {
+	if c.heartbeat.interval == 0 {
+		return defaultHeartbeatInterval
+	}
+	return c.heartbeat.interval
 }
 
 func (c *Conversation) updateLastSent() {
-	c.heartbeat.lastSent = time.Now()
+	c.heartbeat.sentAt(c.now())
 }
 
 func (c *Conversation) maybeHeartbeat(plain MessagePlaintext, toSend messageWithHeader, err error) (MessagePlaintext, []messageWithHeader, error) {
@@ -26,8 +47,12 @@ func (c *Conversation) potentialHeartbeat(plain MessagePlaintext) (toSend messag
 		return
 	}
 
-	now := time.Now()
-	if !c.heartbeat.lastSent.Before(now.Add(-heartbeatInterval)) {
+	c.heartbeat.unacknowledged = true
+	return c.heartbeatAt(c.now())
+}
+
+func (c *Conversation) heartbeatAt(now time.Time) (toSend messageWithHeader, err error) {
+	if !c.heartbeat.lastSent.Before(now.Add(-c.heartbeatInterval())) {
 		return
 	}
 
@@ -41,7 +66,8 @@ func (c *Conversation) potentialHeartbeat(plain MessagePlaintext) (toSend messag
 		return nil, err
 	}
 
-	c.updateLastSent()
+	c.heartbeat.sentAt(now)
 	c.messageEvent(MessageEventLogHeartbeatSent)
+	c.count(MetricHeartbeatSent)
 	return
 }
@@ -6,6 +6,7 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func assertEquals(t *testing.T, actual, expected interface{}) {
@@ -182,3 +183,19 @@ func (c *Conversation) doesntExpectSecurityEvent(t *testing.T, f func()) {
 
 	f()
 }
+
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{time.Date(2016, 3, 14, 15, 9, 26, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.t
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.t = f.t.Add(d)
+}
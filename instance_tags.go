@@ -0,0 +1,172 @@
+package otr3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// InstanceTag is the instance tag we use for one account. It corresponds to one line in a libotr instance tags file
+type InstanceTag struct {
+	Account  string
+	Protocol string
+	Tag      uint32
+}
+
+// InstanceTagStore keeps track of our instance tags for each account, so that the same tag can be used every time a client
+// is started. The zero value is an empty store ready to use. It is safe to use an InstanceTagStore from several goroutines.
+type InstanceTagStore struct {
+	lock    sync.RWMutex
+	entries []*InstanceTag
+}
+
+func (s *InstanceTagStore) find(account, protocol string) *InstanceTag {
+	for _, e := range s.entries {
+		if e.Account == account && e.Protocol == protocol {
+			return e
+		}
+	}
+	return nil
+}
+
+// Lookup returns the instance tag for the given account, and not ok if there is none
+func (s *InstanceTagStore) Lookup(account, protocol string) (uint32, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if e := s.find(account, protocol); e != nil {
+		return e.Tag, true
+	}
+	return 0, false
+}
+
+// Set sets the instance tag for the given account, replacing any existing one
+func (s *InstanceTagStore) Set(account, protocol string, tag uint32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.set(account, protocol, tag)
+}
+
+func (s *InstanceTagStore) set(account, protocol string, tag uint32) {
+	if e := s.find(account, protocol); e != nil {
+		e.Tag = tag
+		return
+	}
+	s.entries = append(s.entries, &InstanceTag{Account: account, Protocol: protocol, Tag: tag})
+}
+
+// InstanceTagFor returns the instance tag for the given account. If there is none, a new one will be generated
+// using the random source given, added to the store and returned. The store should be exported after that, so
+// the same tag can be used the next time.
+func (s *InstanceTagStore) InstanceTagFor(account, protocol string, r io.Reader) (uint32, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if e := s.find(account, protocol); e != nil {
+		return e.Tag, nil
+	}
+
+	tag, err := randomInstanceTag(r)
+	if err != nil {
+		return 0, err
+	}
+	s.set(account, protocol, tag)
+	return tag, nil
+}
+
+// Remove forgets the instance tag for the given account. It returns false if there was none
+func (s *InstanceTagStore) Remove(account, protocol string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, e := range s.entries {
+		if e.Account == account && e.Protocol == protocol {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// All returns every instance tag in the store, in the order they were added
+func (s *InstanceTagStore) All() []InstanceTag {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := make([]InstanceTag, len(s.entries))
+	for i, e := range s.entries {
+		result[i] = *e
+	}
+	return result
+}
+
+// ImportInstanceTagsFromFile will read the libotr formatted instance tags file given and return a store with all entries in it
+func ImportInstanceTagsFromFile(fname string) (*InstanceTagStore, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ImportInstanceTags(f)
+}
+
+// ExportInstanceTagsToFile will create the named file (or truncate it) and write all the instance tags in the store to that file in libotr format.
+func ExportInstanceTagsToFile(s *InstanceTagStore, fname string) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ExportInstanceTags(s, f)
+}
+
+// ImportInstanceTags will read libotr formatted instance tag data and return a store with all entries in it.
+// Just like libotr, lines that can't be parsed and tags that are not valid are ignored.
+func ImportInstanceTags(r io.Reader) (*InstanceTagStore, error) {
+	s := &InstanceTagStore{}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if e, ok := parseInstanceTagLine(line); ok {
+			s.set(e.Account, e.Protocol, e.Tag)
+		}
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ExportInstanceTags will write all the instance tags in the store in libotr format
+func ExportInstanceTags(s *InstanceTagStore, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range s.All() {
+		fmt.Fprintf(bw, "%s\t%s\t%08x\n", e.Account, e.Protocol, e.Tag)
+	}
+	return bw.Flush()
+}
+
+// An instance tag line looks like this:
+//
+//	account<TAB>protocol<TAB>hex instance tag
+func parseInstanceTagLine(line string) (InstanceTag, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	parts := strings.Split(line, "\t")
+	if len(parts) != 3 {
+		return InstanceTag{}, false
+	}
+
+	tag, err := strconv.ParseUint(parts[2], 16, 32)
+	if err != nil || uint32(tag) < minValidInstanceTag {
+		return InstanceTag{}, false
+	}
+
+	return InstanceTag{Account: parts[0], Protocol: parts[1], Tag: uint32(tag)}, true
+}
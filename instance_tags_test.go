@@ -0,0 +1,109 @@
+package otr3
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+const fixtureInstanceTagsFile = "alice@example.org/laptop\tprpl-jabber\t1a2b3c4d\n" +
+	"alice\tprpl-irc\t00000100\n"
+
+func Test_ImportInstanceTags_readsAllEntries(t *testing.T) {
+	s, err := ImportInstanceTags(bytes.NewBufferString(fixtureInstanceTagsFile))
+	assertNil(t, err)
+
+	assertDeepEquals(t, s.All(), []InstanceTag{
+		{Account: "alice@example.org/laptop", Protocol: "prpl-jabber", Tag: 0x1a2b3c4d},
+		{Account: "alice", Protocol: "prpl-irc", Tag: 0x100},
+	})
+}
+
+func Test_ImportInstanceTags_ignoresMalformedLinesAndInvalidTags(t *testing.T) {
+	s, err := ImportInstanceTags(bytes.NewBufferString("not an instance tag line\n" +
+		"alice\tprpl-irc\tzzzz\n" +
+		"alice\tprpl-irc\t000000ff\n" +
+		"alice\tprpl-irc\t1ffffffff\n" +
+		"alice\tprpl-jabber\tabcdef01"))
+	assertNil(t, err)
+
+	assertDeepEquals(t, s.All(), []InstanceTag{{Account: "alice", Protocol: "prpl-jabber", Tag: 0xabcdef01}})
+}
+
+func Test_ExportInstanceTags_writesTheLibOTRFormat(t *testing.T) {
+	s, _ := ImportInstanceTags(bytes.NewBufferString(fixtureInstanceTagsFile))
+	bt := bytes.NewBuffer(nil)
+
+	err := ExportInstanceTags(s, bt)
+	assertNil(t, err)
+	assertEquals(t, bt.String(), fixtureInstanceTagsFile)
+}
+
+func Test_ExportInstanceTagsToFile_canBeReadBack(t *testing.T) {
+	s, _ := ImportInstanceTags(bytes.NewBufferString(fixtureInstanceTagsFile))
+
+	err := ExportInstanceTagsToFile(s, "test_resources/test_export_of_instance_tags.blah")
+	assertNil(t, err)
+	defer os.Remove("test_resources/test_export_of_instance_tags.blah")
+
+	res, err2 := ImportInstanceTagsFromFile("test_resources/test_export_of_instance_tags.blah")
+	assertNil(t, err2)
+	assertDeepEquals(t, res.All(), s.All())
+}
+
+func Test_ImportInstanceTagsFromFile_returnsAnErrorIfTheFileDoesntExist(t *testing.T) {
+	_, err := ImportInstanceTagsFromFile("this_file_doesnt_exist.instance_tags")
+	assertNotNil(t, err)
+}
+
+func Test_InstanceTagStore_SetReplacesTheTagOfAnAccount(t *testing.T) {
+	s := &InstanceTagStore{}
+
+	s.Set("alice", "xmpp", 0x1000)
+	s.Set("alice", "irc", 0x2000)
+	s.Set("alice", "xmpp", 0x3000)
+
+	assertEquals(t, len(s.All()), 2)
+	tag, ok := s.Lookup("alice", "xmpp")
+	assertTrue(t, ok)
+	assertEquals(t, tag, uint32(0x3000))
+}
+
+func Test_InstanceTagStore_LookupReturnsNotOKForUnknownAccounts(t *testing.T) {
+	s := &InstanceTagStore{}
+	s.Set("alice", "xmpp", 0x1000)
+
+	_, ok := s.Lookup("alice", "irc")
+	assertFalse(t, ok)
+}
+
+func Test_InstanceTagStore_InstanceTagForGeneratesATagOnlyOnce(t *testing.T) {
+	s := &InstanceTagStore{}
+
+	tag, err := s.InstanceTagFor("alice", "xmpp", fixedRand([]string{"00000001", "12345678"}))
+	assertNil(t, err)
+	assertEquals(t, tag, uint32(0x12345678))
+
+	tag, err = s.InstanceTagFor("alice", "xmpp", fixedRand([]string{"abcdef01"}))
+	assertNil(t, err)
+	assertEquals(t, tag, uint32(0x12345678))
+}
+
+func Test_InstanceTagStore_InstanceTagForReturnsAnErrorIfRandomnessFails(t *testing.T) {
+	s := &InstanceTagStore{}
+
+	_, err := s.InstanceTagFor("alice", "xmpp", fixedRand([]string{"0102"}))
+
+	assertEquals(t, err, errShortRandomRead)
+	assertEquals(t, len(s.All()), 0)
+}
+
+func Test_InstanceTagStore_RemoveForgetsTheTag(t *testing.T) {
+	s := &InstanceTagStore{}
+	s.Set("alice", "xmpp", 0x1000)
+
+	assertTrue(t, s.Remove("alice", "xmpp"))
+	assertFalse(t, s.Remove("alice", "xmpp"))
+	_, ok := s.Lookup("alice", "xmpp")
+	assertFalse(t, ok)
+}
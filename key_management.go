@@ -2,6 +2,7 @@ package otr3
 
 import (
 	"encoding/binary"
+	"fmt"
 	"hash"
 	"io"
 	"math/big"
@@ -175,14 +176,40 @@ func (k *keyManagementContext) revealMACKeysForOurPreviousKeyID() {
 }
 
 func (c *Conversation) rotateKeys(dataMessage dataMsg) error {
+	ourKeyID, theirKeyID, revealed := c.keys.ourKeyID, c.keys.theirKeyID, len(c.keys.oldMACKeys)
+
 	if err := c.keys.rotateOurKeys(dataMessage.recipientKeyID, c.rand()); err != nil {
 		return err
 	}
 	c.keys.rotateTheirKey(dataMessage.senderKeyID, dataMessage.y)
 
+	if ourKeyID != c.keys.ourKeyID || theirKeyID != c.keys.theirKeyID {
+		c.logKeyRotation(c.keys.oldMACKeys[revealed:])
+	}
+
 	return nil
 }
 
+func (c *Conversation) logKeyRotation(revealedMACKeys []macKey) {
+	if c.logger == nil {
+		return
+	}
+
+	// The keys are only formatted when they will be logged, since they would be redacted anyway
+	var revealed []string
+	if c.logSecrets {
+		for _, k := range revealedMACKeys {
+			revealed = append(revealed, fmt.Sprintf("%X", []byte(k)))
+		}
+	}
+
+	c.log(LogKeysRotated,
+		LogField{Key: "ourKeyID", Value: c.keys.ourKeyID},
+		LogField{Key: "theirKeyID", Value: c.keys.theirKeyID},
+		LogField{Key: "revealedMACKeys", Value: revealed, Secret: true},
+	)
+}
+
 func (k *keyManagementContext) rotateOurKeys(recipientKeyID uint32, randomness io.Reader) error {
 	if recipientKeyID == k.ourKeyID {
 		k.revealMACKeysForOurPreviousKeyID()
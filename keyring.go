@@ -0,0 +1,265 @@
+package otr3
+
+import (
+	"bytes"
+	"os"
+	"sync"
+)
+
+// Keyring stores the private keys of our accounts, identified by account name and protocol. It can be implemented
+// to keep keys wherever they are stored in a deployment - the implementations here keep them in memory, in a libotr
+// formatted file or in a passphrase encrypted file. A Keyring keeps at most one account for each name and protocol.
+type Keyring interface {
+	// List returns all accounts in the keyring
+	List() ([]*Account, error)
+	// Get returns the account with the given name and protocol, and not ok if there is no such account
+	Get(name, protocol string) (a *Account, ok bool, err error)
+	// Put adds the account to the keyring, replacing any existing account with the same name and protocol
+	Put(a *Account) error
+	// Delete removes the account with the given name and protocol. It is not an error if there is no such account
+	Delete(name, protocol string) error
+}
+
+// SetOurKeysFromKeyring assigns our private keys to the conversation from the account with the given name and protocol
+func (c *Conversation) SetOurKeysFromKeyring(k Keyring, name, protocol string) error {
+	a, ok, err := k.Get(name, protocol)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newOtrErrorf("no key for account %s (%s)", name, protocol)
+	}
+
+	c.SetOurKeys([]PrivateKey{a.Key})
+	return nil
+}
+
+func findAccountIn(acs []*Account, name, protocol string) (int, bool) {
+	for i, a := range acs {
+		if a.Name == name && a.Protocol == protocol {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func putAccountIn(acs []*Account, a *Account) []*Account {
+	if i, ok := findAccountIn(acs, a.Name, a.Protocol); ok {
+		acs[i] = a
+		return acs
+	}
+	return append(acs, a)
+}
+
+func deleteAccountFrom(acs []*Account, name, protocol string) ([]*Account, bool) {
+	if i, ok := findAccountIn(acs, name, protocol); ok {
+		return append(acs[:i], acs[i+1:]...), true
+	}
+	return acs, false
+}
+
+// MemoryKeyring is a Keyring that only keeps the keys in memory. The zero value is an empty keyring ready to use.
+// It is safe to use a MemoryKeyring from several goroutines.
+type MemoryKeyring struct {
+	lock     sync.RWMutex
+	accounts []*Account
+}
+
+// NewMemoryKeyring creates a keyring with the given accounts, for example the result of ImportKeysFromFile
+func NewMemoryKeyring(acs ...*Account) *MemoryKeyring {
+	k := &MemoryKeyring{}
+	for _, a := range acs {
+		k.accounts = putAccountIn(k.accounts, a)
+	}
+	return k
+}
+
+// List implements Keyring
+func (k *MemoryKeyring) List() ([]*Account, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	return append([]*Account{}, k.accounts...), nil
+}
+
+// Get implements Keyring
+func (k *MemoryKeyring) Get(name, protocol string) (*Account, bool, error) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	if i, ok := findAccountIn(k.accounts, name, protocol); ok {
+		return k.accounts[i], true, nil
+	}
+	return nil, false, nil
+}
+
+// Put implements Keyring
+func (k *MemoryKeyring) Put(a *Account) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.accounts = putAccountIn(k.accounts, a)
+	return nil
+}
+
+// PutAll adds all the accounts to the keyring at once, replacing any existing accounts with the same name and protocol
+func (k *MemoryKeyring) PutAll(acs ...*Account) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	for _, a := range acs {
+		k.accounts = putAccountIn(k.accounts, a)
+	}
+	return nil
+}
+
+// Delete implements Keyring
+func (k *MemoryKeyring) Delete(name, protocol string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.accounts, _ = deleteAccountFrom(k.accounts, name, protocol)
+	return nil
+}
+
+// FileKeyring is a Keyring that keeps the keys in a file. Every operation reads the file again, so changes made by
+// other programs will be seen, and every change writes the whole file. A file that doesn't exist is an empty keyring.
+// The file is replaced atomically and is only readable by the current user.
+// It is safe to use a FileKeyring from several goroutines, but not from several processes at the same time.
+type FileKeyring struct {
+	lock       sync.Mutex
+	fname      string
+	encrypted  bool
+	passphrase []byte
+}
+
+// NewFileKeyring creates a keyring that stores the keys in a plain libotr formatted file, just like ExportKeysToFile
+func NewFileKeyring(fname string) *FileKeyring {
+	return &FileKeyring{fname: fname}
+}
+
+// NewEncryptedFileKeyring creates a keyring that stores the keys in a file encrypted with the passphrase, just like
+// ExportKeysToFileWithPassphrase. If the file is a plain libotr file, it will be read and then encrypted on the first change.
+func NewEncryptedFileKeyring(fname string, passphrase []byte) *FileKeyring {
+	return &FileKeyring{fname: fname, encrypted: true, passphrase: makeCopy(passphrase)}
+}
+
+func (k *FileKeyring) load() ([]*Account, error) {
+	f, err := os.Open(k.fname)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if k.encrypted {
+		return ImportKeysWithPassphrase(f, k.passphrase)
+	}
+	return ImportKeys(f)
+}
+
+func (k *FileKeyring) save(acs []*Account) error {
+	var w bytes.Buffer
+	defer func() { wipeBytes(w.Bytes()) }()
+
+	if k.encrypted {
+		if err := ExportKeysWithPassphrase(acs, &w, k.passphrase); err != nil {
+			return err
+		}
+	} else {
+		exportAccounts(acs, &w)
+	}
+
+	return writeFileAtomically(k.fname, w.Bytes())
+}
+
+func writeFileAtomically(fname string, data []byte) error {
+	tmp := fname + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, fname)
+}
+
+// List implements Keyring
+func (k *FileKeyring) List() ([]*Account, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	return k.load()
+}
+
+// Get implements Keyring
+func (k *FileKeyring) Get(name, protocol string) (*Account, bool, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	acs, err := k.load()
+	if err != nil {
+		return nil, false, err
+	}
+	if i, ok := findAccountIn(acs, name, protocol); ok {
+		return acs[i], true, nil
+	}
+	return nil, false, nil
+}
+
+// Put implements Keyring
+func (k *FileKeyring) Put(a *Account) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	acs, err := k.load()
+	if err != nil {
+		return err
+	}
+	return k.save(putAccountIn(acs, a))
+}
+
+// PutAll adds all the accounts to the keyring, replacing any existing accounts with the same name and protocol.
+// The file is only written once, so either all or none of the accounts are added.
+func (k *FileKeyring) PutAll(acs ...*Account) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	existing, err := k.load()
+	if err != nil {
+		return err
+	}
+	for _, a := range acs {
+		existing = putAccountIn(existing, a)
+	}
+	return k.save(existing)
+}
+
+// Delete implements Keyring
+func (k *FileKeyring) Delete(name, protocol string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	acs, err := k.load()
+	if err != nil {
+		return err
+	}
+	acs, found := deleteAccountFrom(acs, name, protocol)
+	if !found {
+		return nil
+	}
+	return k.save(acs)
+}
@@ -0,0 +1,213 @@
+package otr3
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_MemoryKeyring_zeroValueIsEmpty(t *testing.T) {
+	k := &MemoryKeyring{}
+
+	acs, err := k.List()
+	_, ok, err2 := k.Get("alice@example.org", "prpl-jabber")
+
+	assertNil(t, err)
+	assertNil(t, err2)
+	assertEquals(t, len(acs), 0)
+	assertFalse(t, ok)
+}
+
+func Test_MemoryKeyring_canPutGetAndDeleteAccounts(t *testing.T) {
+	k := NewMemoryKeyring(fixtureAccounts()...)
+	other := &Account{Name: "alice@example.org", Protocol: "prpl-irc", Key: bobPrivateKey}
+
+	assertNil(t, k.Put(other))
+	a, ok, err := k.Get("alice@example.org", "prpl-irc")
+	assertNil(t, err)
+	assertTrue(t, ok)
+	assertEquals(t, a, other)
+
+	assertNil(t, k.Delete("alice@example.org", "prpl-jabber"))
+	_, ok, _ = k.Get("alice@example.org", "prpl-jabber")
+	assertFalse(t, ok)
+
+	acs, _ := k.List()
+	assertEquals(t, len(acs), 2)
+	assertEquals(t, acs[0].Name, "bob@example.org")
+	assertEquals(t, acs[1], other)
+}
+
+func Test_MemoryKeyring_replacesAccountsWithTheSameNameAndProtocol(t *testing.T) {
+	k := NewMemoryKeyring(fixtureAccounts()...)
+	replacement := &Account{Name: "alice@example.org", Protocol: "prpl-jabber", Key: bobPrivateKey}
+
+	k.Put(replacement)
+
+	acs, _ := k.List()
+	assertEquals(t, len(acs), 2)
+	assertEquals(t, acs[0], replacement)
+}
+
+func Test_MemoryKeyring_PutAllAddsAllTheAccounts(t *testing.T) {
+	k := &MemoryKeyring{}
+
+	assertNil(t, k.PutAll(fixtureAccounts()...))
+
+	acs, _ := k.List()
+	assertEquals(t, len(acs), 2)
+}
+
+func Test_MemoryKeyring_deletingAnUnknownAccountIsNotAnError(t *testing.T) {
+	k := NewMemoryKeyring(fixtureAccounts()...)
+
+	assertNil(t, k.Delete("someone@example.org", "prpl-jabber"))
+
+	acs, _ := k.List()
+	assertEquals(t, len(acs), 2)
+}
+
+func Test_FileKeyring_treatsAMissingFileAsEmpty(t *testing.T) {
+	k := NewFileKeyring("test_resources/this_file_doesnt_exist.blah")
+
+	acs, err := k.List()
+	_, ok, err2 := k.Get("alice@example.org", "prpl-jabber")
+	err3 := k.Delete("alice@example.org", "prpl-jabber")
+
+	assertNil(t, err)
+	assertNil(t, err2)
+	assertNil(t, err3)
+	assertEquals(t, len(acs), 0)
+	assertFalse(t, ok)
+}
+
+func Test_FileKeyring_storesAccountsInALibOTRFile(t *testing.T) {
+	fname := "test_resources/test_file_keyring.blah"
+	defer os.Remove(fname)
+	k := NewFileKeyring(fname)
+
+	for _, a := range fixtureAccounts() {
+		assertNil(t, k.Put(a))
+	}
+	assertNil(t, k.Delete("bob@example.org", "prpl-jabber"))
+
+	info, _ := os.Stat(fname)
+	assertEquals(t, info.Mode().Perm(), os.FileMode(0600))
+
+	res, err := ImportKeysFromFile(fname)
+	assertNil(t, err)
+	assertEquals(t, len(res), 1)
+	assertEquals(t, res[0].Name, "alice@example.org")
+
+	a, ok, err := NewFileKeyring(fname).Get("alice@example.org", "prpl-jabber")
+	assertNil(t, err)
+	assertTrue(t, ok)
+	assertDeepEquals(t, a.Key.Serialize(), alicePrivateKey.Serialize())
+}
+
+func Test_FileKeyring_PutAllMergesTheAccountsIntoTheFile(t *testing.T) {
+	fname := "test_resources/test_file_keyring_put_all.blah"
+	defer os.Remove(fname)
+	k := NewFileKeyring(fname)
+	replacement := &Account{Name: "alice@example.org", Protocol: "prpl-jabber", Key: bobPrivateKey}
+	other := &Account{Name: "alice@example.org", Protocol: "prpl-irc", Key: alicePrivateKey}
+
+	assertNil(t, k.PutAll(fixtureAccounts()...))
+	assertNil(t, k.PutAll(replacement, other))
+
+	acs, err := ImportKeysFromFile(fname)
+	assertNil(t, err)
+	assertEquals(t, len(acs), 3)
+	assertDeepEquals(t, acs[0].Key.Serialize(), bobPrivateKey.Serialize())
+	assertEquals(t, acs[2].Protocol, "prpl-irc")
+}
+
+func Test_FileKeyring_PutAllLeavesTheFileAloneIfItCantBeRead(t *testing.T) {
+	before, _ := ioutil.ReadFile("test_resources/invalid_key.asc")
+
+	err := NewFileKeyring("test_resources/invalid_key.asc").PutAll(fixtureAccounts()...)
+
+	after, _ := ioutil.ReadFile("test_resources/invalid_key.asc")
+	assertNotNil(t, err)
+	assertDeepEquals(t, after, before)
+}
+
+func Test_FileKeyring_returnsAnErrorForAnInvalidFile(t *testing.T) {
+	k := NewFileKeyring("test_resources/invalid_key.asc")
+
+	_, err := k.List()
+	_, _, err2 := k.Get("alice@example.org", "prpl-jabber")
+	err3 := k.Put(fixtureAccounts()[0])
+
+	assertNotNil(t, err)
+	assertNotNil(t, err2)
+	assertNotNil(t, err3)
+}
+
+func Test_FileKeyring_returnsAnErrorIfTheFileCantBeWritten(t *testing.T) {
+	k := NewFileKeyring("non_existing_directory/keys.blah")
+
+	err := k.Put(fixtureAccounts()[0])
+
+	assertNotNil(t, err)
+}
+
+func Test_EncryptedFileKeyring_storesAccountsEncrypted(t *testing.T) {
+	fname := "test_resources/test_encrypted_file_keyring.blah"
+	defer os.Remove(fname)
+	k := NewEncryptedFileKeyring(fname, []byte("my passphrase"))
+
+	assertNil(t, k.Put(fixtureAccounts()[0]))
+
+	data, _ := ioutil.ReadFile(fname)
+	assertTrue(t, IsEncryptedKeyData(data))
+
+	_, _, err := NewEncryptedFileKeyring(fname, []byte("wrong")).Get("alice@example.org", "prpl-jabber")
+	assertEquals(t, err, ErrWrongPassphrase)
+
+	a, ok, err := NewEncryptedFileKeyring(fname, []byte("my passphrase")).Get("alice@example.org", "prpl-jabber")
+	assertNil(t, err)
+	assertTrue(t, ok)
+	assertDeepEquals(t, a.Key.Serialize(), alicePrivateKey.Serialize())
+}
+
+func Test_EncryptedFileKeyring_encryptsAPlainFileOnTheFirstChange(t *testing.T) {
+	fname := "test_resources/test_encrypted_file_keyring_from_plain.blah"
+	defer os.Remove(fname)
+	ExportKeysToFile(fixtureAccounts(), fname)
+	k := NewEncryptedFileKeyring(fname, []byte("my passphrase"))
+
+	assertNil(t, k.Delete("bob@example.org", "prpl-jabber"))
+
+	data, _ := ioutil.ReadFile(fname)
+	assertTrue(t, IsEncryptedKeyData(data))
+	acs, err := k.List()
+	assertNil(t, err)
+	assertEquals(t, len(acs), 1)
+}
+
+func Test_SetOurKeysFromKeyring_setsTheKeyOfTheAccount(t *testing.T) {
+	c := &Conversation{}
+
+	err := c.SetOurKeysFromKeyring(NewMemoryKeyring(fixtureAccounts()...), "bob@example.org", "prpl-jabber")
+
+	assertNil(t, err)
+	assertDeepEquals(t, c.GetOurKeys(), []PrivateKey{bobPrivateKey})
+}
+
+func Test_SetOurKeysFromKeyring_returnsAnErrorForAnUnknownAccount(t *testing.T) {
+	c := &Conversation{}
+
+	err := c.SetOurKeysFromKeyring(NewMemoryKeyring(), "bob@example.org", "prpl-jabber")
+
+	assertDeepEquals(t, err, newOtrError("no key for account bob@example.org (prpl-jabber)"))
+	assertNil(t, c.GetOurKeys())
+}
+
+func Test_SetOurKeysFromKeyring_returnsTheErrorFromTheKeyring(t *testing.T) {
+	c := &Conversation{}
+
+	err := c.SetOurKeysFromKeyring(NewFileKeyring("test_resources/invalid_key.asc"), "bob@example.org", "prpl-jabber")
+
+	assertNotNil(t, err)
+}
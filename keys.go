@@ -144,19 +144,23 @@ func ExportKeysToFile(acs []*Account, fname string) error {
 		return err
 	}
 	defer f.Close()
-	exportAccounts(acs, f)
-	return nil
+	return exportAccounts(acs, f)
 }
 
 // ImportKeys will read the libotr formatted data given and return all accounts defined in it
 func ImportKeys(r io.Reader) ([]*Account, error) {
 	res, ok := readAccounts(bufio.NewReader(r))
 	if !ok {
-		return nil, newOtrError("couldn't import data into private key")
+		return nil, ErrInvalidKey
 	}
 	return res, nil
 }
 
+// ExportKeys will write all the accounts in libotr format
+func ExportKeys(acs []*Account, w io.Writer) error {
+	return exportAccounts(acs, w)
+}
+
 func assignParameter(k *dsa.PrivateKey, s string, v *big.Int) bool {
 	switch s {
 	case "g":
@@ -561,12 +565,12 @@ func exportAccount(a *Account, w *bufio.Writer) {
 	w.WriteString(")\n")
 }
 
-func exportAccounts(as []*Account, w io.Writer) {
+func exportAccounts(as []*Account, w io.Writer) error {
 	bw := bufio.NewWriter(w)
 	bw.WriteString("(privkeys\n")
 	for _, a := range as {
 		exportAccount(a, bw)
 	}
 	bw.WriteString(")\n")
-	bw.Flush()
+	return bw.Flush()
 }
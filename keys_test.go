@@ -1056,6 +1056,20 @@ func Test_exportAccounts_exportsAccounts(t *testing.T) {
 `)
 }
 
+func Test_ExportKeys_exportsKeysThatCanBeImportedAgain(t *testing.T) {
+	priv := &DSAPrivateKey{}
+	priv.Parse(serializedPrivateKey)
+	acc := &Account{Name: "hello", Protocol: "go-xmpp", Key: priv}
+	bt := &bytes.Buffer{}
+
+	err := ExportKeys([]*Account{acc}, bt)
+	assertNil(t, err)
+
+	res, err2 := ImportKeys(bt)
+	assertNil(t, err2)
+	assertDeepEquals(t, res[0].Key, acc.Key)
+}
+
 func Test_ExportKeysToFile_exportsKeysToAFile(t *testing.T) {
 	priv := &DSAPrivateKey{}
 	priv.Parse(serializedPrivateKey)
@@ -0,0 +1,207 @@
+package otr3
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// LockedConversation wraps a Conversation so that it can be used from several goroutines at once - for example
+// receiving messages on one goroutine while sending on another. All operations are serialized using one lock,
+// which also covers the key rotation done while sending and receiving.
+//
+// Event handlers are called while the lock is held. They must not call methods on the LockedConversation, since
+// that would deadlock, but can safely use the Conversation returned by Unlocked, since they are already protected.
+type LockedConversation struct {
+	lock sync.Mutex
+	c    *Conversation
+}
+
+// NewLockedConversation creates a LockedConversation for the given conversation. After this, the conversation
+// should only be used through the LockedConversation.
+func NewLockedConversation(c *Conversation) *LockedConversation {
+	return &LockedConversation{c: c}
+}
+
+// Unlocked returns the wrapped conversation. It should only be used inside of event handlers or WithConversation,
+// where the lock is already held.
+func (l *LockedConversation) Unlocked() *Conversation {
+	return l.c
+}
+
+// WithConversation calls the function given with the wrapped conversation while holding the lock.
+// It can be used for operations that don't have a locked version, such as changing settings.
+func (l *LockedConversation) WithConversation(f func(c *Conversation)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	f(l.c)
+}
+
+// Send is the locked version of Conversation.Send
+func (l *LockedConversation) Send(m ValidMessage, trace ...interface{}) ([]ValidMessage, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.Send(m, trace...)
+}
+
+// Receive is the locked version of Conversation.Receive
+func (l *LockedConversation) Receive(m ValidMessage) (plain MessagePlaintext, toSend []ValidMessage, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.Receive(m)
+}
+
+// QueryMessage is the locked version of Conversation.QueryMessage
+func (l *LockedConversation) QueryMessage() ValidMessage {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.QueryMessage()
+}
+
+// End is the locked version of Conversation.End
+func (l *LockedConversation) End() ([]ValidMessage, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.End()
+}
+
+// StartAuthenticate is the locked version of Conversation.StartAuthenticate
+func (l *LockedConversation) StartAuthenticate(question string, mutualSecret []byte) ([]ValidMessage, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.StartAuthenticate(question, mutualSecret)
+}
+
+// ProvideAuthenticationSecret is the locked version of Conversation.ProvideAuthenticationSecret
+func (l *LockedConversation) ProvideAuthenticationSecret(mutualSecret []byte) ([]ValidMessage, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.ProvideAuthenticationSecret(mutualSecret)
+}
+
+// AbortAuthentication is the locked version of Conversation.AbortAuthentication
+func (l *LockedConversation) AbortAuthentication() ([]ValidMessage, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.AbortAuthentication()
+}
+
+// SMPQuestion is the locked version of Conversation.SMPQuestion
+func (l *LockedConversation) SMPQuestion() (string, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.SMPQuestion()
+}
+
+// UseExtraSymmetricKey is the locked version of Conversation.UseExtraSymmetricKey
+func (l *LockedConversation) UseExtraSymmetricKey(usage uint32, usageData []byte) ([]byte, []ValidMessage, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.UseExtraSymmetricKey(usage, usageData)
+}
+
+// IsEncrypted is the locked version of Conversation.IsEncrypted
+func (l *LockedConversation) IsEncrypted() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.IsEncrypted()
+}
+
+// GetTheirKey is the locked version of Conversation.GetTheirKey
+func (l *LockedConversation) GetTheirKey() PublicKey {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.GetTheirKey()
+}
+
+// GetSSID is the locked version of Conversation.GetSSID
+func (l *LockedConversation) GetSSID() [8]byte {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.GetSSID()
+}
+
+// SecureSessionID is the locked version of Conversation.SecureSessionID
+func (l *LockedConversation) SecureSessionID() (parts []string, highlightIndex int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.SecureSessionID()
+}
+
+// MarshalState is the locked version of Conversation.MarshalState
+func (l *LockedConversation) MarshalState(key []byte) ([]byte, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.MarshalState(key)
+}
+
+// UnmarshalState is the locked version of Conversation.UnmarshalState
+func (l *LockedConversation) UnmarshalState(key, state []byte) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.UnmarshalState(key, state)
+}
+
+// Tick is the locked version of Conversation.Tick
+func (l *LockedConversation) Tick(now time.Time) ([]ValidMessage, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.Tick(now)
+}
+
+// State is the locked version of Conversation.State
+func (l *LockedConversation) State() ConversationState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.State()
+}
+
+// DumpState is the locked version of Conversation.DumpState
+func (l *LockedConversation) DumpState(w io.Writer) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.DumpState(w)
+}
+
+// DumpStateJSON is the locked version of Conversation.DumpStateJSON
+func (l *LockedConversation) DumpStateJSON(w io.Writer) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.DumpStateJSON(w)
+}
+
+// Events is the locked version of Conversation.Events
+func (l *LockedConversation) Events() <-chan Event {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.Events()
+}
+
+// DroppedEvents is the locked version of Conversation.DroppedEvents
+func (l *LockedConversation) DroppedEvents() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.c.DroppedEvents()
+}
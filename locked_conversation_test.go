@@ -0,0 +1,151 @@
+package otr3
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests are mostly useful when run with the race detector: go test -race -run LockedConversation
+
+const concurrentMessageCount = 50
+
+func encryptedLockedPeers(t *testing.T) (alice, bob *LockedConversation) {
+	a, b := encryptedStatePeers(t)
+	return NewLockedConversation(a), NewLockedConversation(b)
+}
+
+// sendConcurrently sends messages from one side on its own goroutine, in order, to the channel given
+func sendConcurrently(t *testing.T, wg *sync.WaitGroup, from *LockedConversation, name string, out chan<- ValidMessage) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(out)
+		for i := 0; i < concurrentMessageCount; i++ {
+			toSend, err := from.Send(ValidMessage(fmt.Sprintf("%s %d", name, i)))
+			assertNil(t, err)
+			for _, m := range toSend {
+				out <- m
+			}
+		}
+	}()
+}
+
+// receiveConcurrently receives all messages from the channel on its own goroutine and checks that they arrive in order.
+// Messages generated while receiving, like heartbeats, are dropped.
+func receiveConcurrently(t *testing.T, wg *sync.WaitGroup, to *LockedConversation, name string, in <-chan ValidMessage) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		i := 0
+		for m := range in {
+			plain, _, err := to.Receive(m)
+			assertNil(t, err)
+			if plain != nil {
+				assertDeepEquals(t, plain, MessagePlaintext(fmt.Sprintf("%s %d", name, i)))
+				i++
+			}
+		}
+		assertEquals(t, i, concurrentMessageCount)
+	}()
+}
+
+func Test_LockedConversation_canSendAndReceiveConcurrently(t *testing.T) {
+	alice, bob := encryptedLockedPeers(t)
+	toBob := make(chan ValidMessage, 10)
+	toAlice := make(chan ValidMessage, 10)
+	wg := &sync.WaitGroup{}
+
+	sendConcurrently(t, wg, alice, "alice", toBob)
+	sendConcurrently(t, wg, bob, "bob", toAlice)
+	receiveConcurrently(t, wg, bob, "alice", toBob)
+	receiveConcurrently(t, wg, alice, "bob", toAlice)
+	wg.Wait()
+
+	assertTrue(t, alice.IsEncrypted())
+	assertTrue(t, bob.IsEncrypted())
+	sendBetween(t, alice.Unlocked(), bob.Unlocked(), "after all that")
+}
+
+func Test_LockedConversation_canQueryAndAuthenticateWhileSendingAndReceiving(t *testing.T) {
+	alice, bob := encryptedLockedPeers(t)
+	toBob := make(chan ValidMessage, 10)
+	wg := &sync.WaitGroup{}
+
+	sendConcurrently(t, wg, alice, "alice", toBob)
+	receiveConcurrently(t, wg, bob, "alice", toBob)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < concurrentMessageCount; i++ {
+			alice.IsEncrypted()
+			alice.GetSSID()
+			alice.GetTheirKey()
+			alice.SecureSessionID()
+			bob.SMPQuestion()
+			bob.Tick(time.Now())
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < concurrentMessageCount/10; i++ {
+			_, err := alice.StartAuthenticate("question", []byte("secret"))
+			assertNil(t, err)
+			_, err = alice.AbortAuthentication()
+			assertNil(t, err)
+			_, err = bob.MarshalState([]byte("state key"))
+			assertNil(t, err)
+		}
+	}()
+	wg.Wait()
+}
+
+func Test_LockedConversation_canEndWhileSending(t *testing.T) {
+	alice, bob := encryptedLockedPeers(t)
+	wg := &sync.WaitGroup{}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < concurrentMessageCount; i++ {
+			toSend, _ := bob.Send(ValidMessage("hello"))
+			for _, m := range toSend {
+				alice.Receive(m)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		toSend, err := alice.End()
+		assertNil(t, err)
+		for _, m := range toSend {
+			bob.Receive(m)
+		}
+	}()
+	wg.Wait()
+
+	assertFalse(t, alice.IsEncrypted())
+}
+
+func Test_LockedConversation_WithConversationHoldsTheLock(t *testing.T) {
+	l := NewLockedConversation(&Conversation{})
+	done := make(chan bool)
+
+	l.WithConversation(func(c *Conversation) {
+		assertEquals(t, c, l.Unlocked())
+		go func() {
+			l.IsEncrypted()
+			done <- true
+		}()
+
+		select {
+		case <-done:
+			t.Errorf("Expected IsEncrypted to wait for the lock")
+		default:
+		}
+	})
+
+	<-done
+}
@@ -0,0 +1,187 @@
+package otr3
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// LogEvent is the kind of thing that happened in a conversation, sent to a Logger
+type LogEvent int
+
+const (
+	// LogAuthStateChanged is logged when the state of the AKE changes
+	LogAuthStateChanged LogEvent = iota
+	// LogSMPStateChanged is logged when the state of SMP changes
+	LogSMPStateChanged
+	// LogMessageStateChanged is logged when the conversation becomes encrypted, finished or plaintext
+	LogMessageStateChanged
+	// LogKeysRotated is logged when new DH keys are used after receiving a data message
+	LogKeysRotated
+	// LogFragmentDropped is logged when a received fragment, or the fragments received before it, are thrown away
+	LogFragmentDropped
+	// LogMessageRejected is logged when a received message can't be handled
+	LogMessageRejected
+)
+
+// RedactedValue replaces the values of secret log fields, unless secrets are logged
+const RedactedValue = "[REDACTED]"
+
+// LogField is one named value of a LogRecord
+type LogField struct {
+	Key   string
+	Value interface{}
+	// Secret is true for values that shouldn't be shown to others, like keys and message contents
+	Secret bool
+}
+
+// LogRecord describes one thing that happened in a conversation
+type LogRecord struct {
+	Event                            LogEvent
+	Time                             time.Time
+	OurInstanceTag, TheirInstanceTag uint32
+
+	// From and To are the old and new state for state changes, named the same way as in ConversationState
+	From, To string
+	// Err is the reason a message was rejected
+	Err error
+
+	Fields []LogField
+}
+
+// Logger receives a LogRecord for every state change, key rotation, dropped fragment and rejected message
+// of a conversation. It is called synchronously, while the conversation is handling a message.
+type Logger interface {
+	Log(r LogRecord)
+}
+
+type dynamicLogger struct {
+	l func(r LogRecord)
+}
+
+func (d dynamicLogger) Log(r LogRecord) {
+	d.l(r)
+}
+
+// SetLogger assigns the logger for this conversation
+func (c *Conversation) SetLogger(l Logger) {
+	c.logger = l
+}
+
+// SetLogSecrets decides if the values of secret log fields are given to the logger. By default they are replaced
+// with RedactedValue. This should only be turned on while debugging, since logs tend to end up in many places.
+func (c *Conversation) SetLogSecrets(s bool) {
+	c.logSecrets = s
+}
+
+func (c *Conversation) logRecord(r LogRecord) {
+	if c.logger == nil {
+		return
+	}
+
+	r.Time = c.now()
+	r.OurInstanceTag, r.TheirInstanceTag = c.ourInstanceTag, c.theirInstanceTag
+	if !c.logSecrets {
+		for i := range r.Fields {
+			if r.Fields[i].Secret {
+				r.Fields[i].Value = RedactedValue
+			}
+		}
+	}
+
+	c.logger.Log(r)
+}
+
+func (c *Conversation) log(e LogEvent, fields ...LogField) {
+	c.logRecord(LogRecord{Event: e, Fields: fields})
+}
+
+func (c *Conversation) logStateChange(e LogEvent, from, to string) {
+	if from != to {
+		c.logRecord(LogRecord{Event: e, From: from, To: to})
+	}
+}
+
+func (c *Conversation) authStateName() string {
+	if c.ake == nil || c.ake.state == nil {
+		return authStateNone{}.identityString()
+	}
+	return c.ake.state.identityString()
+}
+
+func (c *Conversation) smpStateName() string {
+	if c.smp.state == nil {
+		return smpStateExpect1{}.identityString()
+	}
+	return c.smp.state.identityString()
+}
+
+// logAuthStateChange logs a change from the given auth state to the current one. It is meant to be deferred,
+// with the auth state from before the change.
+func (c *Conversation) logAuthStateChange(from string) {
+	c.logStateChange(LogAuthStateChanged, from, c.authStateName())
+}
+
+// logSMPStateChange logs a change from the given SMP state to the current one. It is meant to be deferred,
+// with the SMP state from before the change.
+func (c *Conversation) logSMPStateChange(from string) {
+	c.logStateChange(LogSMPStateChanged, from, c.smpStateName())
+}
+
+func (c *Conversation) setMsgState(s msgState) {
+	from := c.msgState
+	c.msgState = s
+	c.logStateChange(LogMessageStateChanged, from.identityString(), s.identityString())
+}
+
+func (c *Conversation) logRejectedMessage(message []byte, err error) {
+	c.logRecord(LogRecord{Event: LogMessageRejected, Err: err, Fields: []LogField{
+		{Key: "message", Value: string(message), Secret: true},
+	}})
+}
+
+// String returns the name of the log event
+func (e LogEvent) String() string {
+	switch e {
+	case LogAuthStateChanged:
+		return "AuthStateChanged"
+	case LogSMPStateChanged:
+		return "SMPStateChanged"
+	case LogMessageStateChanged:
+		return "MessageStateChanged"
+	case LogKeysRotated:
+		return "KeysRotated"
+	case LogFragmentDropped:
+		return "FragmentDropped"
+	case LogMessageRejected:
+		return "MessageRejected"
+	default:
+		return "LOG EVENT: (THIS SHOULD NEVER HAPPEN)"
+	}
+}
+
+// String returns the record on one line, for example:
+//
+//	2016-01-02T15:04:05Z [00000100/00000101] MessageStateChanged PLAINTEXT -> ENCRYPTED
+func (r LogRecord) String() string {
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "%s [%08X/%08X] %s", r.Time.Format(time.RFC3339), r.OurInstanceTag, r.TheirInstanceTag, r.Event)
+	if r.From != "" || r.To != "" {
+		fmt.Fprintf(w, " %s -> %s", r.From, r.To)
+	}
+	if r.Err != nil {
+		fmt.Fprintf(w, " error=%q", r.Err.Error())
+	}
+	for _, f := range r.Fields {
+		fmt.Fprintf(w, " %s=%v", f.Key, f.Value)
+	}
+	return w.String()
+}
+
+// DebugLogger is a Logger that writes all records to standard error
+type DebugLogger struct{}
+
+// Log writes the record to standard error
+func (DebugLogger) Log(r LogRecord) {
+	fmt.Fprintf(standardErrorOutput, "%s%s\n", debugPrefix, r)
+}
@@ -0,0 +1,204 @@
+package otr3
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func recordLogs(c *Conversation) *[]LogRecord {
+	records := &[]LogRecord{}
+	c.SetLogger(dynamicLogger{func(r LogRecord) {
+		*records = append(*records, r)
+	}})
+	return records
+}
+
+func recordsFor(records []LogRecord, e LogEvent) []LogRecord {
+	var result []LogRecord
+	for _, r := range records {
+		if r.Event == e {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func transitions(records []LogRecord, e LogEvent) []string {
+	var result []string
+	for _, r := range recordsFor(records, e) {
+		result = append(result, r.From+" -> "+r.To)
+	}
+	return result
+}
+
+func Test_Logger_receivesStateChangesDuringTheAKE(t *testing.T) {
+	alice := newStatePeer(alicePrivateKey)
+	bob := newStatePeer(bobPrivateKey)
+	aliceLogs := recordLogs(alice)
+	bobLogs := recordLogs(bob)
+
+	exchangeBetween(t, alice, bob, []ValidMessage{alice.QueryMessage()})
+
+	assertDeepEquals(t, transitions(*aliceLogs, LogMessageStateChanged), []string{"PLAINTEXT -> ENCRYPTED"})
+	assertDeepEquals(t, transitions(*bobLogs, LogMessageStateChanged), []string{"PLAINTEXT -> ENCRYPTED"})
+	assertDeepEquals(t, transitions(*bobLogs, LogAuthStateChanged), []string{"NONE -> AWAITING_DHKEY", "AWAITING_DHKEY -> AWAITING_SIG", "AWAITING_SIG -> NONE"})
+	assertDeepEquals(t, transitions(*aliceLogs, LogAuthStateChanged), []string{"NONE -> AWAITING_REVEALSIG", "AWAITING_REVEALSIG -> NONE"})
+}
+
+func Test_Logger_receivesTheTimeAndInstanceTags(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	clock := newFakeClock()
+	alice.Clock = clock
+	logs := recordLogs(alice)
+
+	alice.End()
+
+	assertEquals(t, len(*logs), 1)
+	assertEquals(t, (*logs)[0].Time, clock.Now())
+	assertEquals(t, (*logs)[0].OurInstanceTag, alice.ourInstanceTag)
+	assertEquals(t, (*logs)[0].TheirInstanceTag, bob.ourInstanceTag)
+	assertDeepEquals(t, transitions(*logs, LogMessageStateChanged), []string{"ENCRYPTED -> PLAINTEXT"})
+}
+
+func Test_Logger_receivesMessageStateChangesWhenThePeerEnds(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	logs := recordLogs(bob)
+
+	toSend, _ := alice.End()
+	bob.Receive(toSend[0])
+
+	assertDeepEquals(t, transitions(*logs, LogMessageStateChanged), []string{"ENCRYPTED -> FINISHED"})
+}
+
+func Test_Logger_receivesKeyRotationsWithRedactedMACKeys(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	logs := recordLogs(alice)
+
+	sendBetween(t, alice, bob, "hello")
+	sendBetween(t, bob, alice, "hi")
+	sendBetween(t, alice, bob, "how are you?")
+	sendBetween(t, bob, alice, "fine")
+
+	rotations := recordsFor(*logs, LogKeysRotated)
+	assertTrue(t, len(rotations) > 0)
+	last := rotations[len(rotations)-1]
+	assertEquals(t, last.Fields[0], LogField{Key: "ourKeyID", Value: alice.keys.ourKeyID})
+	assertEquals(t, last.Fields[1], LogField{Key: "theirKeyID", Value: alice.keys.theirKeyID})
+	assertEquals(t, last.Fields[2].Key, "revealedMACKeys")
+	assertEquals(t, last.Fields[2].Value, RedactedValue)
+	assertTrue(t, last.Fields[2].Secret)
+}
+
+func Test_Logger_receivesSecretsIfAskedFor(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	logs := recordLogs(alice)
+	alice.SetLogSecrets(true)
+
+	sendBetween(t, alice, bob, "hello")
+	sendBetween(t, bob, alice, "hi")
+	sendBetween(t, alice, bob, "how are you?")
+	sendBetween(t, bob, alice, "fine")
+
+	found := false
+	for _, r := range recordsFor(*logs, LogKeysRotated) {
+		if keys, ok := r.Fields[2].Value.([]string); ok && len(keys) > 0 {
+			found = true
+			assertEquals(t, len(keys[0]), 40)
+		}
+	}
+	assertTrue(t, found)
+}
+
+func Test_Logger_receivesSMPStateChanges(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	aliceLogs := recordLogs(alice)
+	bobLogs := recordLogs(bob)
+
+	toSend, err := alice.StartAuthenticate("", []byte("secret"))
+	assertNil(t, err)
+	bob.Receive(toSend[0])
+
+	assertDeepEquals(t, transitions(*aliceLogs, LogSMPStateChanged), []string{"EXPECT1 -> EXPECT2"})
+	assertDeepEquals(t, transitions(*bobLogs, LogSMPStateChanged), []string{"EXPECT1 -> EXPECT1_WQ"})
+
+	alice.AbortAuthentication()
+	assertDeepEquals(t, transitions(*aliceLogs, LogSMPStateChanged), []string{"EXPECT1 -> EXPECT2", "EXPECT2 -> EXPECT1"})
+}
+
+func Test_Logger_receivesDroppedFragments(t *testing.T) {
+	c := newStatePeer(alicePrivateKey)
+	logs := recordLogs(c)
+
+	c.Receive(ValidMessage("?OTR,00001,00003,one,"))
+	c.Receive(ValidMessage("?OTR,00003,00003,three,"))
+
+	dropped := recordsFor(*logs, LogFragmentDropped)
+	assertEquals(t, len(dropped), 1)
+	assertDeepEquals(t, dropped[0].Fields, []LogField{
+		{Key: "index", Value: uint16(3)},
+		{Key: "count", Value: uint16(3)},
+		{Key: "reason", Value: "out of order"},
+	})
+}
+
+func Test_Logger_receivesRejectedMessagesWithTheMessageRedacted(t *testing.T) {
+	c := &Conversation{}
+	c.Policies.AllowV3()
+	logs := recordLogs(c)
+
+	_, _, err := c.Receive(ValidMessage("?OTR:AAMD!!!."))
+
+	rejected := recordsFor(*logs, LogMessageRejected)
+	assertEquals(t, len(rejected), 1)
+	assertEquals(t, rejected[0].Err, err)
+	assertDeepEquals(t, rejected[0].Fields, []LogField{{Key: "message", Value: RedactedValue, Secret: true}})
+}
+
+func Test_Logger_isNotCalledWithoutChanges(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	logs := recordLogs(bob)
+
+	sendBetween(t, alice, bob, "hello")
+
+	assertNil(t, recordsFor(*logs, LogMessageStateChanged))
+	assertNil(t, recordsFor(*logs, LogAuthStateChanged))
+	assertNil(t, recordsFor(*logs, LogSMPStateChanged))
+}
+
+func Test_LogRecord_String_returnsTheRecordOnOneLine(t *testing.T) {
+	r := LogRecord{
+		Event:            LogMessageRejected,
+		Time:             time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
+		OurInstanceTag:   0x100,
+		TheirInstanceTag: 0x101,
+		Err:              errors.New("bad message"),
+		Fields:           []LogField{{Key: "message", Value: RedactedValue, Secret: true}},
+	}
+	assertEquals(t, r.String(), `2016-01-02T15:04:05Z [00000100/00000101] MessageRejected error="bad message" message=[REDACTED]`)
+
+	r = LogRecord{Event: LogAuthStateChanged, From: "NONE", To: "AWAITING_DHKEY"}
+	assertEquals(t, r.String(), "0001-01-01T00:00:00Z [00000000/00000000] AuthStateChanged NONE -> AWAITING_DHKEY")
+}
+
+func Test_LogEvent_String_returnsTheNameOfTheEvent(t *testing.T) {
+	assertEquals(t, LogAuthStateChanged.String(), "AuthStateChanged")
+	assertEquals(t, LogSMPStateChanged.String(), "SMPStateChanged")
+	assertEquals(t, LogMessageStateChanged.String(), "MessageStateChanged")
+	assertEquals(t, LogKeysRotated.String(), "KeysRotated")
+	assertEquals(t, LogFragmentDropped.String(), "FragmentDropped")
+	assertEquals(t, LogMessageRejected.String(), "MessageRejected")
+	assertEquals(t, LogEvent(100).String(), "LOG EVENT: (THIS SHOULD NEVER HAPPEN)")
+}
+
+func Test_DebugLogger_writesToStandardError(t *testing.T) {
+	bt := &bytes.Buffer{}
+	orgStdErr := standardErrorOutput
+	defer func() { standardErrorOutput = orgStdErr }()
+	standardErrorOutput = bt
+
+	DebugLogger{}.Log(LogRecord{Event: LogKeysRotated})
+
+	assertEquals(t, bt.String(), "[DEBUG] 0001-01-01T00:00:00Z [00000000/00000000] KeysRotated\n")
+}
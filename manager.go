@@ -0,0 +1,316 @@
+package otr3
+
+import (
+	"io"
+	"sort"
+	"sync"
+)
+
+// Manager keeps track of all conversations for a set of accounts. Conversations are created on demand the
+// first time they are asked for, using the key of the account and the policies, fragment size and event
+// handlers configured on the manager. Settings should be configured before any conversations are created,
+// since changing them later will not affect existing conversations.
+// It is safe to use a Manager from several goroutines, but the conversations themselves are not protected.
+type Manager struct {
+	Rand         io.Reader
+	Clock        Clock
+	Policies     policies
+	FragmentSize uint16
+
+	smpEventHandler      SMPEventHandler
+	errorMessageHandler  ErrorMessageHandler
+	messageEventHandler  MessageEventHandler
+	securityEventHandler SecurityEventHandler
+	receivedKeyHandler   ReceivedKeyHandler
+	metrics              Metrics
+	events               *eventStream
+	fingerprints         *FingerprintStore
+	instanceTags         *InstanceTagStore
+	setup                func(*Account, string, *Conversation)
+
+	lock          sync.Mutex
+	accounts      []*Account
+	conversations map[conversationKey]*Conversation
+}
+
+type conversationKey struct {
+	account, protocol, peer string
+}
+
+// NewManager creates a manager for the given accounts
+func NewManager(accounts ...*Account) *Manager {
+	return &Manager{
+		accounts:      accounts,
+		conversations: make(map[conversationKey]*Conversation),
+	}
+}
+
+// AddAccount adds an account to the manager. If an account with the same name and protocol already exists, it will be replaced,
+// but existing conversations for it will continue to use the old key.
+func (m *Manager) AddAccount(a *Account) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, ac := range m.accounts {
+		if ac.Name == a.Name && ac.Protocol == a.Protocol {
+			m.accounts[i] = a
+			return
+		}
+	}
+	m.accounts = append(m.accounts, a)
+}
+
+// Accounts returns all accounts of this manager
+func (m *Manager) Accounts() []*Account {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]*Account{}, m.accounts...)
+}
+
+// Account returns the account with the given name and protocol, and not ok if there is no such account
+func (m *Manager) Account(name, protocol string) (*Account, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.findAccount(name, protocol)
+}
+
+func (m *Manager) findAccount(name, protocol string) (*Account, bool) {
+	for _, a := range m.accounts {
+		if a.Name == name && a.Protocol == protocol {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// SetSMPEventHandler assigns the SMPEventHandler for all conversations created after this call
+func (m *Manager) SetSMPEventHandler(handler SMPEventHandler) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.smpEventHandler = handler
+}
+
+// SetErrorMessageHandler assigns the ErrorMessageHandler for all conversations created after this call
+func (m *Manager) SetErrorMessageHandler(handler ErrorMessageHandler) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.errorMessageHandler = handler
+}
+
+// SetMessageEventHandler assigns the MessageEventHandler for all conversations created after this call
+func (m *Manager) SetMessageEventHandler(handler MessageEventHandler) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.messageEventHandler = handler
+}
+
+// SetSecurityEventHandler assigns the SecurityEventHandler for all conversations created after this call
+func (m *Manager) SetSecurityEventHandler(handler SecurityEventHandler) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.securityEventHandler = handler
+}
+
+// SetReceivedKeyHandler assigns the ReceivedKeyHandler for all conversations created after this call
+func (m *Manager) SetReceivedKeyHandler(handler ReceivedKeyHandler) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.receivedKeyHandler = handler
+}
+
+// SetMetrics makes all conversations created after this call report into the given Metrics
+func (m *Manager) SetMetrics(metrics Metrics) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.metrics = metrics
+}
+
+// Events returns a channel that receives the events of all conversations created after the first call, in the same
+// way as Conversation.Events does for one conversation. The EventInfo of each event tells which conversation it is for.
+func (m *Manager) Events() <-chan Event {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.events == nil {
+		m.events = newEventStream()
+	}
+	return m.events.ch
+}
+
+// SetFingerprintStore makes all conversations created after this call use the given store to decide the trust of their peer
+func (m *Manager) SetFingerprintStore(s *FingerprintStore) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.fingerprints = s
+}
+
+// SetInstanceTagStore makes all conversations created after this call use the instance tag of their account from the given store.
+// Accounts without an instance tag will get a new one added to the store.
+func (m *Manager) SetInstanceTagStore(s *InstanceTagStore) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.instanceTags = s
+}
+
+// SetConversationSetup assigns a function that will be called every time a new conversation is created. It can be used
+// to do setup that depends on the account or peer - for example to install event handlers that know which peer they are for.
+// The function is called without the manager lock held, so it can call back into the manager. The new conversation can
+// already be found by Lookup and Each while the function runs.
+func (m *Manager) SetConversationSetup(f func(account *Account, peer string, c *Conversation)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.setup = f
+}
+
+// Conversation returns the conversation between the given account and peer, creating it if necessary.
+// It returns an error if the account is not known by the manager.
+func (m *Manager) Conversation(account, protocol, peer string) (*Conversation, error) {
+	c, a, setup, err := m.findOrCreateConversation(account, protocol, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	if a != nil && setup != nil {
+		setup(a, peer, c)
+	}
+
+	return c, nil
+}
+
+// findOrCreateConversation returns the conversation between the given account and peer. The account and the setup
+// function are only returned if the conversation was created by this call, so that the caller can run the setup
+// without holding the lock.
+func (m *Manager) findOrCreateConversation(account, protocol, peer string) (*Conversation, *Account, func(*Account, string, *Conversation), error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := conversationKey{account, protocol, peer}
+	if c, ok := m.conversations[key]; ok {
+		return c, nil, nil, nil
+	}
+
+	a, ok := m.findAccount(account, protocol)
+	if !ok {
+		return nil, nil, nil, newOtrErrorf("unknown account %s (%s)", account, protocol)
+	}
+
+	c := m.newConversation(a)
+	if m.instanceTags != nil {
+		tag, err := m.instanceTags.InstanceTagFor(account, protocol, c.rand())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		c.InitializeInstanceTag(tag)
+	}
+	if m.fingerprints != nil {
+		c.SetTrustResolver(m.fingerprints.TrustResolverFor(account, protocol, peer))
+	}
+	m.conversations[key] = c
+
+	return c, a, m.setup, nil
+}
+
+// Lookup returns the conversation between the given account and peer, and not ok if it hasn't been created yet
+func (m *Manager) Lookup(account, protocol, peer string) (*Conversation, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c, ok := m.conversations[conversationKey{account, protocol, peer}]
+	return c, ok
+}
+
+// Remove forgets the conversation between the given account and peer, without ending it
+func (m *Manager) Remove(account, protocol, peer string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.conversations, conversationKey{account, protocol, peer})
+}
+
+// Each calls the function given for every conversation in the manager, ordered by account, protocol and peer.
+// The function should not call back into the manager.
+func (m *Manager) Each(f func(account, protocol, peer string, c *Conversation)) {
+	for _, e := range m.sortedConversations(func(conversationKey) bool { return true }) {
+		f(e.key.account, e.key.protocol, e.key.peer, e.c)
+	}
+}
+
+// End ends all conversations for the given account, for example when logging out. It returns the messages to send, keyed by peer.
+// All conversations will be ended even if some of them fail, and the first error will be returned.
+func (m *Manager) End(account, protocol string) (map[string][]ValidMessage, error) {
+	result := make(map[string][]ValidMessage)
+	var errs []error
+
+	for _, e := range m.sortedConversations(func(k conversationKey) bool { return k.account == account && k.protocol == protocol }) {
+		toSend, err := e.c.End()
+		if len(toSend) > 0 {
+			result[e.key.peer] = toSend
+		}
+		errs = append(errs, err)
+	}
+
+	return result, firstError(errs...)
+}
+
+type managedConversation struct {
+	key conversationKey
+	c   *Conversation
+}
+
+type managedConversations []managedConversation
+
+func (m managedConversations) Len() int      { return len(m) }
+func (m managedConversations) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m managedConversations) Less(i, j int) bool {
+	l, r := m[i].key, m[j].key
+	if l.account != r.account {
+		return l.account < r.account
+	}
+	if l.protocol != r.protocol {
+		return l.protocol < r.protocol
+	}
+	return l.peer < r.peer
+}
+
+func (m *Manager) sortedConversations(include func(conversationKey) bool) managedConversations {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var result managedConversations
+	for k, c := range m.conversations {
+		if include(k) {
+			result = append(result, managedConversation{k, c})
+		}
+	}
+	sort.Sort(result)
+	return result
+}
+
+func (m *Manager) newConversation(a *Account) *Conversation {
+	c := &Conversation{
+		Rand:     m.Rand,
+		Clock:    m.Clock,
+		Policies: m.Policies,
+	}
+	c.SetOurKeys([]PrivateKey{a.Key})
+	c.SetFragmentSize(m.FragmentSize)
+	c.SetSMPEventHandler(m.smpEventHandler)
+	c.SetErrorMessageHandler(m.errorMessageHandler)
+	c.SetMessageEventHandler(m.messageEventHandler)
+	c.SetSecurityEventHandler(m.securityEventHandler)
+	c.receivedKeyHandler = m.receivedKeyHandler
+	c.SetMetrics(m.metrics)
+	c.events = m.events
+	return c
+}
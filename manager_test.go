@@ -0,0 +1,242 @@
+package otr3
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func newTestManager() *Manager {
+	m := NewManager(
+		&Account{Name: "alice@example.org", Protocol: "xmpp", Key: alicePrivateKey},
+		&Account{Name: "alice", Protocol: "irc", Key: bobPrivateKey},
+	)
+	m.Rand = rand.Reader
+	m.Policies.AllowV3()
+	m.FragmentSize = 200
+	return m
+}
+
+// exchangeBetween passes messages back and forth between two conversations until neither has anything more to say
+func exchangeBetween(t *testing.T, a, b *Conversation, toB []ValidMessage) {
+	for len(toB) > 0 {
+		var toA []ValidMessage
+		for _, m := range toB {
+			_, ts, err := b.Receive(m)
+			assertNil(t, err)
+			toA = append(toA, ts...)
+		}
+		a, b, toB = b, a, toA
+	}
+}
+
+func Test_Manager_ConversationCreatesAConversationWithTheSettingsOfTheManager(t *testing.T) {
+	m := newTestManager()
+	clock := newFakeClock()
+	m.Clock = clock
+	var events []SecurityEvent
+	m.SetSecurityEventHandler(dynamicSecurityEventHandler{func(e SecurityEvent) {
+		events = append(events, e)
+	}})
+
+	c, err := m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+
+	assertNil(t, err)
+	assertDeepEquals(t, c.ourKeys, []PrivateKey{alicePrivateKey})
+	assertEquals(t, c.Policies, policies(allowV3))
+	assertEquals(t, c.fragmentSize, uint16(200))
+	assertEquals(t, c.Rand, rand.Reader)
+	assertEquals(t, c.Clock, Clock(clock))
+
+	c.securityEvent(GoneSecure)
+	assertDeepEquals(t, events, []SecurityEvent{GoneSecure})
+}
+
+func Test_Manager_ConversationUsesTheKeyOfTheRightAccount(t *testing.T) {
+	m := newTestManager()
+
+	c, _ := m.Conversation("alice", "irc", "bob")
+
+	assertDeepEquals(t, c.ourKeys, []PrivateKey{bobPrivateKey})
+}
+
+func Test_Manager_ConversationReturnsTheSameConversationForTheSamePeer(t *testing.T) {
+	m := newTestManager()
+
+	c1, _ := m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+	c2, _ := m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+	c3, _ := m.Conversation("alice@example.org", "xmpp", "carol@example.org")
+
+	assertEquals(t, c1, c2)
+	assertFalse(t, c1 == c3)
+}
+
+func Test_Manager_ConversationReturnsAnErrorForAnUnknownAccount(t *testing.T) {
+	m := newTestManager()
+
+	c, err := m.Conversation("alice@example.org", "irc", "bob")
+
+	assertNil(t, c)
+	assertEquals(t, err, newOtrError("unknown account alice@example.org (irc)"))
+}
+
+func Test_Manager_LookupDoesNotCreateConversations(t *testing.T) {
+	m := newTestManager()
+
+	_, ok := m.Lookup("alice@example.org", "xmpp", "bob@example.org")
+	assertFalse(t, ok)
+
+	c1, _ := m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+	c2, ok := m.Lookup("alice@example.org", "xmpp", "bob@example.org")
+	assertTrue(t, ok)
+	assertEquals(t, c1, c2)
+
+	m.Remove("alice@example.org", "xmpp", "bob@example.org")
+	_, ok = m.Lookup("alice@example.org", "xmpp", "bob@example.org")
+	assertFalse(t, ok)
+}
+
+func Test_Manager_AddAccountReplacesAnExistingAccount(t *testing.T) {
+	m := newTestManager()
+
+	m.AddAccount(&Account{Name: "alice", Protocol: "irc", Key: alicePrivateKey})
+	m.AddAccount(&Account{Name: "carol", Protocol: "irc", Key: bobPrivateKey})
+
+	assertEquals(t, len(m.Accounts()), 3)
+	a, ok := m.Account("alice", "irc")
+	assertTrue(t, ok)
+	assertEquals(t, a.Key, alicePrivateKey)
+}
+
+func Test_Manager_EachIteratesOverAllConversationsInOrder(t *testing.T) {
+	m := newTestManager()
+	m.Conversation("alice@example.org", "xmpp", "carol@example.org")
+	m.Conversation("alice", "irc", "bob")
+	m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+
+	var peers []string
+	m.Each(func(account, protocol, peer string, c *Conversation) {
+		peers = append(peers, account+"/"+protocol+"/"+peer)
+	})
+
+	assertDeepEquals(t, peers, []string{"alice/irc/bob", "alice@example.org/xmpp/bob@example.org", "alice@example.org/xmpp/carol@example.org"})
+}
+
+func Test_Manager_SetConversationSetupIsCalledForNewConversations(t *testing.T) {
+	m := newTestManager()
+	var setup []string
+	m.SetConversationSetup(func(a *Account, peer string, c *Conversation) {
+		setup = append(setup, a.Name+"/"+peer)
+	})
+
+	m.Conversation("alice", "irc", "bob")
+	m.Conversation("alice", "irc", "bob")
+
+	assertDeepEquals(t, setup, []string{"alice/bob"})
+}
+
+func Test_Manager_SetConversationSetupCanCallBackIntoTheManager(t *testing.T) {
+	m := newTestManager()
+	var found *Conversation
+	m.SetConversationSetup(func(a *Account, peer string, c *Conversation) {
+		found, _ = m.Lookup(a.Name, a.Protocol, peer)
+	})
+
+	c, err := m.Conversation("alice", "irc", "bob")
+
+	assertNil(t, err)
+	assertEquals(t, found, c)
+}
+
+func Test_Manager_SetFingerprintStoreGivesEachConversationATrustResolverForItsPeer(t *testing.T) {
+	m := newTestManager()
+	s := &FingerprintStore{}
+	s.Add("alice", "irc", "bob", alicePrivateKey.PublicKey().Fingerprint())
+	s.SetTrust("alice", "irc", "bob", alicePrivateKey.PublicKey().Fingerprint(), TrustVerified)
+	m.SetFingerprintStore(s)
+
+	c1, _ := m.Conversation("alice", "irc", "bob")
+	c2, _ := m.Conversation("alice", "irc", "carol")
+
+	assertEquals(t, c1.trustResolver.ResolveTrust(alicePrivateKey.PublicKey()), TrustLevelVerified)
+	assertEquals(t, c2.trustResolver.ResolveTrust(alicePrivateKey.PublicKey()), TrustLevelUnverified)
+}
+
+func Test_Manager_EndEndsAllConversationsOfTheAccount(t *testing.T) {
+	m := newTestManager()
+	bob := newSessionPeer(bobPrivateKey, 0x2000)
+	c, _ := m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+	other, _ := m.Conversation("alice", "irc", "bob")
+	other.msgState = encrypted
+	m.Conversation("alice@example.org", "xmpp", "carol@example.org")
+
+	exchangeBetween(t, c, bob, []ValidMessage{c.QueryMessage()})
+	assertTrue(t, c.IsEncrypted())
+
+	toSend, err := m.End("alice@example.org", "xmpp")
+
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+	assertTrue(t, len(toSend["bob@example.org"]) > 0)
+	assertFalse(t, c.IsEncrypted())
+	assertTrue(t, other.IsEncrypted())
+}
+
+func Test_Manager_SetInstanceTagStoreGivesConversationsTheInstanceTagOfTheirAccount(t *testing.T) {
+	m := newTestManager()
+	s := &InstanceTagStore{}
+	s.Set("alice", "irc", 0x1234)
+	m.SetInstanceTagStore(s)
+
+	c1, _ := m.Conversation("alice", "irc", "bob")
+	c2, _ := m.Conversation("alice@example.org", "xmpp", "bob")
+	c3, _ := m.Conversation("alice@example.org", "xmpp", "carol")
+
+	assertEquals(t, c1.ourInstanceTag, uint32(0x1234))
+	tag, ok := s.Lookup("alice@example.org", "xmpp")
+	assertTrue(t, ok)
+	assertEquals(t, c2.ourInstanceTag, tag)
+	assertEquals(t, c3.ourInstanceTag, tag)
+}
+
+func Test_Manager_ConversationReturnsAnErrorIfTheInstanceTagCantBeGenerated(t *testing.T) {
+	m := newTestManager()
+	m.Rand = fixedRand([]string{"00"})
+	m.SetInstanceTagStore(&InstanceTagStore{})
+
+	_, err := m.Conversation("alice", "irc", "bob")
+
+	assertEquals(t, err, errShortRandomRead)
+	_, ok := m.Lookup("alice", "irc", "bob")
+	assertFalse(t, ok)
+}
+
+// This test is mostly useful when run with the race detector: go test -race -run Manager_setters
+func Test_Manager_settersCanBeCalledWhileConversationsAreCreated(t *testing.T) {
+	m := newTestManager()
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < concurrentMessageCount; i++ {
+			_, err := m.Conversation("alice@example.org", "xmpp", fmt.Sprintf("bob%d@example.org", i))
+			assertNil(t, err)
+		}
+	}()
+
+	for i := 0; i < concurrentMessageCount; i++ {
+		m.SetSMPEventHandler(dynamicSMPEventHandler{func(SMPEvent, int, string) {}})
+		m.SetErrorMessageHandler(dynamicErrorMessageHandler{func(ErrorCode) []byte { return nil }})
+		m.SetMessageEventHandler(dynamicMessageEventHandler{func(MessageEvent, []byte, error, ...interface{}) {}})
+		m.SetSecurityEventHandler(dynamicSecurityEventHandler{func(SecurityEvent) {}})
+		m.SetReceivedKeyHandler(nil)
+		m.SetFingerprintStore(&FingerprintStore{})
+		m.SetInstanceTagStore(nil)
+		m.SetConversationSetup(func(*Account, string, *Conversation) {})
+		m.SetMetrics(&MemoryMetrics{})
+	}
+
+	wg.Wait()
+}
@@ -73,18 +73,21 @@ func (d dynamicMessageEventHandler) HandleMessageEvent(event MessageEvent, messa
 }
 
 func (c *Conversation) messageEvent(e MessageEvent, trace ...interface{}) {
+	c.sendMessageEvent(e, nil, nil, trace)
 	if c.messageEventHandler != nil {
 		c.messageEventHandler.HandleMessageEvent(e, nil, nil, trace...)
 	}
 }
 
 func (c *Conversation) messageEventWithError(e MessageEvent, err error) {
+	c.sendMessageEvent(e, nil, err, nil)
 	if c.messageEventHandler != nil {
 		c.messageEventHandler.HandleMessageEvent(e, nil, err)
 	}
 }
 
 func (c *Conversation) messageEventWithMessage(e MessageEvent, msg []byte) {
+	c.sendMessageEvent(e, msg, nil, nil)
 	if c.messageEventHandler != nil {
 		c.messageEventHandler.HandleMessageEvent(e, msg, nil)
 	}
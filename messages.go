@@ -40,12 +40,15 @@ func (c dhCommit) serialize() []byte {
 }
 
 func (c *dhCommit) deserialize(msg []byte) error {
-	var ok1 bool
-	msg, c.encryptedGx, ok1 = extractData(msg)
-	_, h, ok2 := extractData(msg)
-	if !ok1 || !ok2 {
-		return newOtrError("corrupt DH commit message")
+	in, encryptedGx, ok := extractData(msg)
+	if !ok {
+		return newMalformedMessageError("DH commit message", "encrypted gx", msg, msg)
+	}
+	_, h, ok := extractData(in)
+	if !ok {
+		return newMalformedMessageError("DH commit message", "hashed gx", msg, in)
 	}
+	c.encryptedGx = encryptedGx
 	c.yhashedGx = h
 	return nil
 }
@@ -62,7 +65,7 @@ func (c *dhKey) deserialize(msg []byte) error {
 	_, gy, ok := extractMPI(msg)
 
 	if !ok {
-		return newOtrError("corrupt DH key message")
+		return newMalformedMessageError("DH key message", "gy", msg, msg)
 	}
 
 	c.gy = gy
@@ -84,10 +87,16 @@ func (c revealSig) serialize(v otrVersion) []byte {
 }
 
 func (c *revealSig) deserialize(msg []byte, v otrVersion) error {
-	in, r, ok1 := extractData(msg)
-	macSig, encryptedSig, ok2 := extractData(in)
-	if !ok1 || !ok2 || len(macSig) != v.truncateLength() {
-		return newOtrError("corrupt reveal signature message")
+	in, r, ok := extractData(msg)
+	if !ok {
+		return newMalformedMessageError("reveal signature message", "r", msg, msg)
+	}
+	macSig, encryptedSig, ok := extractData(in)
+	if !ok {
+		return newMalformedMessageError("reveal signature message", "encrypted signature", msg, in)
+	}
+	if len(macSig) != v.truncateLength() {
+		return newMalformedMessageError("reveal signature message", "MAC", msg, macSig)
 	}
 
 	copy(c.r[:], r)
@@ -109,9 +118,11 @@ func (c sig) serialize(v otrVersion) []byte {
 
 func (c *sig) deserialize(msg []byte) error {
 	macSig, encryptedSig, ok := extractData(msg)
-
-	if !ok || len(macSig) != 20 {
-		return newOtrError("corrupt signature message")
+	if !ok {
+		return newMalformedMessageError("signature message", "encrypted signature", msg, msg)
+	}
+	if len(macSig) != 20 {
+		return newMalformedMessageError("signature message", "MAC", msg, macSig)
 	}
 	c.encryptedSig = encryptedSig
 	c.macSig = macSig
@@ -147,7 +158,7 @@ func (c dataMsg) checkSign(key []byte, header []byte, v otrVersion) error {
 	authenticatorCalculated := mac.Sum(nil)
 
 	if subtle.ConstantTimeCompare(c.authenticator, authenticatorCalculated) == 0 {
-		return newOtrConflictError("bad signature MAC in encrypted signature")
+		return &MACVerificationError{Message: "data message"}
 	}
 	return nil
 }
@@ -166,44 +177,44 @@ func (c dataMsg) serializeUnsigned() []byte {
 
 func (c *dataMsg) deserializeUnsigned(msg []byte) error {
 	if len(msg) == 0 {
-		return newOtrError("dataMsg.deserialize empty message")
+		return newMalformedMessageError("data message", "flags", msg, msg)
 	}
 	in := msg
 	c.flag = in[0]
 
 	in = in[1:]
 	var ok bool
+	var rest []byte
 
-	in, c.senderKeyID, ok = extractWord(in)
-	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted senderKeyID")
+	if rest, c.senderKeyID, ok = extractWord(in); !ok {
+		return newMalformedMessageError("data message", "sender key id", msg, in)
 	}
+	in = rest
 
-	in, c.recipientKeyID, ok = extractWord(in)
-	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted recipientKeyID")
+	if rest, c.recipientKeyID, ok = extractWord(in); !ok {
+		return newMalformedMessageError("data message", "recipient key id", msg, in)
 	}
+	in = rest
 
-	in, c.y, ok = extractMPI(in)
-	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted y")
+	if rest, c.y, ok = extractMPI(in); !ok {
+		return newMalformedMessageError("data message", "next DH key", msg, in)
 	}
+	in = rest
 
 	if len(in) < len(c.topHalfCtr) {
-		return newOtrError("dataMsg.deserialize corrupted topHalfCtr")
+		return newMalformedMessageError("data message", "counter", msg, in)
 	}
 
 	copy(c.topHalfCtr[:], in)
 	if binary.BigEndian.Uint64(c.topHalfCtr[:]) == 0 {
-		return newOtrError("dataMsg.deserialize invalid topHalfCtr")
+		return newMalformedMessageError("data message", "counter", msg, in)
 	}
 
-	copy(c.topHalfCtr[:], in)
 	in = in[len(c.topHalfCtr):]
-	in, c.encryptedMsg, ok = extractData(in)
-	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted encryptedMsg")
+	if rest, c.encryptedMsg, ok = extractData(in); !ok {
+		return newMalformedMessageError("data message", "encrypted message", msg, in)
 	}
+	in = rest
 
 	c.serializeUnsignedCache = msg[:len(msg)-len(in)]
 	return nil
@@ -232,18 +243,20 @@ func (c *dataMsg) deserialize(msg []byte, v otrVersion) error {
 		return err
 	}
 
-	msg = msg[len(c.serializeUnsignedCache):]
-	c.authenticator = msg[0:v.hashLength()]
-	msg = msg[len(c.authenticator):]
+	in := msg[len(c.serializeUnsignedCache):]
+	if len(in) < v.hashLength() {
+		return newMalformedMessageError("data message", "authenticator", msg, in)
+	}
+	c.authenticator = in[0:v.hashLength()]
+	in = in[len(c.authenticator):]
 
-	var revKeysBytes []byte
-	msg, revKeysBytes, ok := extractData(msg)
+	_, revKeysBytes, ok := extractData(in)
 	if !ok {
-		return newOtrError("dataMsg.deserialize corrupted revealMACKeys")
+		return newMalformedMessageError("data message", "revealed MAC keys", msg, in)
 	}
 	for len(revKeysBytes) > 0 {
 		if len(revKeysBytes) < v.hashLength() {
-			return newOtrError("dataMsg.deserialize corrupted revealMACKeys")
+			return newMalformedMessageError("data message", "revealed MAC keys", msg, in)
 		}
 		revKey := make([]byte, v.hashLength())
 		copy(revKey, revKeysBytes)
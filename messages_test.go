@@ -87,7 +87,21 @@ func Test_dataMsg_deserializeUnsigned_failsWhenTopHalfCtrIsZero(t *testing.T) {
 	dataMessage := dataMsg{}
 	err := dataMessage.deserializeUnsigned(msg)
 
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize invalid topHalfCtr")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid counter at offset 14")
+}
+
+func Test_dataMsg_deserialize_failsWhenTheAuthenticatorIsTooShort(t *testing.T) {
+	msg := dataMsg{
+		senderKeyID:    uint32(0x00000001),
+		recipientKeyID: uint32(0x00000001),
+		y:              big.NewInt(1),
+		topHalfCtr:     [8]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+	}.serializeUnsigned()
+
+	dataMessage := dataMsg{}
+	err := dataMessage.deserialize(append(msg, 0x01, 0x02), otrV3{})
+
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid authenticator at offset 26")
 }
 
 func Test_dataMsgCheckSignWithoutError(t *testing.T) {
@@ -105,7 +119,7 @@ func Test_dataMsgCheckSignWithError(t *testing.T) {
 		authenticator:          []byte{0x6e, 0x6, 0x76, 0x45, 0xbb, 0x94, 0x5c, 0xa2, 0xfc, 0x13, 0xa9, 0xfa, 0x58, 0xb7, 0xd7, 0x23, 0xee, 0xab, 0x62, 0xe8},
 	}
 	macKey := macKey{0x00, 0x01, 0x02, 0x03, 0x00, 0x01, 0x02, 0x03, 0x00, 0x01, 0x02, 0x03, 0x00, 0x01, 0x02, 0x03, 0x00, 0x01, 0x02, 0x03}
-	assertDeepEquals(t, m.checkSign(macKey, []byte{}, otrV3{}), newOtrConflictError("bad signature MAC in encrypted signature"))
+	assertDeepEquals(t, m.checkSign(macKey, []byte{}, otrV3{}), &MACVerificationError{Message: "data message"})
 }
 
 func Test_dataMsgDeserialze(t *testing.T) {
@@ -160,7 +174,7 @@ func Test_dataMsgDeserialzeErrorWhenEmpty(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize empty message")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid flags at offset 0")
 }
 
 func Test_dataMsgDeserialzeErrorWhenCorruptedSenderKeyID(t *testing.T) {
@@ -174,7 +188,7 @@ func Test_dataMsgDeserialzeErrorWhenCorruptedSenderKeyID(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize corrupted senderKeyID")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid sender key id at offset 1")
 }
 
 func Test_dataMsgDeserialzeErrorWhenCorruptedReceiverKeyID(t *testing.T) {
@@ -190,7 +204,7 @@ func Test_dataMsgDeserialzeErrorWhenCorruptedReceiverKeyID(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize corrupted recipientKeyID")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid recipient key id at offset 5")
 }
 
 func Test_dataMsgDeserialzeErrorWhenCorruptedY(t *testing.T) {
@@ -209,7 +223,7 @@ func Test_dataMsgDeserialzeErrorWhenCorruptedY(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize corrupted y")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid next DH key at offset 9")
 }
 
 func Test_dataMsgDeserialzeErrorWhenCorruptedEncryptedMsg(t *testing.T) {
@@ -232,7 +246,7 @@ func Test_dataMsgDeserialzeErrorWhenCorruptedEncryptedMsg(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize corrupted encryptedMsg")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid encrypted message at offset 22")
 }
 
 func Test_dataMsgDeserialzeErrorWhenCorruptedTopHalfCtr(t *testing.T) {
@@ -253,7 +267,7 @@ func Test_dataMsgDeserialzeErrorWhenCorruptedTopHalfCtr(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize corrupted topHalfCtr")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid counter at offset 14")
 }
 
 func Test_dataMsgDeserialzeErrorWhenCorruptedRevealMACKeys(t *testing.T) {
@@ -292,7 +306,7 @@ func Test_dataMsgDeserialzeErrorWhenCorruptedRevealMACKeys(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize corrupted revealMACKeys")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid revealed MAC keys at offset 50")
 }
 
 func Test_dataMsgDeserialzeErrorWhenCorruptedRevealMACKeyEnding(t *testing.T) {
@@ -330,7 +344,7 @@ func Test_dataMsgDeserialzeErrorWhenCorruptedRevealMACKeyEnding(t *testing.T) {
 
 	dataMessage := dataMsg{}
 	err := dataMessage.deserialize(msg, otrV3{})
-	assertEquals(t, err.Error(), "otr: dataMsg.deserialize corrupted revealMACKeys")
+	assertEquals(t, err.Error(), "otr: corrupt data message: invalid revealed MAC keys at offset 50")
 }
 
 func Test_plainDataMsgShouldDeserializeOneTLV(t *testing.T) {
@@ -0,0 +1,144 @@
+package otr3
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// Metric is something that is counted by Metrics
+type Metric int
+
+const (
+	// MetricAKEStarted is counted when we send or receive a DH-Commit message
+	MetricAKEStarted Metric = iota
+	// MetricAKECompleted is counted when the AKE finishes and the conversation becomes encrypted
+	MetricAKECompleted
+	// MetricAKEFailed is counted when an AKE message can't be handled
+	MetricAKEFailed
+	// MetricSMPSucceeded is counted when SMP finishes and the peer knows the same secret
+	MetricSMPSucceeded
+	// MetricSMPFailed is counted when SMP finishes and the peer doesn't know the same secret
+	MetricSMPFailed
+	// MetricSMPAborted is counted when SMP is aborted by the peer
+	MetricSMPAborted
+	// MetricSMPCheated is counted when the peer sends SMP values that are invalid
+	MetricSMPCheated
+	// MetricSMPError is counted when an SMP message arrives in the wrong SMP state
+	MetricSMPError
+	// MetricDecryptionFailed is counted when a data message can't be read
+	MetricDecryptionFailed
+	// MetricHeartbeatSent is counted when a heartbeat is sent
+	MetricHeartbeatSent
+	// MetricHeartbeatReceived is counted when a heartbeat is received
+	MetricHeartbeatReceived
+	// MetricMessageResent is counted when a message is sent again after the conversation became encrypted
+	MetricMessageResent
+	// MetricFragmentsReassembled is counted when all fragments of a message have been received
+	MetricFragmentsReassembled
+
+	metricCount
+)
+
+// Metrics receives counts of what happens in conversations, for example to show on a dashboard.
+// One Metrics can be used for many conversations, so it must be safe to use from several goroutines.
+type Metrics interface {
+	Increment(m Metric)
+}
+
+// SetMetrics assigns the Metrics this conversation reports into
+func (c *Conversation) SetMetrics(m Metrics) {
+	c.metrics = m
+}
+
+func (c *Conversation) count(m Metric) {
+	if c.metrics != nil {
+		c.metrics.Increment(m)
+	}
+}
+
+func (c *Conversation) countSMPEvent(e SMPEvent) {
+	switch e {
+	case SMPEventSuccess:
+		c.count(MetricSMPSucceeded)
+	case SMPEventFailure:
+		c.count(MetricSMPFailed)
+	case SMPEventAbort:
+		c.count(MetricSMPAborted)
+	case SMPEventCheated:
+		c.count(MetricSMPCheated)
+	case SMPEventError:
+		c.count(MetricSMPError)
+	}
+}
+
+// MemoryMetrics is a Metrics that keeps the counts in memory. The zero value is ready to use, and
+// it is safe to use from several goroutines.
+type MemoryMetrics struct {
+	counts [metricCount]uint64
+}
+
+// Increment implements Metrics
+func (m *MemoryMetrics) Increment(metric Metric) {
+	if metric >= 0 && metric < metricCount {
+		atomic.AddUint64(&m.counts[metric], 1)
+	}
+}
+
+// Value returns the current count of the metric
+func (m *MemoryMetrics) Value(metric Metric) uint64 {
+	if metric < 0 || metric >= metricCount {
+		return 0
+	}
+	return atomic.LoadUint64(&m.counts[metric])
+}
+
+// Values returns the current count of all metrics, keyed by the name of the metric
+func (m *MemoryMetrics) Values() map[string]uint64 {
+	result := make(map[string]uint64, metricCount)
+	for metric := Metric(0); metric < metricCount; metric++ {
+		result[metric.String()] = m.Value(metric)
+	}
+	return result
+}
+
+// Publish exports the metrics with expvar under the given name, so that they are shown at /debug/vars together
+// with the other exported variables of the program. Just like expvar.Publish, it panics if the name is already used.
+func (m *MemoryMetrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Values()
+	}))
+}
+
+// String returns the name of the metric
+func (m Metric) String() string {
+	switch m {
+	case MetricAKEStarted:
+		return "AKEStarted"
+	case MetricAKECompleted:
+		return "AKECompleted"
+	case MetricAKEFailed:
+		return "AKEFailed"
+	case MetricSMPSucceeded:
+		return "SMPSucceeded"
+	case MetricSMPFailed:
+		return "SMPFailed"
+	case MetricSMPAborted:
+		return "SMPAborted"
+	case MetricSMPCheated:
+		return "SMPCheated"
+	case MetricSMPError:
+		return "SMPError"
+	case MetricDecryptionFailed:
+		return "DecryptionFailed"
+	case MetricHeartbeatSent:
+		return "HeartbeatSent"
+	case MetricHeartbeatReceived:
+		return "HeartbeatReceived"
+	case MetricMessageResent:
+		return "MessageResent"
+	case MetricFragmentsReassembled:
+		return "FragmentsReassembled"
+	default:
+		return "METRIC: (THIS SHOULD NEVER HAPPEN)"
+	}
+}
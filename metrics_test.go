@@ -0,0 +1,174 @@
+package otr3
+
+import (
+	"expvar"
+	"testing"
+)
+
+func Test_Metrics_countsTheAKE(t *testing.T) {
+	alice := newStatePeer(alicePrivateKey)
+	bob := newStatePeer(bobPrivateKey)
+	m := &MemoryMetrics{}
+	alice.SetMetrics(m)
+	bob.SetMetrics(m)
+
+	exchangeBetween(t, alice, bob, []ValidMessage{alice.QueryMessage()})
+
+	assertEquals(t, m.Value(MetricAKEStarted), uint64(2))
+	assertEquals(t, m.Value(MetricAKECompleted), uint64(2))
+	assertEquals(t, m.Value(MetricAKEFailed), uint64(0))
+}
+
+func Test_Metrics_countsFailedAKEMessages(t *testing.T) {
+	c := aliceContextAtAwaitingRevealSig()
+	m := &MemoryMetrics{}
+	c.SetMetrics(m)
+
+	c.potentialAuthError(nil, errShortRandomRead)
+
+	assertEquals(t, m.Value(MetricAKEFailed), uint64(1))
+}
+
+func Test_Metrics_countsSuccessfulSMP(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	m := &MemoryMetrics{}
+	alice.SetMetrics(m)
+	bob.SetMetrics(m)
+
+	toSend, err := alice.StartAuthenticate("", []byte("secret"))
+	assertNil(t, err)
+	_, _, err = bob.Receive(toSend[0])
+	assertNil(t, err)
+	toSend, err = bob.ProvideAuthenticationSecret([]byte("secret"))
+	assertNil(t, err)
+	exchangeBetween(t, bob, alice, toSend)
+
+	assertEquals(t, m.Value(MetricSMPSucceeded), uint64(2))
+	assertEquals(t, m.Value(MetricSMPFailed), uint64(0))
+}
+
+func Test_Metrics_countsAbortedSMP(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	m := &MemoryMetrics{}
+	bob.SetMetrics(m)
+
+	toSend, _ := alice.StartAuthenticate("", []byte("secret"))
+	bob.Receive(toSend[0])
+	toSend, _ = alice.AbortAuthentication()
+	bob.Receive(toSend[0])
+
+	assertEquals(t, m.Value(MetricSMPAborted), uint64(1))
+}
+
+func Test_Metrics_countsHeartbeats(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	m := &MemoryMetrics{}
+	alice.SetMetrics(m)
+	bob.SetMetrics(m)
+
+	toSend, err := bob.Send(ValidMessage("hello"))
+	assertNil(t, err)
+	_, heartbeat, err := alice.Receive(toSend[0])
+	assertNil(t, err)
+	assertEquals(t, len(heartbeat), 1)
+	bob.Receive(heartbeat[0])
+
+	assertEquals(t, m.Value(MetricHeartbeatSent), uint64(1))
+	assertEquals(t, m.Value(MetricHeartbeatReceived), uint64(1))
+}
+
+func Test_Metrics_countsDataMessagesThatCantBeRead(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	m := &MemoryMetrics{}
+	bob.SetMetrics(m)
+
+	toSend, _ := alice.Send(ValidMessage("hello"))
+	bob.Receive(toSend[0])
+	bob.Receive(toSend[0])
+
+	assertEquals(t, m.Value(MetricDecryptionFailed), uint64(1))
+}
+
+func Test_Metrics_countsReassembledFragments(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	m := &MemoryMetrics{}
+	bob.SetMetrics(m)
+	alice.SetFragmentSize(100)
+
+	toSend, err := alice.Send(ValidMessage("a message that is too long to fit in one fragment"))
+	assertNil(t, err)
+	assertTrue(t, len(toSend) > 1)
+	for _, f := range toSend {
+		bob.Receive(f)
+	}
+
+	assertEquals(t, m.Value(MetricFragmentsReassembled), uint64(1))
+}
+
+func Test_Metrics_countsResentMessages(t *testing.T) {
+	c := aliceContextAtAwaitingRevealSig()
+	m := &MemoryMetrics{}
+	c.SetMetrics(m)
+	c.resend.later(MessagePlaintext("what do you think turn 2"))
+	c.resend.later(MessagePlaintext("I mean, about that thing"))
+	c.resend.mayRetransmit = retransmitWithPrefix
+	c.updateLastSent()
+
+	c.receiveDecoded(fixtureRevealSigMsg(otrV2{}))
+
+	assertEquals(t, m.Value(MetricMessageResent), uint64(2))
+}
+
+func Test_MemoryMetrics_Values_returnsAllMetricsByName(t *testing.T) {
+	m := &MemoryMetrics{}
+	m.Increment(MetricAKEStarted)
+	m.Increment(MetricAKEStarted)
+	m.Increment(MetricMessageResent)
+	m.Increment(Metric(100))
+
+	values := m.Values()
+
+	assertEquals(t, len(values), int(metricCount))
+	assertEquals(t, values["AKEStarted"], uint64(2))
+	assertEquals(t, values["MessageResent"], uint64(1))
+	assertEquals(t, values["SMPFailed"], uint64(0))
+	assertEquals(t, m.Value(Metric(100)), uint64(0))
+}
+
+func Test_MemoryMetrics_Publish_exportsTheMetricsWithExpvar(t *testing.T) {
+	m := &MemoryMetrics{}
+	m.Publish("otr3_test_metrics")
+	m.Increment(MetricHeartbeatSent)
+
+	v := expvar.Get("otr3_test_metrics")
+
+	assertTrue(t, v != nil)
+	assertEquals(t, v.String(), `{"AKECompleted":0,"AKEFailed":0,"AKEStarted":0,"DecryptionFailed":0,"FragmentsReassembled":0,"HeartbeatReceived":0,"HeartbeatSent":1,"MessageResent":0,"SMPAborted":0,"SMPCheated":0,"SMPError":0,"SMPFailed":0,"SMPSucceeded":0}`)
+}
+
+func Test_Manager_SetMetrics_isUsedByNewConversations(t *testing.T) {
+	mm := &MemoryMetrics{}
+	m := newTestManager()
+	m.SetMetrics(mm)
+
+	c, _ := m.Conversation("alice@example.org", "xmpp", "bob@example.org")
+
+	assertEquals(t, c.metrics, Metrics(mm))
+}
+
+func Test_Metric_String_returnsTheNameOfTheMetric(t *testing.T) {
+	assertEquals(t, MetricAKEStarted.String(), "AKEStarted")
+	assertEquals(t, MetricAKECompleted.String(), "AKECompleted")
+	assertEquals(t, MetricAKEFailed.String(), "AKEFailed")
+	assertEquals(t, MetricSMPSucceeded.String(), "SMPSucceeded")
+	assertEquals(t, MetricSMPFailed.String(), "SMPFailed")
+	assertEquals(t, MetricSMPAborted.String(), "SMPAborted")
+	assertEquals(t, MetricSMPCheated.String(), "SMPCheated")
+	assertEquals(t, MetricSMPError.String(), "SMPError")
+	assertEquals(t, MetricDecryptionFailed.String(), "DecryptionFailed")
+	assertEquals(t, MetricHeartbeatSent.String(), "HeartbeatSent")
+	assertEquals(t, MetricHeartbeatReceived.String(), "HeartbeatReceived")
+	assertEquals(t, MetricMessageResent.String(), "MessageResent")
+	assertEquals(t, MetricFragmentsReassembled.String(), "FragmentsReassembled")
+	assertEquals(t, Metric(100).String(), "METRIC: (THIS SHOULD NEVER HAPPEN)")
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash"
+	"io"
 	"math/big"
 	"strconv"
 )
@@ -105,20 +106,29 @@ func (c *Conversation) generateInstanceTag() error {
 		return nil
 	}
 
+	ret, err := randomInstanceTag(c.rand())
+	if err != nil {
+		return err
+	}
+
+	c.ourInstanceTag = ret
+
+	return nil
+}
+
+func randomInstanceTag(r io.Reader) (uint32, error) {
 	var ret uint32
 	var dst [4]byte
 
 	for ret < minValidInstanceTag {
-		if err := c.randomInto(dst[:]); err != nil {
-			return err
+		if err := randomInto(r, dst[:]); err != nil {
+			return 0, err
 		}
 
 		ret = binary.BigEndian.Uint32(dst[:])
 	}
 
-	c.ourInstanceTag = ret
-
-	return nil
+	return ret, nil
 }
 
 func malformedMessage(c *Conversation) {
@@ -0,0 +1,408 @@
+package otr3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MessageType is the kind of message found by ParseMessage
+type MessageType int
+
+const (
+	// MessageTypeNotOTR is a plain message without anything OTR related in it
+	MessageTypeNotOTR MessageType = iota
+	// MessageTypeTaggedPlaintext is a plain message with a whitespace tag
+	MessageTypeTaggedPlaintext
+	// MessageTypeQuery is an OTR query message
+	MessageTypeQuery
+	// MessageTypeError is an OTR error message
+	MessageTypeError
+	// MessageTypeFragment is one fragment of a bigger OTR message
+	MessageTypeFragment
+	// MessageTypeDHCommit is the first message of the AKE
+	MessageTypeDHCommit
+	// MessageTypeDHKey is the second message of the AKE
+	MessageTypeDHKey
+	// MessageTypeRevealSig is the third message of the AKE
+	MessageTypeRevealSig
+	// MessageTypeSig is the last message of the AKE
+	MessageTypeSig
+	// MessageTypeData is an encrypted data message
+	MessageTypeData
+	// MessageTypeUnknown is a message that starts like an OTR message, but isn't one we know about
+	MessageTypeUnknown
+)
+
+// ParsedMessage has all the fields ParseMessage found in a message. Only the fields that make sense for the type
+// of the message are set. Since data messages are encrypted, the contents of them are not available.
+type ParsedMessage struct {
+	Type MessageType
+
+	// Version is the protocol version for encoded messages and fragments
+	Version uint16
+	// Versions are the protocol versions offered by query messages and whitespace tags
+	Versions []int
+	// The instance tags are only available for version 3 encoded messages and fragments
+	SenderInstanceTag, ReceiverInstanceTag uint32
+
+	// Plaintext is the human readable part of plain, tagged plaintext, query and error messages
+	Plaintext []byte
+
+	FragmentIndex, FragmentCount uint16
+	FragmentData                 []byte
+
+	// For DH-Commit messages
+	EncryptedGx, HashedGx []byte
+
+	// For DH-Key messages
+	Gy *big.Int
+
+	// For Reveal Signature and Signature messages
+	RevealedKey, EncryptedSignature, SignatureMAC []byte
+
+	// For data messages
+	Flags                           byte
+	SenderKeyID, RecipientKeyID     uint32
+	NextDHPublicKey                 *big.Int
+	TopHalfCounter                  [8]byte
+	EncryptedMessage, Authenticator []byte
+	RevealedMACKeys                 [][]byte
+}
+
+// ParseMessage identifies the OTR message given and extracts all of its fields, without needing a conversation.
+// It is meant for debugging - for example to find out what an OTR message in a bug report contains. Fragments are
+// not put together, but their contents can be given to ParseMessage once all of them have been found.
+func ParseMessage(msg []byte) (ParsedMessage, error) {
+	p := ParsedMessage{Type: MessageTypeUnknown}
+
+	switch guessMessageType(msg) {
+	case msgGuessNotOTR:
+		p.Type = MessageTypeNotOTR
+		p.Plaintext = makeCopy(msg)
+	case msgGuessTaggedPlaintext:
+		p.Type = MessageTypeTaggedPlaintext
+		var versions int
+		p.Plaintext, versions = extractWhitespaceTag(msg)
+		p.Versions = versionsIn(versions)
+	case msgGuessQuery:
+		p.Type = MessageTypeQuery
+		p.Versions = parseOTRQueryMessage(msg)
+		p.Plaintext = queryPlaintext(msg)
+	case msgGuessError:
+		p.Type = MessageTypeError
+		p.Plaintext = makeCopy(withoutPotentialSpaceStart(msg[len(errorMarker):]))
+	case msgGuessFragment:
+		return p, p.parseFragment(msg)
+	default:
+		if bytes.HasPrefix(msg, msgMarker) {
+			return p, p.parseEncoded(msg)
+		}
+	}
+
+	return p, nil
+}
+
+func versionsIn(versions int) []int {
+	var result []int
+	for _, v := range []int{2, 3} {
+		if versions&(1<<uint(v)) != 0 {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// queryPlaintext returns the human readable text after the versions in a query message
+func queryPlaintext(msg []byte) []byte {
+	rest := msg[len(queryMarker):]
+	if len(rest) > 0 && rest[0] == '?' {
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && rest[0] == 'v' {
+		i := bytes.IndexByte(rest, '?')
+		if i == -1 {
+			return nil
+		}
+		rest = rest[i+1:]
+	}
+	return makeCopy(bytes.TrimSpace(rest))
+}
+
+func (p *ParsedMessage) parseFragment(msg []byte) error {
+	p.Type = MessageTypeFragment
+	rest := msg
+
+	switch {
+	case bytes.HasPrefix(msg, otrv3FragmentationPrefix):
+		p.Version = 3
+		rest = msg[len(otrv3FragmentationPrefix):]
+		end := bytes.IndexByte(rest, fragmentSeparator[0])
+		if end == -1 {
+			return errInvalidOTRMessage
+		}
+		tags := bytes.Split(rest[:end], fragmentItagsSeparator)
+		if len(tags) != 2 {
+			return errInvalidOTRMessage
+		}
+		var err1, err2 error
+		p.SenderInstanceTag, err1 = parseItag(tags[0])
+		p.ReceiverInstanceTag, err2 = parseItag(tags[1])
+		if err1 != nil || err2 != nil {
+			return errInvalidOTRMessage
+		}
+		rest = rest[end+1:]
+	default:
+		p.Version = 2
+		rest = msg[len(otrv2FragmentationPrefix):]
+	}
+
+	var ok bool
+	if p.FragmentData, p.FragmentIndex, p.FragmentCount, ok = parseFragment(rest); !ok {
+		return errInvalidOTRMessage
+	}
+	p.FragmentData = makeCopy(p.FragmentData)
+	return nil
+}
+
+func (p *ParsedMessage) parseEncoded(msg []byte) error {
+	if len(msg) < len(msgMarker)+1 || msg[len(msg)-1] != '.' {
+		return errInvalidOTRMessage
+	}
+	decoded, err := b64decode(removeOTRMsgEnvelope(msg))
+	if err != nil {
+		return errInvalidOTRMessage
+	}
+
+	var version otrVersion
+	index, protocolVersion, ok := extractShort(decoded)
+	switch {
+	case !ok:
+		return errInvalidOTRMessage
+	case protocolVersion == 2:
+		version = otrV2{}
+	case protocolVersion == 3:
+		version = otrV3{}
+	default:
+		return ErrUnsupportedVersion
+	}
+	p.Version = protocolVersion
+
+	if len(index) < 1 {
+		return errInvalidOTRMessage
+	}
+	msgType := index[0]
+	index = index[1:]
+
+	if protocolVersion == 3 {
+		if index, p.SenderInstanceTag, ok = extractWord(index); !ok {
+			return errInvalidOTRMessage
+		}
+		if index, p.ReceiverInstanceTag, ok = extractWord(index); !ok {
+			return errInvalidOTRMessage
+		}
+	}
+
+	switch msgType {
+	case msgTypeDHCommit:
+		p.Type = MessageTypeDHCommit
+		m := dhCommit{}
+		err = m.deserialize(index)
+		p.EncryptedGx, p.HashedGx = m.encryptedGx, m.yhashedGx
+	case msgTypeDHKey:
+		p.Type = MessageTypeDHKey
+		m := dhKey{}
+		err = m.deserialize(index)
+		p.Gy = m.gy
+	case msgTypeRevealSig:
+		p.Type = MessageTypeRevealSig
+		m := revealSig{}
+		if err = m.deserialize(index, version); err == nil {
+			p.RevealedKey = makeCopy(m.r[:])
+		}
+		p.EncryptedSignature, p.SignatureMAC = m.encryptedSig, m.macSig
+	case msgTypeSig:
+		p.Type = MessageTypeSig
+		m := sig{}
+		err = m.deserialize(index)
+		p.EncryptedSignature, p.SignatureMAC = m.encryptedSig, m.macSig
+	case msgTypeData:
+		p.Type = MessageTypeData
+		m := dataMsg{}
+		err = m.deserialize(index, version)
+		p.Flags, p.SenderKeyID, p.RecipientKeyID = m.flag, m.senderKeyID, m.recipientKeyID
+		p.NextDHPublicKey, p.TopHalfCounter = m.y, m.topHalfCtr
+		p.EncryptedMessage, p.Authenticator = m.encryptedMsg, m.authenticator
+		for _, k := range m.oldMACKeys {
+			p.RevealedMACKeys = append(p.RevealedMACKeys, []byte(k))
+		}
+	default:
+		return newOtrErrorf("unknown message type 0x%X", msgType)
+	}
+
+	return err
+}
+
+func (p ParsedMessage) dataMessage() (dataMsg, otrVersion, error) {
+	if p.Type != MessageTypeData {
+		return dataMsg{}, nil, errNotDataMessage
+	}
+
+	var version otrVersion
+	switch p.Version {
+	case 2:
+		version = otrV2{}
+	case 3:
+		version = otrV3{}
+	default:
+		return dataMsg{}, nil, ErrUnsupportedVersion
+	}
+
+	if p.NextDHPublicKey == nil {
+		return dataMsg{}, nil, errInvalidOTRMessage
+	}
+
+	m := dataMsg{
+		flag:           p.Flags,
+		senderKeyID:    p.SenderKeyID,
+		recipientKeyID: p.RecipientKeyID,
+		y:              p.NextDHPublicKey,
+		topHalfCtr:     p.TopHalfCounter,
+		encryptedMsg:   p.EncryptedMessage,
+		authenticator:  p.Authenticator,
+	}
+	for _, k := range p.RevealedMACKeys {
+		m.oldMACKeys = append(m.oldMACKeys, macKey(k))
+	}
+	return m, version, nil
+}
+
+func (p ParsedMessage) header() []byte {
+	out := appendShort(nil, p.Version)
+	out = append(out, msgTypeData)
+	if p.Version == 3 {
+		out = appendWord(out, p.SenderInstanceTag)
+		out = appendWord(out, p.ReceiverInstanceTag)
+	}
+	return out
+}
+
+// AuthenticatedData returns the part of a data message that the Authenticator is calculated over - the header
+// and all the fields up to and including the encrypted message.
+func (p ParsedMessage) AuthenticatedData() ([]byte, error) {
+	m, _, err := p.dataMessage()
+	if err != nil {
+		return nil, err
+	}
+	return append(p.header(), m.serializeUnsigned()...), nil
+}
+
+// Encode returns a data message as an "?OTR:" message built from the fields of p. Together with ParseMessage it
+// makes it possible to change the fields of a data message and send it again.
+func (p ParsedMessage) Encode() ([]byte, error) {
+	m, version, err := p.dataMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg := append(p.header(), m.serialize(version)...)
+	return append(append(makeCopy(msgMarker), b64encode(msg)...), '.'), nil
+}
+
+// String returns the name of the message type
+func (t MessageType) String() string {
+	switch t {
+	case MessageTypeNotOTR:
+		return "Not OTR"
+	case MessageTypeTaggedPlaintext:
+		return "Tagged plaintext message"
+	case MessageTypeQuery:
+		return "Query message"
+	case MessageTypeError:
+		return "Error message"
+	case MessageTypeFragment:
+		return "Fragment"
+	case MessageTypeDHCommit:
+		return "D-H Commit Message"
+	case MessageTypeDHKey:
+		return "D-H Key Message"
+	case MessageTypeRevealSig:
+		return "Reveal Signature Message"
+	case MessageTypeSig:
+		return "Signature Message"
+	case MessageTypeData:
+		return "Data Message"
+	default:
+		return "Unknown message"
+	}
+}
+
+// String returns all the fields of the message, one per line, in the same format as otr_parse from the libotr toolkit
+func (p ParsedMessage) String() string {
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "%s:\n", p.Type)
+
+	field := func(name string, format string, a ...interface{}) {
+		fmt.Fprintf(w, "\t%s: "+format+"\n", append([]interface{}{name}, a...)...)
+	}
+	hexField := func(name string, b []byte) {
+		field(name, "%s", strings.ToUpper(hex.EncodeToString(b)))
+	}
+
+	switch p.Type {
+	case MessageTypeNotOTR, MessageTypeError:
+		field("Message", "%s", p.Plaintext)
+		return w.String()
+	case MessageTypeTaggedPlaintext, MessageTypeQuery:
+		field("Versions", "%v", p.Versions)
+		field("Message", "%s", p.Plaintext)
+		return w.String()
+	case MessageTypeUnknown:
+		return w.String()
+	}
+
+	field("Version", "%d", p.Version)
+	if p.Version == 3 {
+		field("Sender instance", "%08X", p.SenderInstanceTag)
+		field("Receiver instance", "%08X", p.ReceiverInstanceTag)
+	}
+
+	switch p.Type {
+	case MessageTypeFragment:
+		field("Fragment", "%d of %d", p.FragmentIndex, p.FragmentCount)
+		field("Data", "%s", p.FragmentData)
+	case MessageTypeDHCommit:
+		hexField("Encrypted g^x", p.EncryptedGx)
+		hexField("Hashed g^x", p.HashedGx)
+	case MessageTypeDHKey:
+		hexField("g^y", bigIntBytes(p.Gy))
+	case MessageTypeRevealSig:
+		hexField("Key", p.RevealedKey)
+		hexField("Encrypted signature", p.EncryptedSignature)
+		hexField("MAC", p.SignatureMAC)
+	case MessageTypeSig:
+		hexField("Encrypted signature", p.EncryptedSignature)
+		hexField("MAC", p.SignatureMAC)
+	case MessageTypeData:
+		field("Flags", "%02X", p.Flags)
+		field("Sender keyid", "%d", p.SenderKeyID)
+		field("Rcpt keyid", "%d", p.RecipientKeyID)
+		hexField("DH y", bigIntBytes(p.NextDHPublicKey))
+		hexField("Counter", p.TopHalfCounter[:])
+		hexField("Encrypted message", p.EncryptedMessage)
+		hexField("MAC", p.Authenticator)
+		for _, k := range p.RevealedMACKeys {
+			hexField("Revealed MAC key", k)
+		}
+	}
+
+	return w.String()
+}
+
+func bigIntBytes(n *big.Int) []byte {
+	if n == nil {
+		return nil
+	}
+	return n.Bytes()
+}
@@ -0,0 +1,238 @@
+package otr3
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// akeMessages runs an AKE between alice and bob, and returns the messages of it in order
+func akeMessages(t *testing.T) (alice, bob *Conversation, msgs []ValidMessage) {
+	alice = newStatePeer(alicePrivateKey)
+	bob = newStatePeer(bobPrivateKey)
+
+	toSend := []ValidMessage{alice.QueryMessage()}
+	from, to := alice, bob
+	for len(toSend) > 0 {
+		msgs = append(msgs, toSend...)
+		var next []ValidMessage
+		for _, m := range toSend {
+			_, ts, err := to.Receive(m)
+			assertNil(t, err)
+			next = append(next, ts...)
+		}
+		toSend, from, to = next, to, from
+	}
+	return
+}
+
+func Test_ParseMessage_parsesPlainMessages(t *testing.T) {
+	p, err := ParseMessage([]byte("hello there"))
+
+	assertNil(t, err)
+	assertEquals(t, p.Type, MessageTypeNotOTR)
+	assertDeepEquals(t, p.Plaintext, []byte("hello there"))
+}
+
+func Test_ParseMessage_parsesTaggedPlaintextMessages(t *testing.T) {
+	msg := append([]byte("hello there"), genWhitespaceTag(policies(allowV2|allowV3))...)
+
+	p, err := ParseMessage(msg)
+
+	assertNil(t, err)
+	assertEquals(t, p.Type, MessageTypeTaggedPlaintext)
+	assertDeepEquals(t, p.Versions, []int{2, 3})
+	assertDeepEquals(t, p.Plaintext, []byte("hello there"))
+}
+
+func Test_ParseMessage_parsesQueryMessages(t *testing.T) {
+	p, err := ParseMessage([]byte("?OTRv23? Bob has requested an Off-the-Record private conversation."))
+	p2, err2 := ParseMessage([]byte("?OTR?v2?"))
+
+	assertNil(t, err)
+	assertEquals(t, p.Type, MessageTypeQuery)
+	assertDeepEquals(t, p.Versions, []int{2, 3})
+	assertDeepEquals(t, p.Plaintext, []byte("Bob has requested an Off-the-Record private conversation."))
+
+	assertNil(t, err2)
+	assertDeepEquals(t, p2.Versions, []int{1, 2})
+	assertDeepEquals(t, p2.Plaintext, []byte{})
+}
+
+func Test_ParseMessage_parsesErrorMessages(t *testing.T) {
+	p, err := ParseMessage([]byte("?OTR Error: You sent encrypted data I couldn't read"))
+
+	assertNil(t, err)
+	assertEquals(t, p.Type, MessageTypeError)
+	assertDeepEquals(t, p.Plaintext, []byte("You sent encrypted data I couldn't read"))
+}
+
+func Test_ParseMessage_parsesFragments(t *testing.T) {
+	p, err := ParseMessage([]byte("?OTR|00000100|00000201,00001,00002,?OTR:AAMD,"))
+	p2, err2 := ParseMessage([]byte("?OTR,00002,00003,AAID,"))
+
+	assertNil(t, err)
+	assertEquals(t, p.Type, MessageTypeFragment)
+	assertEquals(t, p.Version, uint16(3))
+	assertEquals(t, p.SenderInstanceTag, uint32(0x100))
+	assertEquals(t, p.ReceiverInstanceTag, uint32(0x201))
+	assertEquals(t, p.FragmentIndex, uint16(1))
+	assertEquals(t, p.FragmentCount, uint16(2))
+	assertDeepEquals(t, p.FragmentData, []byte("?OTR:AAMD"))
+
+	assertNil(t, err2)
+	assertEquals(t, p2.Version, uint16(2))
+	assertEquals(t, p2.FragmentIndex, uint16(2))
+	assertEquals(t, p2.FragmentCount, uint16(3))
+	assertDeepEquals(t, p2.FragmentData, []byte("AAID"))
+}
+
+func Test_ParseMessage_returnsAnErrorForInvalidFragments(t *testing.T) {
+	for _, m := range []string{
+		"?OTR|00000100,00001,00002,data,",
+		"?OTR|0000010X|00000201,00001,00002,data,",
+		"?OTR|00000100|00000201",
+		"?OTR,00001,data,",
+	} {
+		p, err := ParseMessage([]byte(m))
+		assertEquals(t, p.Type, MessageTypeFragment)
+		assertEquals(t, err, errInvalidOTRMessage)
+	}
+}
+
+func Test_ParseMessage_parsesAllAKEMessages(t *testing.T) {
+	alice, bob, msgs := akeMessages(t)
+	assertEquals(t, len(msgs), 5)
+
+	dhCommit, err := ParseMessage(msgs[1])
+	assertNil(t, err)
+	assertEquals(t, dhCommit.Type, MessageTypeDHCommit)
+	assertEquals(t, dhCommit.Version, uint16(3))
+	assertEquals(t, dhCommit.SenderInstanceTag, bob.ourInstanceTag)
+	assertEquals(t, dhCommit.ReceiverInstanceTag, uint32(0))
+	assertEquals(t, len(dhCommit.HashedGx), 32)
+
+	dhKey, err := ParseMessage(msgs[2])
+	assertNil(t, err)
+	assertEquals(t, dhKey.Type, MessageTypeDHKey)
+	assertEquals(t, dhKey.SenderInstanceTag, alice.ourInstanceTag)
+	assertEquals(t, dhKey.ReceiverInstanceTag, bob.ourInstanceTag)
+	assertTrue(t, dhKey.Gy.BitLen() > 1500)
+
+	revealSig, err := ParseMessage(msgs[3])
+	assertNil(t, err)
+	assertEquals(t, revealSig.Type, MessageTypeRevealSig)
+	assertEquals(t, len(revealSig.RevealedKey), 16)
+	assertEquals(t, len(revealSig.SignatureMAC), 20)
+
+	sig, err := ParseMessage(msgs[4])
+	assertNil(t, err)
+	assertEquals(t, sig.Type, MessageTypeSig)
+	assertEquals(t, len(sig.SignatureMAC), 20)
+	assertTrue(t, len(sig.EncryptedSignature) > 0)
+}
+
+func Test_ParseMessage_parsesDataMessages(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	revealed := false
+
+	for i := 0; i < 4; i++ {
+		toSend, _ := alice.Send(ValidMessage("hello"))
+		p, err := ParseMessage(toSend[0])
+		assertNil(t, err)
+		assertEquals(t, p.Type, MessageTypeData)
+		assertEquals(t, p.SenderInstanceTag, alice.ourInstanceTag)
+		assertEquals(t, p.SenderKeyID, alice.keys.ourKeyID-1)
+		assertEquals(t, p.RecipientKeyID, alice.keys.theirKeyID)
+		assertDeepEquals(t, p.NextDHPublicKey, alice.keys.ourCurrentDHKeys.pub)
+		assertEquals(t, len(p.Authenticator), 20)
+		revealed = revealed || len(p.RevealedMACKeys) > 0
+
+		bob.Receive(toSend[0])
+		sendBetween(t, bob, alice, "hi")
+	}
+
+	assertTrue(t, revealed)
+}
+
+func Test_ParseMessage_returnsAnErrorForInvalidEncodedMessages(t *testing.T) {
+	_, err := ParseMessage([]byte("?OTR:!!!!."))
+	_, err2 := ParseMessage([]byte("?OTR:" + string(b64encode([]byte{0x00, 0x04, 0x02})) + "."))
+	_, err3 := ParseMessage([]byte("?OTR:" + string(b64encode([]byte{0x00, 0x02, 0x42})) + "."))
+	_, err4 := ParseMessage([]byte("?OTR:AAMD"))
+	_, err5 := ParseMessage([]byte("?OTR:" + string(b64encode([]byte{0x00, 0x03, 0x02, 0x00})) + "."))
+
+	assertEquals(t, err, errInvalidOTRMessage)
+	assertEquals(t, err2, ErrUnsupportedVersion)
+	assertDeepEquals(t, err3, newOtrError("unknown message type 0x42"))
+	assertEquals(t, err4, errInvalidOTRMessage)
+	assertEquals(t, err5, errInvalidOTRMessage)
+}
+
+func Test_ParseMessage_returnsAnErrorForTruncatedMessages(t *testing.T) {
+	alice, _ := encryptedStatePeers(t)
+	toSend, _ := alice.Send(ValidMessage("hello"))
+	decoded, _ := b64decode(removeOTRMsgEnvelope(encodedMessage(toSend[0])))
+
+	for i := 0; i < len(decoded)-4; i++ {
+		msg := append(append(makeCopy(msgMarker), b64encode(decoded[:i])...), '.')
+		_, err := ParseMessage(msg)
+		assertNotNil(t, err)
+	}
+}
+
+func Test_ParsedMessage_StringShowsAllFields(t *testing.T) {
+	_, _, msgs := akeMessages(t)
+	p, _ := ParseMessage(msgs[2])
+
+	s := p.String()
+
+	assertTrue(t, strings.HasPrefix(s, "D-H Key Message:\n\tVersion: 3\n\tSender instance: "))
+	assertTrue(t, strings.Contains(s, "\tg^y: "+strings.ToUpper(hex.EncodeToString(p.Gy.Bytes()))+"\n"))
+}
+
+func Test_ParsedMessage_StringShowsPlainMessages(t *testing.T) {
+	p, _ := ParseMessage([]byte("?OTRv3? hello"))
+
+	assertEquals(t, p.String(), "Query message:\n\tVersions: [3]\n\tMessage: hello\n")
+}
+
+func Test_ParsedMessage_EncodeGivesBackTheSameDataMessage(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+
+	for i := 0; i < 3; i++ {
+		toSend, _ := alice.Send(ValidMessage("hello"))
+		p, _ := ParseMessage(toSend[0])
+
+		encoded, err := p.Encode()
+
+		assertNil(t, err)
+		assertDeepEquals(t, encoded, []byte(toSend[0]))
+
+		bob.Receive(toSend[0])
+		sendBetween(t, bob, alice, "hi")
+	}
+}
+
+func Test_ParsedMessage_AuthenticatedDataIsThePartBeforeTheAuthenticator(t *testing.T) {
+	alice, _ := encryptedStatePeers(t)
+	toSend, _ := alice.Send(ValidMessage("hello"))
+	p, _ := ParseMessage(toSend[0])
+	decoded, _ := b64decode(removeOTRMsgEnvelope(encodedMessage(toSend[0])))
+
+	data, err := p.AuthenticatedData()
+
+	assertNil(t, err)
+	assertDeepEquals(t, data, decoded[:len(data)])
+	assertDeepEquals(t, p.Authenticator, decoded[len(data):len(data)+len(p.Authenticator)])
+}
+
+func Test_ParsedMessage_EncodeReturnsAnErrorForOtherMessages(t *testing.T) {
+	p, _ := ParseMessage([]byte("?OTRv3? hello"))
+	_, err := p.Encode()
+	assertEquals(t, err, errNotDataMessage)
+
+	p = ParsedMessage{Type: MessageTypeData, Version: 4}
+	_, err = p.AuthenticatedData()
+	assertEquals(t, err, ErrUnsupportedVersion)
+}
@@ -49,9 +49,8 @@ func extractVersionsFromQueryMessage(p policies, msg ValidMessage) int {
 
 var timeoutLength = time.Duration(1) * time.Minute
 
-func isWithinTimeToIgnoreQueryMessage(t time.Time) bool {
-	return t.Add(timeoutLength).After(time.Now())
-
+func isWithinTimeToIgnoreQueryMessage(t, now time.Time) bool {
+	return t.Add(timeoutLength).After(now)
 }
 
 func (c *Conversation) receiveQueryMessage(msg ValidMessage) ([]messageWithHeader, error) {
@@ -61,8 +60,8 @@ func (c *Conversation) receiveQueryMessage(msg ValidMessage) ([]messageWithHeade
 		return nil, err
 	}
 
-	if dontIgnoreFastRepeatQueryMessage != "true" && ((c.msgState == encrypted && isWithinTimeToIgnoreQueryMessage(c.lastMessageStateChange)) ||
-		(c.ake != nil && isWithinTimeToIgnoreQueryMessage(c.ake.lastStateChange))) {
+	if dontIgnoreFastRepeatQueryMessage != "true" && ((c.msgState == encrypted && isWithinTimeToIgnoreQueryMessage(c.lastMessageStateChange, c.now())) ||
+		(c.ake != nil && isWithinTimeToIgnoreQueryMessage(c.ake.lastStateChange, c.now()))) {
 		return nil, nil
 	}
 
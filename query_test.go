@@ -74,7 +74,7 @@ func Test_receiveQueryMessage_returnsErrorIfNoCompatibleVersionCouldBeFound(t *t
 	c := &Conversation{Policies: policies(allowV3)}
 	c.SetOurKeys([]PrivateKey{bobPrivateKey})
 	_, err := c.receiveQueryMessage([]byte("?OTRv?2?"))
-	assertEquals(t, err, errUnsupportedOTRVersion)
+	assertEquals(t, err, ErrUnsupportedVersion)
 }
 
 func Test_receiveQueryMessage_returnsErrorIfDhCommitMessageGeneratesError(t *testing.T) {
@@ -2,7 +2,11 @@ package otr3
 
 // Receive handles a message from a peer. It returns a human readable message and zero or more messages to send back to the peer.
 func (c *Conversation) Receive(m ValidMessage) (plain MessagePlaintext, toSend []ValidMessage, err error) {
-	return c.receiveUnit(m, true)
+	plain, toSend, err = c.receiveUnit(m, true)
+	if err != nil {
+		c.logRejectedMessage(m, err)
+	}
+	return
 }
 
 // Receive handles a message from a peer. It returns a human readable message and zero or more messages to send back to the peer.
@@ -27,11 +31,12 @@ func (c *Conversation) receiveUnit(m ValidMessage, forgetFragments bool) (plain
 	case msgGuessNotOTR:
 		plain, messagesToSend, err = c.receivePlaintext(message)
 	case msgGuessV1KeyExch:
-		return nil, nil, errUnsupportedOTRVersion
+		return nil, nil, ErrUnsupportedVersion
 	case msgGuessFragment:
 		shouldForgetFragment = false
 		c.fragmentationContext, err = c.receiveFragment(c.fragmentationContext, message)
 		if fragmentsFinished(c.fragmentationContext) {
+			c.count(MetricFragmentsReassembled)
 			return c.withInjectionsPlain(c.receiveUnit(c.fragmentationContext.frag, false))
 		}
 	case msgGuessUnknown:
@@ -33,10 +33,10 @@ func Test_receiveDecoded_checkMessageVersion(t *testing.T) {
 	msgV3, _ := cV3.wrapMessageHeader(msgTypeDHCommit, nil)
 
 	_, _, err := cV2.receiveDecoded(msgV3)
-	assertEquals(t, err, errWrongProtocolVersion)
+	assertEquals(t, err, ErrWrongProtocolVersion)
 
 	_, _, err = cV3.receiveDecoded(msgV2)
-	assertEquals(t, err, errWrongProtocolVersion)
+	assertEquals(t, err, ErrWrongProtocolVersion)
 }
 
 func Test_receiveDecoded_returnsErrorIfTheMessageIsCorrupt(t *testing.T) {
@@ -49,7 +49,7 @@ func Test_receiveDecoded_returnsErrorIfTheMessageIsCorrupt(t *testing.T) {
 	assertEquals(t, err, errInvalidOTRMessage)
 
 	_, _, err = cV3.receiveDecoded([]byte{0x00, 0x00})
-	assertEquals(t, err, errWrongProtocolVersion)
+	assertEquals(t, err, ErrWrongProtocolVersion)
 
 	_, _, err = cV3.receiveDecoded([]byte{0x00, 0x03, 0x56, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x01, 0x01})
 	assertDeepEquals(t, err, newOtrError("unknown message type 0x56"))
@@ -231,7 +231,7 @@ func Test_Receive_returnsAnErrorIfWeReceiveARequestToStartAVersion1KeyExchange(t
 
 	_, _, err := c.Receive(ValidMessage("?OTR:AAEK"))
 
-	assertEquals(t, err, errUnsupportedOTRVersion)
+	assertEquals(t, err, ErrUnsupportedVersion)
 }
 
 func Test_Receive_willResetFragmentationContextIfWeReceiveAnUnfragmentedMessage(t *testing.T) {
@@ -5,7 +5,8 @@ import (
 	"time"
 )
 
-const resendInterval = 60 * time.Second
+// How long after the last message sent do we still resend queued messages, if nothing else has been configured?
+const defaultResendInterval = 60 * time.Second
 
 type retransmitFlag int
 
@@ -26,6 +27,7 @@ type resendContext struct {
 	mayRetransmit    retransmitFlag
 	messageTransform func([]byte) []byte
 	retransmitting   bool
+	interval         time.Duration
 
 	messages struct {
 		m []messageToResend
@@ -95,9 +97,32 @@ func (c *Conversation) updateMayRetransmitTo(f retransmitFlag) {
 	c.resend.mayRetransmit = f
 }
 
+// SetResendInterval sets for how long after sending a message we will resend the messages queued while waiting
+// for a private conversation to be established. Setting it to zero will use the default of 60 seconds.
+func (c *Conversation) SetResendInterval(d time.Duration) {
+	c.resend.interval = d
+}
+
+func (c *Conversation) resendInterval() time.Duration {
+	if c.resend.interval == 0 {
+		return defaultResendInterval
+	}
+	return c.resend.interval
+}
+
+func (c *Conversation) withinResendInterval(now time.Time) bool {
+	return c.heartbeat.lastSent.After(now.Add(-c.resendInterval()))
+}
+
 func (c *Conversation) shouldRetransmit() bool {
 	return c.resend.shouldRetransmit() &&
-		c.heartbeat.lastSent.After(time.Now().Add(-resendInterval))
+		c.withinResendInterval(c.now())
+}
+
+func (c *Conversation) expireResends(now time.Time) {
+	if !c.withinResendInterval(now) {
+		c.resend.clear()
+	}
 }
 
 func (c *Conversation) maybeRetransmit() ([]messageWithHeader, error) {
@@ -141,6 +166,9 @@ func (c *Conversation) retransmit() ([]messageWithHeader, error) {
 	}
 	for _, msgx := range msgs {
 		c.messageEvent(ev, msgx.opaque...)
+		if resending {
+			c.count(MetricMessageResent)
+		}
 	}
 
 	c.updateLastSent()
@@ -0,0 +1,163 @@
+package otr3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// scrypt as specified in RFC 7914. It is used to derive keys from passphrases, since it makes
+// brute forcing expensive by needing a lot of memory. It is implemented here to avoid external dependencies.
+
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	result := make([]byte, 0, keyLen+sha256.Size)
+	u := make([]byte, sha256.Size)
+	t := make([]byte, sha256.Size)
+
+	for block := uint32(1); len(result) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(appendWord(nil, block))
+		u = prf.Sum(u[:0])
+		copy(t, u)
+
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		result = append(result, t...)
+	}
+
+	return result[:keyLen]
+}
+
+func rotl(u uint32, n uint) uint32 {
+	return u<<n | u>>(32-n)
+}
+
+func salsa208(b []uint32) {
+	var x [16]uint32
+	copy(x[:], b)
+
+	for i := 0; i < 8; i += 2 {
+		x[4] ^= rotl(x[0]+x[12], 7)
+		x[8] ^= rotl(x[4]+x[0], 9)
+		x[12] ^= rotl(x[8]+x[4], 13)
+		x[0] ^= rotl(x[12]+x[8], 18)
+		x[9] ^= rotl(x[5]+x[1], 7)
+		x[13] ^= rotl(x[9]+x[5], 9)
+		x[1] ^= rotl(x[13]+x[9], 13)
+		x[5] ^= rotl(x[1]+x[13], 18)
+		x[14] ^= rotl(x[10]+x[6], 7)
+		x[2] ^= rotl(x[14]+x[10], 9)
+		x[6] ^= rotl(x[2]+x[14], 13)
+		x[10] ^= rotl(x[6]+x[2], 18)
+		x[3] ^= rotl(x[15]+x[11], 7)
+		x[7] ^= rotl(x[3]+x[15], 9)
+		x[11] ^= rotl(x[7]+x[3], 13)
+		x[15] ^= rotl(x[11]+x[7], 18)
+
+		x[1] ^= rotl(x[0]+x[3], 7)
+		x[2] ^= rotl(x[1]+x[0], 9)
+		x[3] ^= rotl(x[2]+x[1], 13)
+		x[0] ^= rotl(x[3]+x[2], 18)
+		x[6] ^= rotl(x[5]+x[4], 7)
+		x[7] ^= rotl(x[6]+x[5], 9)
+		x[4] ^= rotl(x[7]+x[6], 13)
+		x[5] ^= rotl(x[4]+x[7], 18)
+		x[11] ^= rotl(x[10]+x[9], 7)
+		x[8] ^= rotl(x[11]+x[10], 9)
+		x[9] ^= rotl(x[8]+x[11], 13)
+		x[10] ^= rotl(x[9]+x[8], 18)
+		x[12] ^= rotl(x[15]+x[14], 7)
+		x[13] ^= rotl(x[12]+x[15], 9)
+		x[14] ^= rotl(x[13]+x[12], 13)
+		x[15] ^= rotl(x[14]+x[13], 18)
+	}
+
+	for i := range x {
+		b[i] += x[i]
+	}
+}
+
+// blockMix mixes the 2r blocks of 16 words in b, using y as scratch space
+func blockMix(b, y []uint32, r int) {
+	x := make([]uint32, 16)
+	copy(x, b[(2*r-1)*16:])
+
+	for i := 0; i < 2*r; i++ {
+		for j := range x {
+			x[j] ^= b[i*16+j]
+		}
+		salsa208(x)
+		// even blocks go to the first half of the result, odd blocks to the second
+		copy(y[((i%2)*r+i/2)*16:], x)
+	}
+
+	copy(b, y)
+}
+
+func roMix(b []byte, r, n int) {
+	words := 32 * r
+	x := make([]uint32, words)
+	y := make([]uint32, words)
+	v := make([]uint32, words*n)
+
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+
+	for i := 0; i < n; i++ {
+		copy(v[i*words:], x)
+		blockMix(x, y, r)
+	}
+
+	for i := 0; i < n; i++ {
+		j := int(x[(2*r-1)*16] & uint32(n-1))
+		for k := range x {
+			x[k] ^= v[j*words+k]
+		}
+		blockMix(x, y, r)
+	}
+
+	for i := range x {
+		binary.LittleEndian.PutUint32(b[i*4:], x[i])
+	}
+
+	wipeWords(v)
+	wipeWords(x)
+	wipeWords(y)
+}
+
+func wipeWords(w []uint32) {
+	for i := range w {
+		w[i] = 0
+	}
+}
+
+// scryptKey derives a key of the given length from the password. n must be a power of two larger than one
+func scryptKey(password, salt []byte, n, r, p, keyLen int) ([]byte, error) {
+	if n < 2 || n&(n-1) != 0 {
+		return nil, newOtrError("scrypt: n must be a power of two larger than one")
+	}
+	if r < 1 || p < 1 || uint64(r)*uint64(p) >= 1<<30 || r > maxInt/128/p || r > maxInt/256 || n > maxInt/128/r {
+		return nil, newOtrError("scrypt: parameters are too large")
+	}
+
+	b := pbkdf2SHA256(password, salt, 1, p*128*r)
+	defer wipeBytes(b)
+
+	for i := 0; i < p; i++ {
+		roMix(b[i*128*r:], r, n)
+	}
+
+	return pbkdf2SHA256(password, b, 1, keyLen), nil
+}
+
+const maxInt = int(^uint(0) >> 1)
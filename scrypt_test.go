@@ -0,0 +1,53 @@
+package otr3
+
+import "testing"
+
+// Test vectors from RFC 7914
+
+func Test_pbkdf2SHA256_generatesTheExpectedKeys(t *testing.T) {
+	assertDeepEquals(t, pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64),
+		bytesFromHex("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"))
+	assertDeepEquals(t, pbkdf2SHA256([]byte("Password"), []byte("NaCl"), 80000, 64),
+		bytesFromHex("4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"))
+}
+
+func Test_salsa208_generatesTheExpectedOutput(t *testing.T) {
+	in := bytesFromHex("7e879a214f3ec9867ca940e641718f26baee555b8c61c1b50df846116dcd3b1dee24f319df9b3d8514121e4b5ac5aa3276021d2909c74829edebc68db8b8c25e")
+	b := make([]uint32, 16)
+	for i := range b {
+		b[i] = uint32(in[i*4]) | uint32(in[i*4+1])<<8 | uint32(in[i*4+2])<<16 | uint32(in[i*4+3])<<24
+	}
+
+	salsa208(b)
+
+	out := make([]byte, 64)
+	for i, w := range b {
+		out[i*4], out[i*4+1], out[i*4+2], out[i*4+3] = byte(w), byte(w>>8), byte(w>>16), byte(w>>24)
+	}
+	assertDeepEquals(t, out, bytesFromHex("a41f859c6608cc993b81cacb020cef05044b2181a2fd337dfd7b1c6396682f29b4393168e3c9e6bcfe6bc5b7a06d96bae424cc102c91745c24ad673dc7618f81"))
+}
+
+func Test_scryptKey_generatesTheExpectedKeys(t *testing.T) {
+	k1, err1 := scryptKey([]byte(""), []byte(""), 16, 1, 1, 64)
+	k2, err2 := scryptKey([]byte("password"), []byte("NaCl"), 1024, 8, 16, 64)
+	k3, err3 := scryptKey([]byte("pleaseletmein"), []byte("SodiumChloride"), 16384, 8, 1, 64)
+
+	assertNil(t, err1)
+	assertNil(t, err2)
+	assertNil(t, err3)
+	assertDeepEquals(t, k1, bytesFromHex("77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"))
+	assertDeepEquals(t, k2, bytesFromHex("fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"))
+	assertDeepEquals(t, k3, bytesFromHex("7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"))
+}
+
+func Test_scryptKey_returnsAnErrorForInvalidParameters(t *testing.T) {
+	_, err1 := scryptKey([]byte("password"), []byte("salt"), 1, 1, 1, 32)
+	_, err2 := scryptKey([]byte("password"), []byte("salt"), 1000, 1, 1, 32)
+	_, err3 := scryptKey([]byte("password"), []byte("salt"), 16, 0, 1, 32)
+	_, err4 := scryptKey([]byte("password"), []byte("salt"), 16, 1<<20, 1<<10, 32)
+
+	assertNotNil(t, err1)
+	assertNotNil(t, err2)
+	assertNotNil(t, err3)
+	assertNotNil(t, err4)
+}
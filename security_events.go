@@ -2,7 +2,9 @@ package otr3
 
 import "fmt"
 
-// SecurityEvent define the events used to indicate changes in security status. In comparison with libotr, this library does not take trust levels into concern for security events
+// SecurityEvent define the events used to indicate changes in security status. Trust levels are only taken into concern
+// if a TrustResolver has been set on the conversation - in that case the Verified and Unverified variants of GoneSecure
+// and StillSecure will be signalled instead of the plain ones
 type SecurityEvent int
 
 const (
@@ -12,6 +14,17 @@ const (
 	GoneSecure
 	// StillSecure is signalled when we have refreshed the security state but is still in a secure state
 	StillSecure
+	// GoneSecureVerified is signalled instead of GoneSecure when the key of the peer has been verified
+	GoneSecureVerified
+	// GoneSecureUnverified is signalled instead of GoneSecure when the key of the peer has not been verified
+	GoneSecureUnverified
+	// StillSecureVerified is signalled instead of StillSecure when the key of the peer has been verified
+	StillSecureVerified
+	// StillSecureUnverified is signalled instead of StillSecure when the key of the peer has not been verified
+	StillSecureUnverified
+	// NewFingerprintForVerifiedPeer is signalled before GoneSecureUnverified or StillSecureUnverified when
+	// the peer used to have a verified key, but is now using a different key
+	NewFingerprintForVerifiedPeer
 )
 
 // SecurityEventHandler is an interface for events that are related to changes of security status
@@ -29,6 +42,7 @@ func (d dynamicSecurityEventHandler) HandleSecurityEvent(event SecurityEvent) {
 }
 
 func (c *Conversation) securityEvent(e SecurityEvent) {
+	c.sendSecurityEvent(e)
 	if c.securityEventHandler != nil {
 		c.securityEventHandler.HandleSecurityEvent(e)
 	}
@@ -43,6 +57,16 @@ func (s SecurityEvent) String() string {
 		return "GoneSecure"
 	case StillSecure:
 		return "StillSecure"
+	case GoneSecureVerified:
+		return "GoneSecureVerified"
+	case GoneSecureUnverified:
+		return "GoneSecureUnverified"
+	case StillSecureVerified:
+		return "StillSecureVerified"
+	case StillSecureUnverified:
+		return "StillSecureUnverified"
+	case NewFingerprintForVerifiedPeer:
+		return "NewFingerprintForVerifiedPeer"
 	default:
 		return "SECURITY EVENT: (THIS SHOULD NEVER HAPPEN)"
 	}
@@ -6,6 +6,11 @@ func Test_SecurityEvent_hasValidStringImplementation(t *testing.T) {
 	assertEquals(t, GoneInsecure.String(), "GoneInsecure")
 	assertEquals(t, GoneSecure.String(), "GoneSecure")
 	assertEquals(t, StillSecure.String(), "StillSecure")
+	assertEquals(t, GoneSecureVerified.String(), "GoneSecureVerified")
+	assertEquals(t, GoneSecureUnverified.String(), "GoneSecureUnverified")
+	assertEquals(t, StillSecureVerified.String(), "StillSecureVerified")
+	assertEquals(t, StillSecureUnverified.String(), "StillSecureUnverified")
+	assertEquals(t, NewFingerprintForVerifiedPeer.String(), "NewFingerprintForVerifiedPeer")
 	assertEquals(t, SecurityEvent(20000).String(), "SECURITY EVENT: (THIS SHOULD NEVER HAPPEN)")
 }
 
@@ -1,9 +1,6 @@
 package otr3
 
-import (
-	"bufio"
-	"bytes"
-)
+import "bytes"
 
 // Send takes a human readable message from the local user, possibly encrypts
 // it and returns zero or more messages to send to the peer.
@@ -16,7 +13,7 @@ func (c *Conversation) Send(m ValidMessage, trace ...interface{}) ([]ValidMessag
 	}
 
 	if c.debug && bytes.Index(message, []byte(debugString)) != -1 {
-		c.dump(bufio.NewWriter(standardErrorOutput))
+		c.DumpState(standardErrorOutput)
 		return nil, nil
 	}
 
@@ -27,10 +24,10 @@ func (c *Conversation) Send(m ValidMessage, trace ...interface{}) ([]ValidMessag
 		return c.withInjections(c.sendMessageOnEncrypted(message))
 	case finished:
 		c.messageEvent(MessageEventConnectionEnded)
-		return c.withInjections(nil, newOtrError("cannot send message because secure conversation has finished"))
+		return c.withInjections(nil, ErrSessionFinished)
 	}
 
-	return c.withInjections(nil, newOtrError("cannot send message in current state"))
+	return c.withInjections(nil, ErrNotEncrypted)
 }
 
 func (c *Conversation) sendMessageOnPlaintext(message ValidMessage, trace ...interface{}) ([]ValidMessage, error) {
@@ -56,6 +53,7 @@ func (c *Conversation) sendMessageOnEncrypted(message ValidMessage) ([]ValidMess
 }
 
 func (c *Conversation) sendDHCommit() (toSend messageWithHeader, err error) {
+	defer c.logAuthStateChange(c.authStateName())
 	c.ake.wipe(true)
 	c.ake = nil
 
@@ -70,6 +68,7 @@ func (c *Conversation) sendDHCommit() (toSend messageWithHeader, err error) {
 	}
 
 	c.ake.state = authStateAwaitingDHKey{}
+	c.count(MetricAKEStarted)
 
 	return
 }
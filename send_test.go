@@ -141,20 +141,23 @@ func Test_Send_printsDebugStatementToStderrIfGivenMagicString(t *testing.T) {
   Our instance:   00000101
   Their instance: 00000101
 
-  Msgstate: 0 (PLAINTEXT)
+  Msgstate: PLAINTEXT
 
   Protocol version: 3
   OTR offer: NOT
 
   Auth info:
-    State: 0 (NONE)
+    State: NONE
     Our keyid:   2
     Their keyid: 1
+    Our fingerprint:   
     Their fingerprint: 0BB01C360424522E94EE9C346CE877A1A4288B2F
-    Proto version = 3
+    SSID: 0000000000000000
+
+  Counters:
 
   SM state:
-    Next expected: 0 (EXPECT1)
-    Received_Q: 0
+    Next expected: EXPECT1
+    Received question: false
 `)
 }
@@ -0,0 +1,283 @@
+package otr3
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+// Session keeps track of all the conversations with one peer that is potentially logged in from several places at once.
+// Each instance of the peer gets its own Conversation, and incoming messages are routed to the right one based on
+// the sender instance tag. This corresponds to the master and child contexts in libotr.
+//
+// The master conversation is the one given to NewSession. It is used to configure the children, it receives all
+// messages that don't carry instance tags (plaintext, query, error and version 2 messages) and it is used to send
+// messages when no instance of the peer is known yet.
+type Session struct {
+	master    *Conversation
+	instances map[uint32]*sessionInstance
+}
+
+type sessionInstance struct {
+	c            *Conversation
+	lastReceived time.Time
+}
+
+// NewSession creates a session using the given conversation as master. Keys, policies, event handlers and
+// other settings of the master will be used for all instances created by the session.
+func NewSession(master *Conversation) *Session {
+	return &Session{
+		master:    master,
+		instances: make(map[uint32]*sessionInstance),
+	}
+}
+
+// Master returns the master conversation of this session
+func (s *Session) Master() *Conversation {
+	return s.master
+}
+
+// Instances returns the instance tags of all the instances of the peer we have seen, in ascending order
+func (s *Session) Instances() []uint32 {
+	result := make([]uint32, 0, len(s.instances))
+	for tag := range s.instances {
+		result = append(result, tag)
+	}
+	sort.Sort(instanceTags(result))
+	return result
+}
+
+// Conversation returns the conversation for the given instance of the peer, and not ok if that instance is unknown
+func (s *Session) Conversation(instanceTag uint32) (*Conversation, bool) {
+	if i, ok := s.instances[instanceTag]; ok {
+		return i.c, true
+	}
+	return nil, false
+}
+
+// Best returns the conversation that messages should be sent to when no specific instance has been chosen.
+// Like libotr, it prefers encrypted conversations over finished ones, finished over plaintext, and among
+// equals the instance we most recently received a message from. If no instances are known, the master is returned.
+func (s *Session) Best() *Conversation {
+	best := s.master
+	var bestReceived time.Time
+
+	for _, tag := range s.Instances() {
+		i := s.instances[tag]
+		if msgStatePreference(i.c.msgState) > msgStatePreference(best.msgState) ||
+			(msgStatePreference(i.c.msgState) == msgStatePreference(best.msgState) && i.lastReceived.After(bestReceived)) {
+			best, bestReceived = i.c, i.lastReceived
+		}
+	}
+
+	return best
+}
+
+// Send sends the message to the best instance of the peer
+func (s *Session) Send(m ValidMessage, trace ...interface{}) ([]ValidMessage, error) {
+	return s.Best().Send(m, trace...)
+}
+
+// SendTo sends the message to a specific instance of the peer
+func (s *Session) SendTo(instanceTag uint32, m ValidMessage, trace ...interface{}) ([]ValidMessage, error) {
+	c, ok := s.Conversation(instanceTag)
+	if !ok {
+		return nil, newOtrError("unknown instance")
+	}
+	return c.Send(m, trace...)
+}
+
+// Receive handles a message from the peer by giving it to the conversation for the instance that sent it.
+// It returns the instance tag of the sender - zero if the message was handled by the master - together with
+// the same results as Conversation.Receive
+func (s *Session) Receive(m ValidMessage) (instanceTag uint32, plain MessagePlaintext, toSend []ValidMessage, err error) {
+	their, our, msgType, ok := instanceTagsFrom(m)
+	if !ok {
+		plain, toSend, err = s.master.Receive(m)
+		return 0, plain, toSend, err
+	}
+
+	if err = s.master.generateInstanceTag(); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if their < minValidInstanceTag || (our != 0 && our != s.master.ourInstanceTag) {
+		s.master.messageEvent(MessageEventReceivedMessageForOtherInstance)
+		return their, nil, nil, nil
+	}
+
+	i := s.instanceFor(their)
+	i.lastReceived = s.master.now()
+
+	if guessMessageType(m) == msgGuessFragment {
+		msgType = i.c.reassembledMessageType(m)
+	}
+
+	if msgType == msgTypeDHKey {
+		s.copyPendingAKE(i.c)
+	}
+
+	plain, toSend, err = i.c.Receive(m)
+	return their, plain, toSend, err
+}
+
+// End ends all the conversations in this session and returns the messages to send to the peer
+func (s *Session) End() ([]ValidMessage, error) {
+	var result []ValidMessage
+	var errs []error
+
+	for _, tag := range s.Instances() {
+		toSend, err := s.instances[tag].c.End()
+		result = append(result, toSend...)
+		errs = append(errs, err)
+	}
+
+	toSend, err := s.master.End()
+	result = append(result, toSend...)
+	errs = append(errs, err)
+
+	return result, firstError(errs...)
+}
+
+func (s *Session) instanceFor(their uint32) *sessionInstance {
+	if i, ok := s.instances[their]; ok {
+		return i
+	}
+
+	i := &sessionInstance{c: s.master.newInstanceConversation(their)}
+	s.instances[their] = i
+	return i
+}
+
+// If the master has sent a DH-Commit to all instances, the instance that answers first with a DH-Key
+// gets a copy of that AKE - just like libotr does it
+func (s *Session) copyPendingAKE(c *Conversation) {
+	if s.master.ake == nil || s.master.ake.state != (authStateAwaitingDHKey{}) {
+		return
+	}
+
+	if c.ake != nil && c.ake.state != (authStateNone{}) {
+		return
+	}
+
+	c.ake = s.master.ake.copyForOtherInstance()
+}
+
+func (c *Conversation) newInstanceConversation(theirInstanceTag uint32) *Conversation {
+	return &Conversation{
+		Rand:                 c.Rand,
+		Clock:                c.Clock,
+		ourInstanceTag:       c.ourInstanceTag,
+		theirInstanceTag:     theirInstanceTag,
+		ourKeys:              c.ourKeys,
+		Policies:             c.Policies,
+		fragmentSize:         c.fragmentSize,
+		smpEventHandler:      c.smpEventHandler,
+		errorMessageHandler:  c.errorMessageHandler,
+		messageEventHandler:  c.messageEventHandler,
+		securityEventHandler: c.securityEventHandler,
+		receivedKeyHandler:   c.receivedKeyHandler,
+		trustResolver:        c.trustResolver,
+		logger:               c.logger,
+		logSecrets:           c.logSecrets,
+		metrics:              c.metrics,
+		events:               c.events,
+		debug:                c.debug,
+		friendlyQueryMessage: c.friendlyQueryMessage,
+	}
+}
+
+func msgStatePreference(s msgState) int {
+	switch s {
+	case encrypted:
+		return 2
+	case finished:
+		return 1
+	}
+	return 0
+}
+
+// instanceTagsFrom extracts the instance tags from a version 3 data, AKE or fragment message
+func instanceTagsFrom(m ValidMessage) (their, our uint32, msgType byte, ok bool) {
+	switch guessMessageType(m) {
+	case msgGuessFragment:
+		if !bytes.HasPrefix(m, otrv3FragmentationPrefix) {
+			return 0, 0, 0, false
+		}
+		return instanceTagsFromFragment(m)
+	case msgGuessDHCommit, msgGuessDHKey, msgGuessRevealSig, msgGuessSignature, msgGuessData:
+		return instanceTagsFromEncoded(m)
+	}
+	return 0, 0, 0, false
+}
+
+// instanceTagsFromFragment always returns a message type of zero, since the type is only known once all the
+// fragments have been put together - see reassembledMessageType
+func instanceTagsFromFragment(m ValidMessage) (their, our uint32, msgType byte, ok bool) {
+	parts := bytes.SplitN(m[len(otrv3FragmentationPrefix):], fragmentSeparator, 2)
+	itags := bytes.Split(parts[0], fragmentItagsSeparator)
+	if len(itags) != 2 {
+		return 0, 0, 0, false
+	}
+
+	their, err1 := parseItag(itags[0])
+	our, err2 := parseItag(itags[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, 0, false
+	}
+
+	return their, our, 0, true
+}
+
+// reassembledMessageType returns the type of the message that the version 3 fragment m completes, or zero if
+// m is not the last fragment of a message that c has been receiving
+func (c *Conversation) reassembledMessageType(m ValidMessage) byte {
+	parts := bytes.SplitN(m[len(otrv3FragmentationPrefix):], fragmentSeparator, 2)
+	if len(parts) != 2 {
+		return 0
+	}
+
+	data, ix, l, ok := parseFragment(parts[1])
+	if !ok || fragmentIsInvalid(ix, l) || ix != l {
+		return 0
+	}
+
+	var whole []byte
+	switch {
+	case fragmentIsFirstMessage(ix, l):
+		whole = data
+	case fragmentIsNextMessage(c.fragmentationContext, ix, l):
+		whole = append(makeCopy(c.fragmentationContext.frag), data...)
+	default:
+		return 0
+	}
+
+	_, _, msgType, _ := instanceTagsFromEncoded(ValidMessage(whole))
+	return msgType
+}
+
+func instanceTagsFromEncoded(m ValidMessage) (their, our uint32, msgType byte, ok bool) {
+	if !bytes.HasSuffix(m, []byte{'.'}) {
+		return 0, 0, 0, false
+	}
+
+	msg, err := b64decode(removeOTRMsgEnvelope(encodedMessage(m)))
+	if err != nil || len(msg) < otrv3HeaderLen {
+		return 0, 0, 0, false
+	}
+
+	if _, version, _ := extractShort(msg); version != 3 {
+		return 0, 0, 0, false
+	}
+
+	rest, their, _ := extractWord(msg[messageHeaderPrefix:])
+	_, our, _ = extractWord(rest)
+
+	return their, our, msg[2], true
+}
+
+type instanceTags []uint32
+
+func (t instanceTags) Len() int           { return len(t) }
+func (t instanceTags) Less(i, j int) bool { return t[i] < t[j] }
+func (t instanceTags) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
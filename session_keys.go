@@ -0,0 +1,57 @@
+package otr3
+
+import "math/big"
+
+// SessionKeys are all the keys derived from one Diffie-Hellman shared secret, seen from the side that has the
+// private exponent. The AKE keys are only used if the shared secret came from the AKE, and the sending and
+// receiving keys are used for data messages.
+type SessionKeys struct {
+	// SSID is the secure session id shown to users to detect man-in-the-middle attacks
+	SSID [8]byte
+
+	// The keys used to encrypt and authenticate the Reveal Signature message (c, m1 and m2) and
+	// the Signature message (c', m1' and m2') during the AKE
+	RevealSigAESKey, RevealSigMAC1, RevealSigMAC2 []byte
+	SignatureAESKey, SignatureMAC1, SignatureMAC2 []byte
+
+	SendingAESKey, ReceivingAESKey []byte
+	SendingMACKey, ReceivingMACKey []byte
+
+	// ExtraKey is the extra symmetric key that is available for this pair of DH keys
+	ExtraKey []byte
+}
+
+// CalculateSessionKeys derives the session keys for the given protocol version from our private DH exponent and
+// the public DH value of the peer, the same way a conversation does. It is meant for debugging and for showing
+// how OTR deniability works - anyone who learns one of the private exponents can calculate all of these keys.
+func CalculateSessionKeys(ourPrivateKey, theirPublicKey *big.Int, version int) (SessionKeys, error) {
+	var ret SessionKeys
+
+	var v otrVersion
+	switch version {
+	case 2:
+		v = otrV2{}
+	case 3:
+		v = otrV3{}
+	default:
+		return ret, ErrUnsupportedVersion
+	}
+
+	if ourPrivateKey == nil || ourPrivateKey.Sign() <= 0 || theirPublicKey == nil || !v.isGroupElement(theirPublicKey) {
+		return ret, newOtrError("invalid DH key")
+	}
+
+	s := new(big.Int).Exp(theirPublicKey, ourPrivateKey, p)
+	ssid, revealSigKeys, signatureKeys := calculateAKEKeys(s, v)
+
+	ret.SSID = ssid
+	ret.RevealSigAESKey, ret.RevealSigMAC1, ret.RevealSigMAC2 = revealSigKeys.c, revealSigKeys.m1, revealSigKeys.m2
+	ret.SignatureAESKey, ret.SignatureMAC1, ret.SignatureMAC2 = signatureKeys.c, signatureKeys.m1, signatureKeys.m2
+
+	keys := calculateDHSessionKeys(ourPrivateKey, modExp(g1, ourPrivateKey), theirPublicKey, v)
+	ret.SendingAESKey, ret.ReceivingAESKey = keys.sendingAESKey, keys.receivingAESKey
+	ret.SendingMACKey, ret.ReceivingMACKey = keys.sendingMACKey, keys.receivingMACKey
+	ret.ExtraKey = keys.extraKey
+
+	return ret, nil
+}
@@ -0,0 +1,54 @@
+package otr3
+
+import (
+	"math/big"
+	"testing"
+)
+
+func Test_CalculateSessionKeys_returnsTheSameKeysAsAConversation(t *testing.T) {
+	keys, err := CalculateSessionKeys(fixedX(), fixedGY(), 3)
+
+	assertNil(t, err)
+	assertDeepEquals(t, keys.SendingAESKey, bytesFromHex("42e258bebf031acf442f52d6ef52d6f1"))
+	assertDeepEquals(t, keys.SendingMACKey, bytesFromHex("a45e2b122f58bbe2042f73f092329ad9b5dfe23e"))
+	assertDeepEquals(t, keys.ReceivingAESKey, bytesFromHex("c778c71cb63161e8e06d245e77ff6430"))
+	assertDeepEquals(t, keys.ReceivingMACKey, bytesFromHex("03f8034b891b1e843db5bba9a41ec68a1f5f8bbf"))
+	assertDeepEquals(t, keys.ExtraKey, bytesFromHex("0e1810c7c62c3bace6450dcbef16af8a271b5ac93030b83e9d0d80e0641e3c18"))
+
+	ssid, revealSigKeys, signatureKeys := calculateAKEKeys(modExp(fixedGY(), fixedX()), otrV3{})
+	assertDeepEquals(t, keys.SSID, ssid)
+	assertDeepEquals(t, keys.RevealSigAESKey, revealSigKeys.c)
+	assertDeepEquals(t, keys.RevealSigMAC1, revealSigKeys.m1)
+	assertDeepEquals(t, keys.RevealSigMAC2, revealSigKeys.m2)
+	assertDeepEquals(t, keys.SignatureAESKey, signatureKeys.c)
+	assertDeepEquals(t, keys.SignatureMAC1, signatureKeys.m1)
+	assertDeepEquals(t, keys.SignatureMAC2, signatureKeys.m2)
+}
+
+func Test_CalculateSessionKeys_givesTheOtherSideTheReverseKeys(t *testing.T) {
+	ours, _ := CalculateSessionKeys(fixedX(), fixedGY(), 2)
+	theirs, err := CalculateSessionKeys(fixedY(), fixedGX(), 2)
+
+	assertNil(t, err)
+	assertDeepEquals(t, theirs.SSID, ours.SSID)
+	assertDeepEquals(t, theirs.SendingAESKey, ours.ReceivingAESKey)
+	assertDeepEquals(t, theirs.ReceivingAESKey, ours.SendingAESKey)
+	assertDeepEquals(t, theirs.SendingMACKey, ours.ReceivingMACKey)
+	assertDeepEquals(t, theirs.ExtraKey, ours.ExtraKey)
+}
+
+func Test_CalculateSessionKeys_failsForUnsupportedVersions(t *testing.T) {
+	_, err := CalculateSessionKeys(fixedX(), fixedGY(), 4)
+	assertEquals(t, err, ErrUnsupportedVersion)
+}
+
+func Test_CalculateSessionKeys_failsForInvalidKeys(t *testing.T) {
+	_, err := CalculateSessionKeys(fixedX(), big.NewInt(1), 3)
+	assertEquals(t, err, newOtrError("invalid DH key"))
+
+	_, err = CalculateSessionKeys(nil, fixedGY(), 3)
+	assertEquals(t, err, newOtrError("invalid DH key"))
+
+	_, err = CalculateSessionKeys(fixedX(), nil, 3)
+	assertEquals(t, err, newOtrError("invalid DH key"))
+}
@@ -0,0 +1,225 @@
+package otr3
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func newSessionPeer(key PrivateKey, instanceTag uint32) *Conversation {
+	c := &Conversation{Rand: rand.Reader}
+	c.Policies = policies(allowV2 | allowV3)
+	c.SetOurKeys([]PrivateKey{key})
+	c.InitializeInstanceTag(instanceTag)
+	return c
+}
+
+// exchangeWithSession passes messages back and forth between a session and a single peer conversation until neither has anything more to say
+func exchangeWithSession(t *testing.T, s *Session, peer *Conversation, toPeer []ValidMessage) {
+	for len(toPeer) > 0 {
+		var toSession []ValidMessage
+		for _, m := range toPeer {
+			_, ts, err := peer.Receive(m)
+			assertNil(t, err)
+			toSession = append(toSession, ts...)
+		}
+
+		toPeer = nil
+		for _, m := range toSession {
+			_, _, ts, err := s.Receive(m)
+			assertNil(t, err)
+			toPeer = append(toPeer, ts...)
+		}
+	}
+}
+
+func Test_Session_routesMessagesFromEachInstanceToItsOwnConversation(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+	bobLaptop := newSessionPeer(bobPrivateKey, 0x2000)
+	bobPhone := newSessionPeer(bobPrivateKey, 0x3000)
+
+	exchangeWithSession(t, alice, bobLaptop, []ValidMessage{alice.Master().QueryMessage()})
+	exchangeWithSession(t, alice, bobPhone, []ValidMessage{alice.Master().QueryMessage()})
+
+	assertDeepEquals(t, alice.Instances(), []uint32{0x2000, 0x3000})
+	assertTrue(t, bobLaptop.IsEncrypted())
+	assertTrue(t, bobPhone.IsEncrypted())
+
+	laptop, _ := alice.Conversation(0x2000)
+	phone, _ := alice.Conversation(0x3000)
+	assertTrue(t, laptop.IsEncrypted())
+	assertTrue(t, phone.IsEncrypted())
+	assertEquals(t, laptop.theirInstanceTag, uint32(0x2000))
+	assertEquals(t, phone.theirInstanceTag, uint32(0x3000))
+
+	msg, _ := bobPhone.Send(ValidMessage("hello from my phone"))
+	from, plain, _, err := alice.Receive(msg[0])
+	assertNil(t, err)
+	assertEquals(t, from, uint32(0x3000))
+	assertDeepEquals(t, plain, MessagePlaintext("hello from my phone"))
+
+	msg, _ = alice.SendTo(0x2000, ValidMessage("hello laptop"))
+	plain, _, err = bobLaptop.Receive(msg[0])
+	assertNil(t, err)
+	assertDeepEquals(t, plain, MessagePlaintext("hello laptop"))
+}
+
+func Test_Session_letsAnInstanceAnswerTheDHCommitSentByTheMaster(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+	bob := newSessionPeer(bobPrivateKey, 0x2000)
+
+	_, _, dhCommit, err := alice.Receive(bob.QueryMessage())
+	assertNil(t, err)
+	assertEquals(t, alice.Master().ake.state, authStateAwaitingDHKey{})
+
+	exchangeWithSession(t, alice, bob, dhCommit)
+
+	c, ok := alice.Conversation(0x2000)
+	assertTrue(t, ok)
+	assertTrue(t, c.IsEncrypted())
+	assertTrue(t, bob.IsEncrypted())
+	assertFalse(t, alice.Master().IsEncrypted())
+}
+
+func Test_Session_letsAnInstanceAnswerTheDHCommitWithAFragmentedDHKey(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+	bob := newSessionPeer(bobPrivateKey, 0x2000)
+	bob.SetFragmentSize(100)
+
+	_, _, dhCommit, err := alice.Receive(bob.QueryMessage())
+	assertNil(t, err)
+
+	_, dhKey, err := bob.Receive(dhCommit[0])
+	assertNil(t, err)
+	assertTrue(t, len(dhKey) > 1)
+
+	var revealSig []ValidMessage
+	for _, m := range dhKey {
+		_, _, revealSig, err = alice.Receive(m)
+		assertNil(t, err)
+	}
+	assertEquals(t, len(revealSig), 1)
+
+	exchangeWithSession(t, alice, bob, revealSig)
+
+	c, _ := alice.Conversation(0x2000)
+	assertTrue(t, c.IsEncrypted())
+	assertTrue(t, bob.IsEncrypted())
+}
+
+func Test_reassembledMessageType_returnsTheTypeOnlyForTheLastFragment(t *testing.T) {
+	c := &Conversation{}
+	encoded := "?OTR:" + string(b64encode([]byte{0x00, 0x03, msgTypeDHKey, 0, 0, 0x02, 0x01, 0, 0, 0x01, 0x02})) + "."
+	first := ValidMessage("?OTR|00000201|00000102,00001,00002," + encoded[:6] + ",")
+	last := ValidMessage("?OTR|00000201|00000102,00002,00002," + encoded[6:] + ",")
+
+	assertEquals(t, c.reassembledMessageType(first), byte(0))
+
+	c.fragmentationContext = restartFragment([]byte(encoded[:6]), 1, 2)
+
+	assertEquals(t, c.reassembledMessageType(last), byte(msgTypeDHKey))
+}
+
+func Test_Session_BestPrefersEncryptedInstances(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+	bob := newSessionPeer(bobPrivateKey, 0x2000)
+
+	assertEquals(t, alice.Best(), alice.Master())
+
+	exchangeWithSession(t, alice, bob, []ValidMessage{alice.Master().QueryMessage()})
+
+	c, _ := alice.Conversation(0x2000)
+	assertEquals(t, alice.Best(), c)
+
+	msg, err := alice.Send(ValidMessage("hi"))
+	assertNil(t, err)
+	plain, _, _ := bob.Receive(msg[0])
+	assertDeepEquals(t, plain, MessagePlaintext("hi"))
+}
+
+func Test_Session_BestPrefersTheMostRecentlyActiveInstance(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+	bobLaptop := newSessionPeer(bobPrivateKey, 0x2000)
+	bobPhone := newSessionPeer(bobPrivateKey, 0x3000)
+	exchangeWithSession(t, alice, bobPhone, []ValidMessage{alice.Master().QueryMessage()})
+	exchangeWithSession(t, alice, bobLaptop, []ValidMessage{alice.Master().QueryMessage()})
+
+	laptop, _ := alice.Conversation(0x2000)
+	phone, _ := alice.Conversation(0x3000)
+	assertEquals(t, alice.Best(), laptop)
+
+	msg, _ := bobPhone.Send(ValidMessage("still here"))
+	alice.Receive(msg[0])
+	assertEquals(t, alice.Best(), phone)
+}
+
+func Test_Session_ignoresMessagesForOtherInstancesOfUs(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+	bob := newSessionPeer(bobPrivateKey, 0x2000)
+	aliceElsewhere := newSessionPeer(alicePrivateKey, 0x1001)
+	_, dhCommit, _ := aliceElsewhere.Receive(bob.QueryMessage())
+	_, dhKey, _ := bob.Receive(dhCommit[0])
+
+	alice.Master().expectMessageEvent(t, func() {
+		from, plain, toSend, err := alice.Receive(dhKey[0])
+		assertEquals(t, from, uint32(0x2000))
+		assertNil(t, plain)
+		assertNil(t, toSend)
+		assertNil(t, err)
+	}, MessageEventReceivedMessageForOtherInstance, nil, nil)
+
+	assertEquals(t, len(alice.Instances()), 0)
+}
+
+func Test_Session_givesPlaintextMessagesToTheMaster(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+
+	from, plain, _, err := alice.Receive(ValidMessage("hello"))
+
+	assertNil(t, err)
+	assertEquals(t, from, uint32(0))
+	assertDeepEquals(t, plain, MessagePlaintext("hello"))
+	assertEquals(t, len(alice.Instances()), 0)
+}
+
+func Test_Session_SendToReturnsAnErrorForAnUnknownInstance(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+
+	_, err := alice.SendTo(0x2000, ValidMessage("hello"))
+
+	assertEquals(t, err, newOtrError("unknown instance"))
+}
+
+func Test_Session_EndEndsAllInstances(t *testing.T) {
+	alice := NewSession(newSessionPeer(alicePrivateKey, 0x1000))
+	bobLaptop := newSessionPeer(bobPrivateKey, 0x2000)
+	bobPhone := newSessionPeer(bobPrivateKey, 0x3000)
+	exchangeWithSession(t, alice, bobLaptop, []ValidMessage{alice.Master().QueryMessage()})
+	exchangeWithSession(t, alice, bobPhone, []ValidMessage{alice.Master().QueryMessage()})
+
+	toSend, err := alice.End()
+
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 2)
+	laptop, _ := alice.Conversation(0x2000)
+	phone, _ := alice.Conversation(0x3000)
+	assertFalse(t, laptop.IsEncrypted())
+	assertFalse(t, phone.IsEncrypted())
+}
+
+func Test_instanceTagsFrom_extractsTheTagsFromAFragment(t *testing.T) {
+	their, our, _, ok := instanceTagsFrom(ValidMessage("?OTR|00000201|00000102,00001,00002,?OTR:AAMD,"))
+
+	assertTrue(t, ok)
+	assertEquals(t, their, uint32(0x201))
+	assertEquals(t, our, uint32(0x102))
+}
+
+func Test_instanceTagsFrom_returnsNotOKForMessagesWithoutInstanceTags(t *testing.T) {
+	_, _, _, ok1 := instanceTagsFrom(ValidMessage("?OTR,00001,00002,?OTR:AAID,"))
+	_, _, _, ok2 := instanceTagsFrom(ValidMessage("?OTRv3?"))
+	_, _, _, ok3 := instanceTagsFrom(ValidMessage("?OTR:AAIC."))
+
+	assertFalse(t, ok1)
+	assertFalse(t, ok2)
+	assertFalse(t, ok3)
+}
@@ -0,0 +1,91 @@
+package otr3
+
+import (
+	"crypto"
+	"crypto/dsa"
+	"encoding/asn1"
+	"io"
+	"math/big"
+)
+
+// SignerPrivateKey is a PrivateKey that doesn't have the private key material itself. Instead it asks a crypto.Signer
+// to make the signatures needed during the AKE, so that the long-term key can be kept somewhere else - for example
+// in a separate signing process that is talked to over a pipe, or in a hardware token.
+//
+// The Signer must return a *dsa.PublicKey from Public. Sign is called with the data to sign and crypto.Hash(0) as the
+// options, since the data is a MAC and not the output of a standard hash function. Just like with dsa.Sign, only the
+// leftmost bits of the data that fit in the size of Q should be used. The signature must be returned ASN.1 DER encoded
+// as a sequence of the two integers R and S, the same way as for DSA signatures in X.509.
+//
+// Since the private key isn't available, a SignerPrivateKey can't be serialized, exported to a libotr file or generated.
+type SignerPrivateKey struct {
+	DSAPublicKey
+	Signer crypto.Signer
+}
+
+type dsaSignature struct {
+	R, S *big.Int
+}
+
+var errSignerNotDSA = newOtrError("signer doesn't have a DSA public key")
+var errSignerInvalidSignature = newOtrError("signer returned an invalid signature")
+var errSignerCantGenerate = newOtrError("keys for an external signer can't be generated here")
+
+// NewSignerPrivateKey creates a private key that signs with the given signer
+func NewSignerPrivateKey(s crypto.Signer) (*SignerPrivateKey, error) {
+	pub, ok := s.Public().(*dsa.PublicKey)
+	if !ok || pub.P == nil || pub.Q == nil || pub.G == nil || pub.Y == nil {
+		return nil, errSignerNotDSA
+	}
+
+	k := &SignerPrivateKey{Signer: s}
+	k.DSAPublicKey.PublicKey = *pub
+	return k, nil
+}
+
+// Parse returns not ok, since the private key material of a signer can't be parsed
+func (k *SignerPrivateKey) Parse(in []byte) ([]byte, bool) {
+	return in, false
+}
+
+// Serialize returns nil, since the private key material of a signer isn't available
+func (k *SignerPrivateKey) Serialize() []byte {
+	return nil
+}
+
+// Generate returns an error, since new keys have to be generated by the signer
+func (k *SignerPrivateKey) Generate(io.Reader) error {
+	return errSignerCantGenerate
+}
+
+// PublicKey returns the public key of the signer
+func (k *SignerPrivateKey) PublicKey() PublicKey {
+	return &k.DSAPublicKey
+}
+
+// Sign asks the signer for a signature of the hashed data. The signature is verified before it is returned,
+// so that a misbehaving signer is noticed here instead of by the peer.
+func (k *SignerPrivateKey) Sign(rand io.Reader, hashed []byte) ([]byte, error) {
+	der, err := k.Signer.Sign(rand, hashed, crypto.Hash(0))
+	if err != nil {
+		return nil, err
+	}
+
+	var sig dsaSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil ||
+		sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.BitLen() > 160 || sig.S.BitLen() > 160 {
+		return nil, errSignerInvalidSignature
+	}
+
+	if !dsa.Verify(&k.DSAPublicKey.PublicKey, hashed, sig.R, sig.S) {
+		return nil, errSignerInvalidSignature
+	}
+
+	rBytes := sig.R.Bytes()
+	sBytes := sig.S.Bytes()
+	out := make([]byte, 40)
+	copy(out[20-len(rBytes):], rBytes)
+	copy(out[len(out)-len(sBytes):], sBytes)
+	return out, nil
+}
@@ -0,0 +1,198 @@
+package otr3
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// The external signer used in these tests runs in a separate process - the test binary started again, running
+// Test_externalSignerProcess. It first writes the public key, and then answers every request with a signature.
+// All messages are encoded as DATA.
+
+const externalSignerProcessEnv = "OTR3_TEST_EXTERNAL_SIGNER"
+
+func Test_externalSignerProcess(t *testing.T) {
+	if os.Getenv(externalSignerProcessEnv) != "1" {
+		return
+	}
+
+	key := &alicePrivateKey.(*DSAPrivateKey).PrivateKey
+	in := bufio.NewReader(os.Stdin)
+	os.Stdout.Write(appendData(nil, alicePrivateKey.PublicKey().serialize()))
+
+	for {
+		hashed, err := readSignerData(in)
+		if err != nil {
+			os.Exit(0)
+		}
+		r, s, err := dsa.Sign(rand.Reader, key, hashed)
+		if err != nil {
+			os.Exit(1)
+		}
+		sig, _ := asn1.Marshal(dsaSignature{r, s})
+		os.Stdout.Write(appendData(nil, sig))
+	}
+}
+
+func readSignerData(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	_, n, _ := extractWord(header)
+	data := make([]byte, n)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+type processSigner struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+	pub *dsa.PublicKey
+}
+
+func startExternalSigner(t *testing.T) *processSigner {
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_externalSignerProcess$")
+	cmd.Env = append(os.Environ(), externalSignerProcessEnv+"=1")
+	in, _ := cmd.StdinPipe()
+	out, _ := cmd.StdoutPipe()
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("couldn't start the external signer: %v", err)
+	}
+
+	s := &processSigner{cmd: cmd, in: in, out: bufio.NewReader(out)}
+	serialized, err := readSignerData(s.out)
+	assertNil(t, err)
+	_, ok, pub := ParsePublicKey(serialized)
+	assertTrue(t, ok)
+	s.pub = &pub.(*DSAPublicKey).PublicKey
+	return s
+}
+
+func (s *processSigner) stop() {
+	s.in.Close()
+	s.cmd.Wait()
+}
+
+func (s *processSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func (s *processSigner) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	if _, err := s.in.Write(appendData(nil, digest)); err != nil {
+		return nil, err
+	}
+	return readSignerData(s.out)
+}
+
+type fixedSigner struct {
+	pub crypto.PublicKey
+	sig []byte
+	err error
+}
+
+func (s fixedSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func (s fixedSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return s.sig, s.err
+}
+
+func alicePublicDSAKey() *dsa.PublicKey {
+	return &alicePrivateKey.(*DSAPrivateKey).PrivateKey.PublicKey
+}
+
+func Test_SignerPrivateKey_canBeUsedForAnAKEWithAnExternalSigner(t *testing.T) {
+	signer := startExternalSigner(t)
+	defer signer.stop()
+	key, err := NewSignerPrivateKey(signer)
+	assertNil(t, err)
+
+	alice := newStatePeer(key)
+	bob := newStatePeer(bobPrivateKey)
+	exchangeBetween(t, alice, bob, []ValidMessage{alice.QueryMessage()})
+
+	assertTrue(t, alice.IsEncrypted())
+	assertTrue(t, bob.IsEncrypted())
+	assertDeepEquals(t, bob.GetTheirKey().Fingerprint(), alicePrivateKey.PublicKey().Fingerprint())
+	sendBetween(t, alice, bob, "signed elsewhere")
+	sendBetween(t, bob, alice, "nice")
+}
+
+func Test_NewSignerPrivateKey_returnsAnErrorForANonDSASigner(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	_, err := NewSignerPrivateKey(ecKey)
+	_, err2 := NewSignerPrivateKey(fixedSigner{pub: &dsa.PublicKey{}})
+
+	assertEquals(t, err, errSignerNotDSA)
+	assertEquals(t, err2, errSignerNotDSA)
+}
+
+func Test_SignerPrivateKey_SignReturnsTheErrorFromTheSigner(t *testing.T) {
+	key, _ := NewSignerPrivateKey(fixedSigner{pub: alicePublicDSAKey(), err: errors.New("signer has gone away")})
+
+	_, err := key.Sign(rand.Reader, []byte("hello"))
+
+	assertDeepEquals(t, err, errors.New("signer has gone away"))
+}
+
+func Test_SignerPrivateKey_SignReturnsAnErrorForAMalformedSignature(t *testing.T) {
+	tooLarge, _ := asn1.Marshal(dsaSignature{new(big.Int).Lsh(big.NewInt(1), 200), big.NewInt(1)})
+	for _, sig := range [][]byte{nil, {0x30, 0x01}, tooLarge} {
+		key, _ := NewSignerPrivateKey(fixedSigner{pub: alicePublicDSAKey(), sig: sig})
+
+		_, err := key.Sign(rand.Reader, []byte("hello"))
+
+		assertEquals(t, err, errSignerInvalidSignature)
+	}
+}
+
+func Test_SignerPrivateKey_SignReturnsAnErrorForASignatureByAnotherKey(t *testing.T) {
+	r, s, _ := dsa.Sign(rand.Reader, &bobPrivateKey.(*DSAPrivateKey).PrivateKey, []byte("hello"))
+	sig, _ := asn1.Marshal(dsaSignature{r, s})
+	key, _ := NewSignerPrivateKey(fixedSigner{pub: alicePublicDSAKey(), sig: sig})
+
+	_, err := key.Sign(rand.Reader, []byte("hello"))
+
+	assertEquals(t, err, errSignerInvalidSignature)
+}
+
+func Test_SignerPrivateKey_SignReturnsASignatureThatCanBeVerified(t *testing.T) {
+	r, s, _ := dsa.Sign(rand.Reader, &alicePrivateKey.(*DSAPrivateKey).PrivateKey, []byte("hello"))
+	sig, _ := asn1.Marshal(dsaSignature{r, s})
+	key, _ := NewSignerPrivateKey(fixedSigner{pub: alicePublicDSAKey(), sig: sig})
+
+	res, err := key.Sign(rand.Reader, []byte("hello"))
+
+	assertNil(t, err)
+	assertEquals(t, len(res), 40)
+	rest, ok := alicePrivateKey.PublicKey().Verify([]byte("hello"), res)
+	assertTrue(t, ok)
+	assertEquals(t, len(rest), 0)
+}
+
+func Test_SignerPrivateKey_doesntHaveAnyPrivateKeyMaterial(t *testing.T) {
+	key, _ := NewSignerPrivateKey(fixedSigner{pub: alicePublicDSAKey()})
+
+	_, ok := key.Parse(alicePrivateKey.Serialize())
+
+	assertFalse(t, ok)
+	assertNil(t, key.Serialize())
+	assertEquals(t, key.Generate(rand.Reader), errSignerCantGenerate)
+	assertTrue(t, key.IsAvailableForVersion(3))
+	assertDeepEquals(t, key.PublicKey().Fingerprint(), alicePrivateKey.PublicKey().Fingerprint())
+}
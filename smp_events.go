@@ -39,12 +39,21 @@ func (d dynamicSMPEventHandler) HandleSMPEvent(event SMPEvent, pp int, question
 }
 
 func (c *Conversation) smpEvent(e SMPEvent, percent int) {
+	c.smpEventWithError(e, percent, nil)
+}
+
+// smpEventWithError signals an SMP event together with the reason for it, for the events that have one
+func (c *Conversation) smpEventWithError(e SMPEvent, percent int, err error) {
+	c.countSMPEvent(e)
+	c.sendSMPEvent(e, percent, "", err)
 	if c.smpEventHandler != nil {
 		c.smpEventHandler.HandleSMPEvent(e, percent, "")
 	}
 }
 
 func (c *Conversation) smpEventWithQuestion(e SMPEvent, percent int, question string) {
+	c.countSMPEvent(e)
+	c.sendSMPEvent(e, percent, question, nil)
 	if c.smpEventHandler != nil {
 		c.smpEventHandler.HandleSMPEvent(e, percent, question)
 	}
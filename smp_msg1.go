@@ -54,19 +54,19 @@ func (c *Conversation) generateSMP1() (s smp1State, err error) {
 
 func (c *Conversation) verifySMP1(msg smp1Message) error {
 	if !c.version.isGroupElement(msg.g2a) {
-		return newOtrError("g2a is an invalid group element")
+		return newSMPGroupElementError("g2a")
 	}
 
 	if !c.version.isGroupElement(msg.g3a) {
-		return newOtrError("g3a is an invalid group element")
+		return newSMPGroupElementError("g3a")
 	}
 
 	if !verifyZKP(msg.d2, msg.g2a, msg.c2, 1, c.version) {
-		return newOtrError("c2 is not a valid zero knowledge proof")
+		return newSMPProofError("c2")
 	}
 
 	if !verifyZKP(msg.d3, msg.g3a, msg.c3, 2, c.version) {
-		return newOtrError("c3 is not a valid zero knowledge proof")
+		return newSMPProofError("c3")
 	}
 
 	return nil
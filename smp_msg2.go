@@ -80,34 +80,34 @@ func (c *Conversation) generateSMP2(secret *big.Int, s1 smp1Message) (s smp2Stat
 
 func (c *Conversation) verifySMP2(s1 *smp1State, msg smp2Message) error {
 	if !c.version.isGroupElement(msg.g2b) {
-		return newOtrError("g2b is an invalid group element")
+		return newSMPGroupElementError("g2b")
 	}
 
 	if !c.version.isGroupElement(msg.g3b) {
-		return newOtrError("g3b is an invalid group element")
+		return newSMPGroupElementError("g3b")
 	}
 
 	if !c.version.isGroupElement(msg.pb) {
-		return newOtrError("Pb is an invalid group element")
+		return newSMPGroupElementError("Pb")
 	}
 
 	if !c.version.isGroupElement(msg.qb) {
-		return newOtrError("Qb is an invalid group element")
+		return newSMPGroupElementError("Qb")
 	}
 
 	if !verifyZKP(msg.d2, msg.g2b, msg.c2, 3, c.version) {
-		return newOtrError("c2 is not a valid zero knowledge proof")
+		return newSMPProofError("c2")
 	}
 
 	if !verifyZKP(msg.d3, msg.g3b, msg.c3, 4, c.version) {
-		return newOtrError("c3 is not a valid zero knowledge proof")
+		return newSMPProofError("c3")
 	}
 
 	g2 := modExp(msg.g2b, s1.a2)
 	g3 := modExp(msg.g3b, s1.a3)
 
 	if !verifyZKP2(g2, g3, msg.d5, msg.d6, msg.pb, msg.qb, msg.cp, 5, c.version) {
-		return newOtrError("cP is not a valid zero knowledge proof")
+		return newSMPProofError("cP")
 	}
 
 	return nil
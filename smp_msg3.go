@@ -70,25 +70,25 @@ func (c *Conversation) generateSMP3(secret *big.Int, s1 smp1State, m2 smp2Messag
 
 func (c *Conversation) verifySMP3(s2 *smp2State, msg smp3Message) error {
 	if !c.version.isGroupElement(msg.pa) {
-		return newOtrError("Pa is an invalid group element")
+		return newSMPGroupElementError("Pa")
 	}
 
 	if !c.version.isGroupElement(msg.qa) {
-		return newOtrError("Qa is an invalid group element")
+		return newSMPGroupElementError("Qa")
 	}
 
 	if !c.version.isGroupElement(msg.ra) {
-		return newOtrError("Ra is an invalid group element")
+		return newSMPGroupElementError("Ra")
 	}
 
 	if !verifyZKP3(msg.cp, s2.g2, s2.g3, msg.d5, msg.d6, msg.pa, msg.qa, 6, c.version) {
-		return newOtrError("cP is not a valid zero knowledge proof")
+		return newSMPProofError("cP")
 	}
 
 	qaqb := divMod(msg.qa, s2.qb, p)
 
 	if !verifyZKP4(msg.cr, s2.g3a, msg.d7, qaqb, msg.ra, 7, c.version) {
-		return newOtrError("cR is not a valid zero knowledge proof")
+		return newSMPProofError("cR")
 	}
 
 	return nil
@@ -99,7 +99,7 @@ func (c *Conversation) verifySMP3ProtocolSuccess(s2 *smp2State, msg smp3Message)
 
 	rab := modExp(msg.ra, s2.b3)
 	if !eq(rab, papb) {
-		return newOtrError("protocol failed: x != y")
+		return ErrSMPSecretsDiffer
 	}
 
 	return nil
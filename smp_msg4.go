@@ -29,11 +29,11 @@ func (c *Conversation) generateSMP4(secret *big.Int, s2 smp2State, msg3 smp3Mess
 
 func (c *Conversation) verifySMP4(s3 *smp3State, msg smp4Message) error {
 	if !c.version.isGroupElement(msg.rb) {
-		return newOtrError("Rb is an invalid group element")
+		return newSMPGroupElementError("Rb")
 	}
 
 	if !verifyZKP4(msg.cr, s3.g3b, msg.d7, s3.qaqb, msg.rb, 8, c.version) {
-		return newOtrError("cR is not a valid zero knowledge proof")
+		return newSMPProofError("cR")
 	}
 
 	return nil
@@ -60,7 +60,7 @@ func generateSMP4Message(s smp4State, s2 smp2State, msg3 smp3Message, v otrVersi
 func (c *Conversation) verifySMP4ProtocolSuccess(s1 *smp1State, s3 *smp3State, msg smp4Message) error {
 	rab := modExp(msg.rb, s1.a3)
 	if !eq(rab, s3.papb) {
-		return newOtrError("protocol failed: x != y")
+		return ErrSMPSecretsDiffer
 	}
 
 	return nil
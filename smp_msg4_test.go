@@ -60,7 +60,7 @@ func Test_verifySMP4_succeedsForValidZKPS(t *testing.T) {
 func Test_verifySMP4_failsIfRbIsNotInTheGroupForProtocolV3(t *testing.T) {
 	otr := newConversation(otrV3{}, fixtureRand())
 	err := otr.verifySMP4(fixtureSmp3(), smp4Message{rb: big.NewInt(1)})
-	assertDeepEquals(t, err, newOtrError("Rb is an invalid group element"))
+	assertDeepEquals(t, err, newSMPGroupElementError("Rb"))
 }
 
 func Test_verifySMP4_failsIfCrIsNotACorrectZKP(t *testing.T) {
@@ -68,5 +68,5 @@ func Test_verifySMP4_failsIfCrIsNotACorrectZKP(t *testing.T) {
 	m := fixtureMessage4()
 	m.cr = sub(m.cr, big.NewInt(1))
 	err := otr.verifySMP4(fixtureSmp3(), m)
-	assertDeepEquals(t, err, newOtrError("cR is not a valid zero knowledge proof"))
+	assertDeepEquals(t, err, newSMPProofError("cR"))
 }
@@ -41,8 +41,8 @@ func sendSMPAbortAndRestartStateMachine() (smpState, smpMessage, error) {
 	return abortState(nil)
 }
 
-func (c *Conversation) abortStateMachineAndNotifyCheated() (smpState, smpMessage, error) {
-	c.smpEvent(SMPEventCheated, 0)
+func (c *Conversation) abortStateMachineAndNotifyCheated(err error) (smpState, smpMessage, error) {
+	c.smpEventWithError(SMPEventCheated, 0, err)
 	return sendSMPAbortAndRestartStateMachine()
 }
 
@@ -75,7 +75,7 @@ func (c *Conversation) continueSMP(mutualSecret []byte) (*tlv, error) {
 }
 
 func abortStateMachineAndNotifyError(c *Conversation) (smpState, smpMessage, error) {
-	c.smpEvent(SMPEventError, 0)
+	c.smpEventWithError(SMPEventError, 0, ErrUnexpectedSMPMessage)
 	return sendSMPAbortAndRestartStateMachine()
 }
 
@@ -84,7 +84,7 @@ func (smpStateBase) receiveMessage1(c *Conversation, m smp1Message) (smpState, s
 }
 
 func (smpStateBase) continueMessage1(c *Conversation, mutualSecret []byte) (smpState, smpMessage, error) {
-	return abortState(errNotWaitingForSMPSecret)
+	return abortState(ErrNotWaitingForSMPSecret)
 }
 
 func (smpStateBase) receiveMessage2(c *Conversation, m smp2Message) (smpState, smpMessage, error) {
@@ -102,7 +102,7 @@ func (smpStateBase) receiveMessage4(c *Conversation, m smp4Message) (smpState, s
 func (smpStateExpect1) receiveMessage1(c *Conversation, m smp1Message) (smpState, smpMessage, error) {
 	err := c.verifySMP1(m)
 	if err != nil {
-		return c.abortStateMachineAndNotifyCheated()
+		return c.abortStateMachineAndNotifyCheated(err)
 	}
 
 	if m.hasQuestion {
@@ -124,7 +124,7 @@ func (s smpStateWaitingForSecret) continueMessage1(c *Conversation, mutualSecret
 	c.smp.secret = generateSMPSecret(c.theirKey.Fingerprint(), c.ourCurrentKey.PublicKey().Fingerprint(), c.ssid[:], mutualSecret, c.version)
 	s2, err := c.generateSMP2(c.smp.secret, s.msg)
 	if err != nil {
-		return c.abortStateMachineAndNotifyCheated()
+		return c.abortStateMachineAndNotifyCheated(err)
 	}
 
 	c.smp.s2 = &s2
@@ -135,12 +135,12 @@ func (s smpStateWaitingForSecret) continueMessage1(c *Conversation, mutualSecret
 func (smpStateExpect2) receiveMessage2(c *Conversation, m smp2Message) (smpState, smpMessage, error) {
 	err := c.verifySMP2(c.smp.s1, m)
 	if err != nil {
-		return c.abortStateMachineAndNotifyCheated()
+		return c.abortStateMachineAndNotifyCheated(err)
 	}
 
 	s3, err := c.generateSMP3(c.smp.secret, *c.smp.s1, m)
 	if err != nil {
-		return c.abortStateMachineAndNotifyCheated()
+		return c.abortStateMachineAndNotifyCheated(err)
 	}
 
 	c.smpEvent(SMPEventInProgress, 60)
@@ -153,19 +153,19 @@ func (smpStateExpect2) receiveMessage2(c *Conversation, m smp2Message) (smpState
 func (smpStateExpect3) receiveMessage3(c *Conversation, m smp3Message) (smpState, smpMessage, error) {
 	err := c.verifySMP3(c.smp.s2, m)
 	if err != nil {
-		return c.abortStateMachineAndNotifyCheated()
+		return c.abortStateMachineAndNotifyCheated(err)
 	}
 
 	err = c.verifySMP3ProtocolSuccess(c.smp.s2, m)
 	if err != nil {
-		c.smpEvent(SMPEventFailure, 100)
+		c.smpEventWithError(SMPEventFailure, 100, err)
 		return sendSMPAbortAndRestartStateMachine()
 	}
 	c.smpEvent(SMPEventSuccess, 100)
 
 	ret, err := c.generateSMP4(c.smp.secret, *c.smp.s2, m)
 	if err != nil {
-		return c.abortStateMachineAndNotifyCheated()
+		return c.abortStateMachineAndNotifyCheated(err)
 	}
 
 	c.smp.wipe()
@@ -175,12 +175,12 @@ func (smpStateExpect3) receiveMessage3(c *Conversation, m smp3Message) (smpState
 func (smpStateExpect4) receiveMessage4(c *Conversation, m smp4Message) (smpState, smpMessage, error) {
 	err := c.verifySMP4(c.smp.s3, m)
 	if err != nil {
-		return c.abortStateMachineAndNotifyCheated()
+		return c.abortStateMachineAndNotifyCheated(err)
 	}
 
 	err = c.verifySMP4ProtocolSuccess(c.smp.s1, c.smp.s3, m)
 	if err != nil {
-		c.smpEvent(SMPEventFailure, 100)
+		c.smpEventWithError(SMPEventFailure, 100, err)
 		return sendSMPAbortAndRestartStateMachine()
 	}
 	c.smpEvent(SMPEventSuccess, 100)
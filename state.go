@@ -0,0 +1,328 @@
+package otr3
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+)
+
+const stateFormatVersion = uint16(1)
+
+var errStateNotEncrypted = newOtrError("only the state of an encrypted conversation can be marshalled")
+var errStateInvalid = newOtrError("invalid conversation state")
+var errStateUnauthentic = newOtrError("conversation state could not be authenticated")
+var errStateUnknownOurKey = newOtrError("conversation state uses a key we don't have")
+
+// MarshalState serializes the encrypted session of this conversation - their key, the SSID, instance tags, the
+// DH keys, counters and MAC key history - so that it can be resumed with UnmarshalState after a restart, without
+// a new AKE. The state is encrypted and authenticated using the given key, which can be of any length but should
+// have at least 256 bits of entropy. Our long-term private keys are not part of the state.
+//
+// Since OTR uses counter mode encryption, the state has to be marshalled again after every message sent or received,
+// and an older state must never be restored. Resuming an old state can reuse counters and reveal messages.
+// Ongoing SMP and AKE runs, fragments and pending messages are not saved.
+func (c *Conversation) MarshalState(key []byte) ([]byte, error) {
+	if c.msgState != encrypted {
+		return nil, errStateNotEncrypted
+	}
+
+	plain := c.serializeState()
+	defer wipeBytes(plain)
+
+	encKey, macKey := stateKeys(key)
+	defer wipeBytes(encKey)
+	defer wipeBytes(macKey)
+
+	result := appendShort(nil, stateFormatVersion)
+	iv := make([]byte, aes.BlockSize)
+	if err := c.randomInto(iv); err != nil {
+		return nil, err
+	}
+	result = append(result, iv...)
+
+	ciphertext := make([]byte, len(plain))
+	if err := counterEncipher(encKey, iv, plain, ciphertext); err != nil {
+		return nil, err
+	}
+	result = appendData(result, ciphertext)
+
+	return append(result, stateMAC(macKey, result)...), nil
+}
+
+// UnmarshalState resumes an encrypted session saved with MarshalState, using the same key. Our keys must
+// be set before calling this, and the key used in the saved session has to be one of them.
+// If an error is returned, the conversation is left unchanged.
+func (c *Conversation) UnmarshalState(key, state []byte) error {
+	encKey, macKey := stateKeys(key)
+	defer wipeBytes(encKey)
+	defer wipeBytes(macKey)
+
+	if len(state) < sha256.Size {
+		return errStateInvalid
+	}
+
+	signed, mac := state[:len(state)-sha256.Size], state[len(state)-sha256.Size:]
+	if !hmac.Equal(mac, stateMAC(macKey, signed)) {
+		return errStateUnauthentic
+	}
+
+	index, formatVersion, ok := extractShort(signed)
+	if !ok || formatVersion != stateFormatVersion || len(index) < aes.BlockSize {
+		return errStateInvalid
+	}
+	iv := index[:aes.BlockSize]
+	index, ciphertext, ok := extractData(index[aes.BlockSize:])
+	if !ok || len(index) != 0 {
+		return errStateInvalid
+	}
+
+	plain := make([]byte, len(ciphertext))
+	defer wipeBytes(plain)
+	if err := counterEncipher(encKey, iv, ciphertext, plain); err != nil {
+		return err
+	}
+
+	return c.deserializeState(plain)
+}
+
+func stateKeys(key []byte) (encKey, macKey []byte) {
+	e := sha256.Sum256(append([]byte{0x01}, key...))
+	m := sha256.Sum256(append([]byte{0x02}, key...))
+	return e[:], m[:]
+}
+
+func stateMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (c *Conversation) serializeState() []byte {
+	out := appendShort(nil, c.version.protocolVersion())
+	out = appendWord(out, c.ourInstanceTag)
+	out = appendWord(out, c.theirInstanceTag)
+	out = append(out, c.ssid[:]...)
+	out = appendData(out, c.ourCurrentKey.PublicKey().serialize())
+	out = appendData(out, c.theirKey.serialize())
+	return c.keys.serialize(out)
+}
+
+func (c *Conversation) deserializeState(in []byte) error {
+	var ok bool
+	var protocolVersion uint16
+	var ourInstanceTag, theirInstanceTag uint32
+	var ourPublicKey, theirPublicKey []byte
+
+	if in, protocolVersion, ok = extractShort(in); !ok {
+		return errStateInvalid
+	}
+	if in, ourInstanceTag, ok = extractWord(in); !ok {
+		return errStateInvalid
+	}
+	if in, theirInstanceTag, ok = extractWord(in); !ok {
+		return errStateInvalid
+	}
+	if len(in) < len(c.ssid) {
+		return errStateInvalid
+	}
+	var ssid [8]byte
+	copy(ssid[:], in)
+	in = in[len(ssid):]
+	if in, ourPublicKey, ok = extractData(in); !ok {
+		return errStateInvalid
+	}
+	if in, theirPublicKey, ok = extractData(in); !ok {
+		return errStateInvalid
+	}
+
+	var version otrVersion
+	switch protocolVersion {
+	case 2:
+		version = otrV2{}
+	case 3:
+		version = otrV3{}
+	default:
+		return errStateInvalid
+	}
+
+	_, ok, ourKey := ParsePublicKey(ourPublicKey)
+	if !ok {
+		return errStateInvalid
+	}
+	_, ok, theirKey := ParsePublicKey(theirPublicKey)
+	if !ok {
+		return errStateInvalid
+	}
+
+	var ourCurrentKey PrivateKey
+	for _, k := range c.ourKeys {
+		if bytes.Equal(k.PublicKey().Fingerprint(), ourKey.Fingerprint()) {
+			ourCurrentKey = k
+		}
+	}
+	if ourCurrentKey == nil {
+		return errStateUnknownOurKey
+	}
+
+	keys := keyManagementContext{}
+	if in, ok = keys.deserialize(in); !ok || len(in) != 0 {
+		return errStateInvalid
+	}
+
+	c.version = version
+	c.ourInstanceTag = ourInstanceTag
+	c.theirInstanceTag = theirInstanceTag
+	c.ssid = ssid
+	c.ourCurrentKey = ourCurrentKey
+	c.theirKey = theirKey
+	c.keys = keys
+	c.ake = nil
+	c.setMsgState(encrypted)
+
+	return nil
+}
+
+func (k *keyManagementContext) serialize(out []byte) []byte {
+	out = appendWord(out, k.ourKeyID)
+	out = appendWord(out, k.theirKeyID)
+	out = appendOptionalMPIs(out,
+		k.ourCurrentDHKeys.priv, k.ourCurrentDHKeys.pub,
+		k.ourPreviousDHKeys.priv, k.ourPreviousDHKeys.pub,
+		k.theirCurrentDHPubKey, k.theirPreviousDHPubKey)
+
+	out = appendWord(out, uint32(len(k.counterHistory.counters)))
+	for _, ctr := range k.counterHistory.counters {
+		out = appendWord(out, ctr.ourKeyID)
+		out = appendWord(out, ctr.theirKeyID)
+		out = appendLong(out, ctr.ourCounter)
+		out = appendLong(out, ctr.theirCounter)
+	}
+
+	out = appendWord(out, uint32(len(k.macKeyHistory.items)))
+	for _, item := range k.macKeyHistory.items {
+		out = appendWord(out, item.ourKeyID)
+		out = appendWord(out, item.theirKeyID)
+		out = appendData(out, item.receivingKey)
+	}
+
+	out = appendWord(out, uint32(len(k.oldMACKeys)))
+	for _, key := range k.oldMACKeys {
+		out = appendData(out, key)
+	}
+
+	return out
+}
+
+func (k *keyManagementContext) deserialize(in []byte) ([]byte, bool) {
+	var ok bool
+	var count uint32
+	mpis := make([]*big.Int, 6)
+
+	if in, k.ourKeyID, ok = extractWord(in); !ok {
+		return nil, false
+	}
+	if in, k.theirKeyID, ok = extractWord(in); !ok {
+		return nil, false
+	}
+	for i := range mpis {
+		if in, mpis[i], ok = extractOptionalMPI(in); !ok {
+			return nil, false
+		}
+	}
+	k.ourCurrentDHKeys = dhKeyPair{priv: mpis[0], pub: mpis[1]}
+	k.ourPreviousDHKeys = dhKeyPair{priv: mpis[2], pub: mpis[3]}
+	k.theirCurrentDHPubKey, k.theirPreviousDHPubKey = mpis[4], mpis[5]
+
+	if in, count, ok = extractWord(in); !ok {
+		return nil, false
+	}
+	for i := uint32(0); i < count; i++ {
+		ctr := &keyPairCounter{}
+		if in, ctr.ourKeyID, ok = extractWord(in); !ok {
+			return nil, false
+		}
+		if in, ctr.theirKeyID, ok = extractWord(in); !ok {
+			return nil, false
+		}
+		if in, ctr.ourCounter, ok = extractLong(in); !ok {
+			return nil, false
+		}
+		if in, ctr.theirCounter, ok = extractLong(in); !ok {
+			return nil, false
+		}
+		k.counterHistory.counters = append(k.counterHistory.counters, ctr)
+	}
+
+	if in, count, ok = extractWord(in); !ok {
+		return nil, false
+	}
+	for i := uint32(0); i < count; i++ {
+		item := macKeyUsage{}
+		var key []byte
+		if in, item.ourKeyID, ok = extractWord(in); !ok {
+			return nil, false
+		}
+		if in, item.theirKeyID, ok = extractWord(in); !ok {
+			return nil, false
+		}
+		if in, key, ok = extractData(in); !ok {
+			return nil, false
+		}
+		item.receivingKey = makeCopy(key)
+		k.macKeyHistory.items = append(k.macKeyHistory.items, item)
+	}
+
+	if in, count, ok = extractWord(in); !ok {
+		return nil, false
+	}
+	for i := uint32(0); i < count; i++ {
+		var key []byte
+		if in, key, ok = extractData(in); !ok {
+			return nil, false
+		}
+		k.oldMACKeys = append(k.oldMACKeys, makeCopy(key))
+	}
+
+	return in, true
+}
+
+func appendLong(l []byte, r uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], r)
+	return append(l, b[:]...)
+}
+
+func extractLong(d []byte) ([]byte, uint64, bool) {
+	if len(d) < 8 {
+		return nil, 0, false
+	}
+	return d[8:], binary.BigEndian.Uint64(d), true
+}
+
+// appendOptionalMPIs serializes MPIs that might not have been set yet. A missing MPI is written with a zero flag byte
+func appendOptionalMPIs(l []byte, r ...*big.Int) []byte {
+	for _, mpi := range r {
+		if mpi == nil {
+			l = append(l, 0x00)
+		} else {
+			l = appendMPI(append(l, 0x01), mpi)
+		}
+	}
+	return l
+}
+
+func extractOptionalMPI(d []byte) ([]byte, *big.Int, bool) {
+	if len(d) < 1 {
+		return nil, nil, false
+	}
+	switch d[0] {
+	case 0x00:
+		return d[1:], nil, true
+	case 0x01:
+		return extractMPI(d[1:])
+	}
+	return nil, nil, false
+}
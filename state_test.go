@@ -0,0 +1,166 @@
+package otr3
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+var fixtureStateKey = []byte("a very secret key used for the state")
+
+func newStatePeer(key PrivateKey) *Conversation {
+	c := &Conversation{Rand: rand.Reader}
+	c.Policies = policies(allowV2 | allowV3)
+	c.SetOurKeys([]PrivateKey{key})
+	return c
+}
+
+func encryptedStatePeers(t *testing.T) (alice, bob *Conversation) {
+	alice = newStatePeer(alicePrivateKey)
+	bob = newStatePeer(bobPrivateKey)
+	exchangeBetween(t, alice, bob, []ValidMessage{alice.QueryMessage()})
+	assertTrue(t, alice.IsEncrypted())
+	assertTrue(t, bob.IsEncrypted())
+	return
+}
+
+func sendBetween(t *testing.T, from, to *Conversation, msg string) {
+	toSend, err := from.Send(ValidMessage(msg))
+	assertNil(t, err)
+	plain, _, err := to.Receive(toSend[0])
+	assertNil(t, err)
+	assertDeepEquals(t, plain, MessagePlaintext(msg))
+}
+
+func Test_UnmarshalState_resumesAnEncryptedConversation(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hello")
+	sendBetween(t, bob, alice, "hi there")
+	sendBetween(t, alice, bob, "how are you?")
+
+	state, err := alice.MarshalState(fixtureStateKey)
+	assertNil(t, err)
+
+	resumed := newStatePeer(alicePrivateKey)
+	err = resumed.UnmarshalState(fixtureStateKey, state)
+	assertNil(t, err)
+
+	assertTrue(t, resumed.IsEncrypted())
+	assertEquals(t, resumed.ourInstanceTag, alice.ourInstanceTag)
+	assertEquals(t, resumed.theirInstanceTag, alice.theirInstanceTag)
+	assertEquals(t, resumed.GetSSID(), alice.GetSSID())
+	assertDeepEquals(t, resumed.GetTheirKey().Fingerprint(), bobPrivateKey.PublicKey().Fingerprint())
+	assertDeepEquals(t, resumed.keys.serialize(nil), alice.keys.serialize(nil))
+
+	sendBetween(t, bob, resumed, "are you still there?")
+	sendBetween(t, resumed, bob, "yes")
+	sendBetween(t, resumed, bob, "still here")
+	sendBetween(t, bob, resumed, "good")
+}
+
+func Test_UnmarshalState_keepsTheCountersSoReplayedMessagesAreRejected(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	toSend, _ := bob.Send(ValidMessage("hello"))
+	alice.Receive(toSend[0])
+
+	state, _ := alice.MarshalState(fixtureStateKey)
+	resumed := newStatePeer(alicePrivateKey)
+	resumed.UnmarshalState(fixtureStateKey, state)
+
+	_, _, err := resumed.Receive(toSend[0])
+	assertEquals(t, err, newOtrConflictError("counter regressed"))
+}
+
+func Test_MarshalState_returnsAnErrorIfTheConversationIsNotEncrypted(t *testing.T) {
+	c := newStatePeer(alicePrivateKey)
+
+	_, err := c.MarshalState(fixtureStateKey)
+
+	assertEquals(t, err, errStateNotEncrypted)
+}
+
+func Test_MarshalState_returnsAnErrorIfRandomnessFails(t *testing.T) {
+	alice, _ := encryptedStatePeers(t)
+	alice.Rand = fixedRand([]string{"0102"})
+
+	_, err := alice.MarshalState(fixtureStateKey)
+
+	assertEquals(t, err, errShortRandomRead)
+}
+
+func Test_UnmarshalState_returnsAnErrorForTheWrongKey(t *testing.T) {
+	alice, _ := encryptedStatePeers(t)
+	state, _ := alice.MarshalState(fixtureStateKey)
+	resumed := newStatePeer(alicePrivateKey)
+
+	err := resumed.UnmarshalState([]byte("another key"), state)
+
+	assertEquals(t, err, errStateUnauthentic)
+	assertFalse(t, resumed.IsEncrypted())
+}
+
+func Test_UnmarshalState_returnsAnErrorIfTheStateHasBeenTamperedWith(t *testing.T) {
+	alice, _ := encryptedStatePeers(t)
+	state, _ := alice.MarshalState(fixtureStateKey)
+	state[30] ^= 0x01
+	resumed := newStatePeer(alicePrivateKey)
+
+	err := resumed.UnmarshalState(fixtureStateKey, state)
+
+	assertEquals(t, err, errStateUnauthentic)
+}
+
+func Test_UnmarshalState_returnsAnErrorForATooShortState(t *testing.T) {
+	err := newStatePeer(alicePrivateKey).UnmarshalState(fixtureStateKey, []byte{0x00, 0x01})
+
+	assertEquals(t, err, errStateInvalid)
+}
+
+func Test_UnmarshalState_returnsAnErrorForAnUnknownFormatVersion(t *testing.T) {
+	_, macKey := stateKeys(fixtureStateKey)
+	state := appendShort(nil, 0x0002)
+	state = append(state, make([]byte, 16)...)
+	state = appendData(state, []byte{0x01, 0x02})
+	state = append(state, stateMAC(macKey, state)...)
+
+	err := newStatePeer(alicePrivateKey).UnmarshalState(fixtureStateKey, state)
+
+	assertEquals(t, err, errStateInvalid)
+}
+
+func Test_UnmarshalState_returnsAnErrorIfWeDontHaveTheKeyUsedInTheState(t *testing.T) {
+	alice, _ := encryptedStatePeers(t)
+	state, _ := alice.MarshalState(fixtureStateKey)
+	resumed := newStatePeer(bobPrivateKey)
+
+	err := resumed.UnmarshalState(fixtureStateKey, state)
+
+	assertEquals(t, err, errStateUnknownOurKey)
+	assertFalse(t, resumed.IsEncrypted())
+}
+
+func Test_keyManagementContext_serializeCanBeReadBackWithMissingKeys(t *testing.T) {
+	k := keyManagementContext{ourKeyID: 2, theirKeyID: 3}
+	k.ourCurrentDHKeys = dhKeyPair{priv: fixedX(), pub: fixedGX()}
+	k.theirCurrentDHPubKey = fixedGY()
+	k.counterHistory.findCounterFor(1, 2).ourCounter = 0x100000001
+	k.macKeyHistory.addKeys(1, 2, macKey{0x01, 0x02})
+	k.oldMACKeys = []macKey{{0x03, 0x04}}
+
+	res := keyManagementContext{}
+	rest, ok := res.deserialize(k.serialize(nil))
+
+	assertTrue(t, ok)
+	assertEquals(t, len(rest), 0)
+	assertDeepEquals(t, res, k)
+}
+
+func Test_keyManagementContext_deserializeReturnsNotOKForTruncatedData(t *testing.T) {
+	k := keyManagementContext{ourKeyID: 2, theirKeyID: 3}
+	k.macKeyHistory.addKeys(1, 2, macKey{0x01, 0x02})
+	data := k.serialize(nil)
+
+	for i := 0; i < len(data); i++ {
+		_, ok := (&keyManagementContext{}).deserialize(data[:i])
+		assertFalse(t, ok)
+	}
+}
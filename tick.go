@@ -0,0 +1,22 @@
+package otr3
+
+import "time"
+
+// Tick should be called regularly - for example every few seconds - with the current time. It takes care of the
+// timed parts of the protocol that would otherwise only happen when a message is received: it sends a heartbeat
+// if we have received messages that haven't been acknowledged within the heartbeat interval, and forgets
+// queued messages that haven't been resent within the resend interval. It returns the messages to send to the peer.
+func (c *Conversation) Tick(now time.Time) ([]ValidMessage, error) {
+	c.expireResends(now)
+
+	if c.msgState != encrypted || !c.heartbeat.unacknowledged {
+		return c.withInjections(nil, nil)
+	}
+
+	toSend, err := c.heartbeatAt(now)
+	if err != nil || toSend == nil {
+		return c.withInjections(nil, err)
+	}
+
+	return c.withInjections(c.fragEncode(toSend), nil)
+}
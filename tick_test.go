@@ -0,0 +1,131 @@
+package otr3
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Tick_sendsAHeartbeatForUnacknowledgedMessagesAfterTheHeartbeatInterval(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hi")
+	sendBetween(t, bob, alice, "hello")
+	now := time.Now()
+
+	toSend, err := alice.Tick(now.Add(30 * time.Second))
+	assertNil(t, err)
+	assertNil(t, toSend)
+
+	alice.expectMessageEvent(t, func() {
+		toSend, err = alice.Tick(now.Add(61 * time.Second))
+	}, MessageEventLogHeartbeatSent, nil, nil)
+	assertNil(t, err)
+	assertEquals(t, len(toSend), 1)
+	assertEquals(t, alice.heartbeat.lastSent, now.Add(61*time.Second))
+
+	plain, _, err := bob.Receive(toSend[0])
+	assertNil(t, err)
+	assertNil(t, plain)
+}
+
+func Test_Tick_sendsOnlyOneHeartbeat(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hi")
+	sendBetween(t, bob, alice, "hello")
+	now := time.Now()
+
+	toSend, _ := alice.Tick(now.Add(61 * time.Second))
+	assertEquals(t, len(toSend), 1)
+
+	toSend, _ = alice.Tick(now.Add(200 * time.Second))
+	assertNil(t, toSend)
+}
+
+func Test_Tick_doesntSendAHeartbeatIfWeHaventReceivedAnything(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hello")
+
+	toSend, err := alice.Tick(time.Now().Add(120 * time.Second))
+
+	assertNil(t, err)
+	assertNil(t, toSend)
+}
+
+func Test_Tick_doesntSendAHeartbeatIfWeHaveAnsweredAlready(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hi")
+	sendBetween(t, bob, alice, "hello")
+	sendBetween(t, alice, bob, "hi")
+
+	toSend, err := alice.Tick(time.Now().Add(120 * time.Second))
+
+	assertNil(t, err)
+	assertNil(t, toSend)
+}
+
+func Test_Tick_doesntSendAHeartbeatIfTheConversationIsNotEncrypted(t *testing.T) {
+	c := newStatePeer(alicePrivateKey)
+	c.heartbeat.unacknowledged = true
+
+	toSend, err := c.Tick(time.Now().Add(120 * time.Second))
+
+	assertNil(t, err)
+	assertNil(t, toSend)
+}
+
+func Test_Tick_usesTheConfiguredHeartbeatInterval(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	alice.SetHeartbeatInterval(5 * time.Second)
+	sendBetween(t, alice, bob, "hi")
+	sendBetween(t, bob, alice, "hello")
+
+	toSend, _ := alice.Tick(time.Now().Add(6 * time.Second))
+
+	assertEquals(t, len(toSend), 1)
+}
+
+func Test_Tick_returnsAnErrorIfTheHeartbeatCantBeCreated(t *testing.T) {
+	alice, bob := encryptedStatePeers(t)
+	sendBetween(t, alice, bob, "hi")
+	sendBetween(t, bob, alice, "hello")
+	alice.keys.ourKeyID = 0
+
+	_, err := alice.Tick(time.Now().Add(61 * time.Second))
+
+	assertNotNil(t, err)
+}
+
+func Test_Tick_forgetsQueuedMessagesAfterTheResendInterval(t *testing.T) {
+	c := &Conversation{}
+	fixtureCorrectResend(c)
+	now := c.heartbeat.lastSent
+
+	c.Tick(now.Add(59 * time.Second))
+	assertEquals(t, len(c.resend.pending()), 1)
+
+	c.Tick(now.Add(60 * time.Second))
+	assertEquals(t, len(c.resend.pending()), 0)
+}
+
+func Test_Tick_usesTheConfiguredResendInterval(t *testing.T) {
+	c := &Conversation{}
+	fixtureCorrectResend(c)
+	c.SetResendInterval(10 * time.Second)
+	now := c.heartbeat.lastSent
+
+	c.Tick(now.Add(5 * time.Second))
+	assertEquals(t, len(c.resend.pending()), 1)
+
+	c.Tick(now.Add(11 * time.Second))
+	assertEquals(t, len(c.resend.pending()), 0)
+}
+
+func Test_shouldRetransmit_usesTheConfiguredResendInterval(t *testing.T) {
+	c := &Conversation{}
+	fixtureCorrectResend(c)
+	c.heartbeat.lastSent = time.Now().Add(-90 * time.Second)
+
+	assertFalse(t, c.shouldRetransmit())
+
+	c.SetResendInterval(120 * time.Second)
+	assertTrue(t, c.shouldRetransmit())
+}
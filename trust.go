@@ -0,0 +1,86 @@
+package otr3
+
+// TrustLevel describes how much we trust the long-term key of the peer
+type TrustLevel int
+
+const (
+	// TrustLevelUnverified means the key of the peer has not been verified
+	TrustLevelUnverified TrustLevel = iota
+	// TrustLevelVerified means the key of the peer has been verified
+	TrustLevelVerified
+	// TrustLevelNewFingerprint means the key of the peer has not been verified, but another key for the same peer has been
+	TrustLevelNewFingerprint
+)
+
+// TrustResolver decides the trust level of the key used by the peer. A TrustResolver is used for one specific peer.
+type TrustResolver interface {
+	// ResolveTrust returns the trust level of the given key of the peer
+	ResolveTrust(theirKey PublicKey) TrustLevel
+}
+
+type dynamicTrustResolver struct {
+	r func(theirKey PublicKey) TrustLevel
+}
+
+func (d dynamicTrustResolver) ResolveTrust(theirKey PublicKey) TrustLevel {
+	return d.r(theirKey)
+}
+
+// SetTrustResolver assigns the resolver used to decide which security events to signal when going secure.
+// If no resolver is set, GoneSecure and StillSecure will be signalled regardless of trust.
+func (c *Conversation) SetTrustResolver(resolver TrustResolver) {
+	c.trustResolver = resolver
+}
+
+func (c *Conversation) resolveTrust() TrustLevel {
+	if c.theirKey == nil {
+		return TrustLevelUnverified
+	}
+	return c.trustResolver.ResolveTrust(c.theirKey)
+}
+
+func (c *Conversation) signalSecure(wasSecure bool) {
+	if c.trustResolver == nil {
+		c.signalSecurityEventIf(!wasSecure, GoneSecure)
+		c.signalSecurityEventIf(wasSecure, StillSecure)
+		return
+	}
+
+	trust := c.resolveTrust()
+	c.signalSecurityEventIf(trust == TrustLevelNewFingerprint, NewFingerprintForVerifiedPeer)
+
+	switch {
+	case trust == TrustLevelVerified && wasSecure:
+		c.securityEvent(StillSecureVerified)
+	case trust == TrustLevelVerified:
+		c.securityEvent(GoneSecureVerified)
+	case wasSecure:
+		c.securityEvent(StillSecureUnverified)
+	default:
+		c.securityEvent(GoneSecureUnverified)
+	}
+}
+
+type fingerprintStoreTrustResolver struct {
+	s                       *FingerprintStore
+	account, protocol, peer string
+}
+
+// TrustResolverFor returns a TrustResolver that looks up the keys of the given peer in this store
+func (s *FingerprintStore) TrustResolverFor(account, protocol, peer string) TrustResolver {
+	return fingerprintStoreTrustResolver{s, account, protocol, peer}
+}
+
+func (r fingerprintStoreTrustResolver) ResolveTrust(theirKey PublicKey) TrustLevel {
+	if r.s.IsVerified(r.account, r.protocol, r.peer, theirKey.Fingerprint()) {
+		return TrustLevelVerified
+	}
+
+	for _, e := range r.s.FingerprintsFor(r.account, r.protocol, r.peer) {
+		if e.IsVerified() {
+			return TrustLevelNewFingerprint
+		}
+	}
+
+	return TrustLevelUnverified
+}
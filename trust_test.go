@@ -0,0 +1,96 @@
+package otr3
+
+import "testing"
+
+func conversationGoingSecureWithTrust(trust TrustLevel, msgState msgState) *Conversation {
+	c := bobContextAfterAKE()
+	c.ourCurrentKey = bobPrivateKey
+	c.theirKey = alicePrivateKey.PublicKey()
+	c.msgState = msgState
+	c.SetTrustResolver(dynamicTrustResolver{func(PublicKey) TrustLevel {
+		return trust
+	}})
+	return c
+}
+
+func collectSecurityEvents(c *Conversation, f func()) []SecurityEvent {
+	var events []SecurityEvent
+	c.securityEventHandler = dynamicSecurityEventHandler{func(event SecurityEvent) {
+		events = append(events, event)
+	}}
+	f()
+	return events
+}
+
+func Test_akeHasFinished_signalsGoneSecureVerifiedIfTheKeyIsVerified(t *testing.T) {
+	c := conversationGoingSecureWithTrust(TrustLevelVerified, plainText)
+
+	c.expectSecurityEvent(t, func() {
+		c.akeHasFinished()
+	}, GoneSecureVerified)
+}
+
+func Test_akeHasFinished_signalsGoneSecureUnverifiedIfTheKeyIsNotVerified(t *testing.T) {
+	c := conversationGoingSecureWithTrust(TrustLevelUnverified, plainText)
+
+	c.expectSecurityEvent(t, func() {
+		c.akeHasFinished()
+	}, GoneSecureUnverified)
+}
+
+func Test_akeHasFinished_signalsStillSecureVerifiedIfTheKeyIsVerified(t *testing.T) {
+	c := conversationGoingSecureWithTrust(TrustLevelVerified, encrypted)
+
+	c.expectSecurityEvent(t, func() {
+		c.akeHasFinished()
+	}, StillSecureVerified)
+}
+
+func Test_akeHasFinished_signalsStillSecureUnverifiedIfTheKeyIsNotVerified(t *testing.T) {
+	c := conversationGoingSecureWithTrust(TrustLevelUnverified, encrypted)
+
+	c.expectSecurityEvent(t, func() {
+		c.akeHasFinished()
+	}, StillSecureUnverified)
+}
+
+func Test_akeHasFinished_signalsANewFingerprintForAVerifiedPeer(t *testing.T) {
+	c := conversationGoingSecureWithTrust(TrustLevelNewFingerprint, plainText)
+
+	events := collectSecurityEvents(c, func() {
+		c.akeHasFinished()
+	})
+
+	assertDeepEquals(t, events, []SecurityEvent{NewFingerprintForVerifiedPeer, GoneSecureUnverified})
+}
+
+func Test_akeHasFinished_givesTheKeyOfThePeerToTheTrustResolver(t *testing.T) {
+	c := conversationGoingSecureWithTrust(TrustLevelUnverified, plainText)
+	var resolvedKey PublicKey
+	c.SetTrustResolver(dynamicTrustResolver{func(k PublicKey) TrustLevel {
+		resolvedKey = k
+		return TrustLevelUnverified
+	}})
+
+	c.akeHasFinished()
+
+	assertEquals(t, resolvedKey, c.theirKey)
+}
+
+func Test_FingerprintStore_TrustResolverForResolvesTheTrustOfAKey(t *testing.T) {
+	s := &FingerprintStore{}
+	alice := alicePrivateKey.PublicKey()
+	bob := bobPrivateKey.PublicKey()
+	r := s.TrustResolverFor("me", "xmpp", "peer")
+
+	assertEquals(t, r.ResolveTrust(alice), TrustLevelUnverified)
+
+	s.Add("me", "xmpp", "peer", alice.Fingerprint())
+	assertEquals(t, r.ResolveTrust(alice), TrustLevelUnverified)
+
+	s.SetTrust("me", "xmpp", "peer", alice.Fingerprint(), TrustSMP)
+	assertEquals(t, r.ResolveTrust(alice), TrustLevelVerified)
+	assertEquals(t, r.ResolveTrust(bob), TrustLevelNewFingerprint)
+
+	assertEquals(t, s.TrustResolverFor("me", "xmpp", "other").ResolveTrust(bob), TrustLevelUnverified)
+}
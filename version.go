@@ -37,10 +37,10 @@ func newOtrVersion(v uint16, p policies) (version otrVersion, err error) {
 		version = otrV3{}
 		toCheck = allowV3
 	default:
-		return nil, errUnsupportedOTRVersion
+		return nil, ErrUnsupportedVersion
 	}
 	if !p.has(toCheck) {
-		return nil, errInvalidVersion
+		return nil, ErrInvalidVersion
 	}
 	return
 }
@@ -70,7 +70,7 @@ func (c *Conversation) checkVersion(message []byte) (err error) {
 	}
 
 	if c.version.protocolVersion() != messageVersion {
-		return errWrongProtocolVersion
+		return ErrWrongProtocolVersion
 	}
 
 	return nil
@@ -90,7 +90,7 @@ func (c *Conversation) commitToVersionFrom(versions int) error {
 	case c.Policies.has(allowV2) && versions&(1<<2) > 0:
 		version = otrV2{}
 	default:
-		return errUnsupportedOTRVersion
+		return ErrUnsupportedVersion
 	}
 
 	c.version = version
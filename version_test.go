@@ -10,12 +10,12 @@ func Test_newOtrVersion_returnsTheCorrectOTRVersionForAValidVersionNumber(t *tes
 
 func Test_newOtrVersion_returnsUnsupportedVersionErrorIfGivenAWrongVersion(t *testing.T) {
 	_, err := newOtrVersion(4, policies(allowV3))
-	assertEquals(t, err, errUnsupportedOTRVersion)
+	assertEquals(t, err, ErrUnsupportedVersion)
 }
 
 func Test_newOtrVersion_returnsAnErrorIfGivenAVersionThatIsntAllowedByPolicy(t *testing.T) {
 	_, err := newOtrVersion(3, policies(allowV2))
-	assertEquals(t, err, errInvalidVersion)
+	assertEquals(t, err, ErrInvalidVersion)
 }
 
 func Test_checkVersion_returnsErrorIfTheMessageIsCorrupt(t *testing.T) {
@@ -44,7 +44,7 @@ func Test_checkVersion_returnsTheErrorFromNewOtrVersion(t *testing.T) {
 	c := &Conversation{Policies: policies(allowV2)}
 	c.ourKeys = []PrivateKey{alicePrivateKey}
 	e := c.checkVersion([]byte{0x00, 0x03})
-	assertEquals(t, e, errUnsupportedOTRVersion)
+	assertEquals(t, e, ErrUnsupportedVersion)
 }
 
 func Test_checkVersion_doesNotSetConversationVersionIfOneIsAlreadySet(t *testing.T) {
@@ -58,5 +58,5 @@ func Test_checkVersion_returnsErrorIfCurrentVersionIsDifferentFromMessageVersion
 	c := &Conversation{Policies: policies(allowV2 | allowV3), version: otrV3{}}
 	c.ourKeys = []PrivateKey{alicePrivateKey}
 	e := c.checkVersion([]byte{0x00, 0x02})
-	assertEquals(t, e, errWrongProtocolVersion)
+	assertEquals(t, e, ErrWrongProtocolVersion)
 }
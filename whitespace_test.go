@@ -107,7 +107,7 @@ func Test_receive_failsWhenReceivesV2WhitespaceTagIfV2IsNotInThePolicy(t *testin
 
 	_, toSend, err := c.Receive(msg)
 
-	assertEquals(t, err, errUnsupportedOTRVersion)
+	assertEquals(t, err, ErrUnsupportedVersion)
 	assertNil(t, toSend)
 }
 
@@ -158,7 +158,7 @@ func Test_receive_failsWhenReceivesV3WhitespaceTagIfV3IsNotInThePolicy(t *testin
 	msg := genWhitespaceTag(policies(allowV3))
 	_, toSend, err := c.Receive(msg)
 
-	assertEquals(t, err, errUnsupportedOTRVersion)
+	assertEquals(t, err, ErrUnsupportedVersion)
 	assertNil(t, toSend)
 }
 